@@ -3,7 +3,6 @@ package cmd
 import (
 	"errors"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
 	"runtime/debug"
@@ -13,6 +12,7 @@ import (
 	"github.com/crumbyte/noxdir/drive"
 	"github.com/crumbyte/noxdir/filter"
 	"github.com/crumbyte/noxdir/pkg/cache"
+	pkgexclude "github.com/crumbyte/noxdir/pkg/exclude"
 	"github.com/crumbyte/noxdir/render"
 	"github.com/crumbyte/noxdir/structure"
 
@@ -24,6 +24,7 @@ var (
 	ErrUnknown = errors.New("unknown error")
 
 	exclude         []string
+	excludePatterns []string
 	root            string
 	sizeLimit       string
 	noEmptyDirs     bool
@@ -31,6 +32,14 @@ var (
 	colorSchemaPath string
 	useCache        bool
 	clearCache      bool
+	cacheDir        string
+	historySize     int
+	hardlinks       string
+	followSymlinks  bool
+	ignoreFiles     string
+	maxDepth        int
+	watch           bool
+	progressive     bool
 
 	tree *structure.Tree
 
@@ -71,7 +80,36 @@ all directories whose name contains that string will be excluded from
 scanning.
 
 Example: --exclude="node_modules,Steam\appcache"
-(first rule will exclude all existing "node_modules" directories)`)
+(first rule will exclude all existing "node_modules" directories)
+
+For precise rules, use the "--exclude-pattern" flag instead.`)
+
+	appCmd.PersistentFlags().StringArrayVarP(
+		&excludePatterns,
+		"exclude-pattern",
+		"X",
+		nil,
+		`Exclude directories and files matching the provided pattern. The
+flag can be provided multiple times. The patterns follow the gitignore
+syntax and are matched against the path relative to the scanned root:
+
+	node_modules    - a name at any level;
+	/build          - a name at the top level only;
+	src/gen         - a path relative to the root;
+	*.log           - "*" and "?" wildcards, "[a-z]" ranges;
+	**/logs, a/**   - any number of directories;
+	tmp/            - directories only;
+	!keep/          - re-include a previously excluded path;
+	re:^cache-\d+$  - a regular expression matched against the path.
+
+The last matching pattern wins. The patterns are case-insensitive on
+Windows and macOS, and case-sensitive on other systems.
+
+Excluded directories are shown in the output as greyed placeholders
+without content.
+
+Example: -X "/build" -X "*.tmp" -X "!keep.tmp"
+`)
 
 	appCmd.PersistentFlags().StringVarP(
 		&root,
@@ -170,7 +208,21 @@ used as long as the flag is provided.
 
 The cache will always store the last session data. In order to update the
 cache and the application's state, use the "r" (refresh) command on a 
-target directory.
+target directory. The "ctrl+r" command refreshes only the directories
+whose modification time has changed and reuses the rest.
+
+The directory provided by the "--root" flag is cached as well. Its scans
+and the refreshed directories are also merged into the cached drive, or
+another cached directory containing them, so the cache stays accurate
+without scanning the entire drive again.
+
+The cache is kept separately for each file system and set of scan
+options, e.g., "--exclude" or "--size-limit"; hence, another disk mounted
+at the same path is never shown from the cache. The drives list shows how
+old the cache of each drive is.
+
+The cache files are checksummed. The damaged files and the files written
+in an unsupported format are discarded, and the drive is scanned again.
 
 Default value is "false".
 
@@ -178,6 +230,121 @@ Example: -c|--use-cache (provide a flag)
 `,
 	)
 
+	appCmd.PersistentFlags().StringVarP(
+		&hardlinks,
+		"hardlinks",
+		"",
+		"once",
+		`Define how the size of files with multiple hard links is accounted.
+The following modes are available:
+
+	once  - count the file only once, for the first found link;
+	all   - count the full file size for every link;
+	split - split the file size evenly between all links.
+
+Files with multiple hard links are listed in a separate view ("ctrl+l").
+
+Default value is "once".
+
+Example: --hardlinks=split
+`,
+	)
+
+	appCmd.PersistentFlags().BoolVarP(
+		&followSymlinks,
+		"follow-symlinks",
+		"",
+		false,
+		`Follow symbolic links during scanning. The linked directories will be
+scanned as regular ones, even if they are located on another volume. Cycles
+are detected, and already visited directories are not scanned again.
+
+If the flag is not provided, symbolic links are shown as zero-size entries
+along with their targets.
+
+NOTE: links are not followed on Windows.
+
+Default value is "false".
+
+Example: --follow-symlinks (provide a flag)
+`,
+	)
+
+	appCmd.PersistentFlags().StringVarP(
+		&ignoreFiles,
+		"ignore-files",
+		"",
+		"",
+		`Honor the ".gitignore", ".ignore", and ".noxdirignore" files found in
+the scanned directories. The rules from each file are applied to the
+directory's subtree. The following modes are available:
+
+	skip  - skip the ignored entries completely;
+	group - group the ignored entries of each directory under a single
+	        "[ignored]" entry, so their size stays visible.
+
+If the flag is provided without a value, the "skip" mode is used. The
+".noxdirignore" files also support the "re:" regular expression rules.
+
+Example: --ignore-files=group
+`,
+	)
+
+	appCmd.PersistentFlags().Lookup("ignore-files").NoOptDefVal = "skip"
+
+	appCmd.PersistentFlags().IntVarP(
+		&maxDepth,
+		"max-depth",
+		"",
+		0,
+		`Limit the depth of the directory tree kept in memory. The directories
+on the provided depth are collapsed: their content is still scanned to
+calculate the size and the number of entries, but the entries themselves
+are not kept. It drastically reduces the memory usage for huge drives.
+
+The collapsed directories are marked in the output, and their content
+will be scanned on demand once the directory is opened.
+
+Default value is "0", which means no limit.
+
+Example: --max-depth=3
+`,
+	)
+
+	appCmd.PersistentFlags().BoolVarP(
+		&watch,
+		"watch",
+		"",
+		false,
+		`Watch the scanned drive or root directory for changes once the scan is
+complete. The created, deleted, renamed, and modified files are applied
+to the displayed tree live, without rescanning. The "ctrl+g" key shows
+the directories that have grown the most within the last minute.
+
+On Linux, a single fanotify mark is used for the entire drive if the
+process has enough privileges. Otherwise, each directory is watched
+separately, which is limited by the "fs.inotify.max_user_watches" value.
+Watching is not supported on other systems yet.
+
+Example: --watch (provide a flag)
+`,
+	)
+
+	appCmd.PersistentFlags().BoolVarP(
+		&progressive,
+		"progressive",
+		"",
+		false,
+		`Keep the navigation available while the drive is being scanned. The
+directory currently opened is scanned before the rest of the drive,
+and the directories whose content is still being scanned are marked
+with the "⏳" icon. The collapsed directories can be opened only once
+the scan is complete.
+
+Example: --progressive (provide a flag)
+`,
+	)
+
 	appCmd.PersistentFlags().BoolVarP(
 		&clearCache,
 		"clear-cache",
@@ -186,17 +353,52 @@ Example: -c|--use-cache (provide a flag)
 		`Delete all cache files from the application's directory.
 
 Example: --clear-cache (provide a flag)
+`,
+	)
+
+	appCmd.PersistentFlags().StringVarP(
+		&cacheDir,
+		"cache-dir",
+		"",
+		"",
+		`Set the directory the cache files are stored in. If not provided, the
+NOXDIR_CACHE_DIR environment variable is used. Otherwise, the cache is
+stored in "$XDG_CACHE_HOME/noxdir" (or "~/.cache/noxdir") on Linux,
+"%LocalAppData%\.noxdir\cache" on Windows, and "~/.noxdir/cache" on
+other systems.
+
+Example: --cache-dir=/tmp/noxdir-cache
+`,
+	)
+
+	appCmd.PersistentFlags().IntVarP(
+		&historySize,
+		"history-size",
+		"",
+		10,
+		`Set the number of previous scan snapshots kept in the cache for each
+root. A new snapshot is added each time the cache is updated, but at
+most once per hour. The snapshots can be compared with the current state
+using the "ctrl+d" command. Provide 0 to disable the history.
+
+Example: --history-size=20
 `,
 	)
 }
 
 func Execute() {
 	if err := appCmd.Execute(); err != nil {
-		var cliErr *CLIError
+		var (
+			cliErr *CLIError
+			cmdErr *CommandError
+		)
 
-		if errors.As(err, &cliErr) {
+		switch {
+		case errors.As(err, &cliErr):
 			printError(cliErr.Error())
-		} else {
+		case errors.As(err, &cmdErr):
+			printError(cmdErr.Error())
+		default:
 			printError(render.ReportError(err, debug.Stack()))
 		}
 
@@ -205,15 +407,24 @@ func Execute() {
 }
 
 func runApp(_ *cobra.Command, _ []string) error {
+	return runUI(resolveNavigation)
+}
+
+// runUI starts the UI with the navigation created by the provided function. The
+// function is called once the color schema is loaded, so the invalid schema is
+// reported before the potentially long scanning.
+func runUI(navigation func() (*render.Navigation, error)) error {
 	if err := initColorSchema(); err != nil {
 		return err
 	}
 
-	vm, err := initViewModel()
+	nav, err := navigation()
 	if err != nil {
 		return err
 	}
 
+	vm := initViewModel(nav)
+
 	teaProg := tea.NewProgram(
 		vm,
 		tea.WithAltScreen(),
@@ -243,12 +454,7 @@ func runApp(_ *cobra.Command, _ []string) error {
 	return nil
 }
 
-func initViewModel() (*render.ViewModel, error) {
-	nav, err := resolveNavigation()
-	if err != nil {
-		return nil, err
-	}
-
+func initViewModel(nav *render.Navigation) *render.ViewModel {
 	var dirModelFilters []filter.EntryFilter
 
 	if noEmptyDirs {
@@ -261,60 +467,34 @@ func initViewModel() (*render.ViewModel, error) {
 		render.NewDirModel(nav, dirModelFilters...),
 	)
 
-	if root != "" {
+	// the tree is already built if the navigation starts from a directory
+	if !nav.OnDrives() {
 		vm.Update(render.ScanFinished{})
 	}
 
-	return vm, nil
+	return vm
 }
 
 func resolveNavigation() (*render.Navigation, error) {
-	var (
-		opts          []structure.TreeOpt
-		fif           []drive.FileInfoFilter
-		cacheInstance *cache.Cache
-	)
-
-	if len(exclude) > 0 {
-		opts = append(opts, structure.WithExclude(exclude))
-	}
+	var cacheInstance *cache.Cache
 
-	sizeLimitFilter, err := parseSizeLimit()
+	opts, err := treeOptions(maxDepth)
 	if err != nil {
-		return nil, NewCLIError(
-			fmt.Errorf("invalid value for size-limit flag: %s", err.Error()),
-		)
-	}
-
-	if sizeLimitFilter != nil {
-		fif = append(fif, sizeLimitFilter)
+		return nil, err
 	}
 
-	if noHidden {
-		fif = append(fif, drive.HiddenFilter)
+	if progressive {
+		opts = append(opts, structure.WithFocusFirst())
 	}
 
 	if useCache || clearCache {
-		cacheInstance, err = cache.NewCache(
-			func(w io.Writer) cache.Encoder {
-				return structure.NewEncoder(w)
-			},
-			func(r io.Reader) cache.Decoder {
-				return structure.NewDecoder(r)
-			},
-			clearCache,
-			cache.WithCompress(),
-		)
+		cacheInstance, err = newCache(clearCache)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	opts = append(
-		opts,
-		structure.WithFileInfoFilter(fif),
-		structure.WithCache(cacheInstance),
-	)
+	opts = append(opts, structure.WithCache(cacheInstance))
 
 	if root != "" {
 		root = strings.TrimSuffix(root, string(os.PathSeparator))
@@ -328,16 +508,97 @@ func resolveNavigation() (*render.Navigation, error) {
 			append(opts, structure.WithPartialRoot())...,
 		)
 
-		return render.NewRootNavigation(tree)
+		nav, err := render.NewRootNavigation(tree)
+		if err == nil && watch {
+			nav.EnableWatch()
+		}
+
+		return nav, err
 	}
 
 	tree = structure.NewTree(nil, opts...)
+	nav := render.NewNavigation(tree)
+
+	if watch {
+		nav.EnableWatch()
+	}
+
+	return nav, nil
+}
+
+// treeOptions builds the tree options from the flags that affect the scanned
+// tree's content. The options are shared by the TUI and the headless commands.
+// The directories on the provided depth are collapsed, and zero value means no
+// limit.
+func treeOptions(collapseDepth int) ([]structure.TreeOpt, error) {
+	var (
+		opts []structure.TreeOpt
+		fif  []drive.FileInfoFilter
+	)
+
+	if len(exclude) > 0 {
+		opts = append(opts, structure.WithExclude(exclude))
+	}
+
+	excludeMatcher, err := pkgexclude.New(excludePatterns)
+	if err != nil {
+		return nil, NewCLIError(
+			fmt.Errorf("invalid value for exclude-pattern flag: %s", err.Error()),
+		)
+	}
+
+	if !excludeMatcher.Empty() {
+		opts = append(opts, structure.WithExcludeMatcher(excludeMatcher))
+	}
+
+	sizeLimitFilter, err := parseSizeLimit()
+	if err != nil {
+		return nil, NewCLIError(
+			fmt.Errorf("invalid value for size-limit flag: %s", err.Error()),
+		)
+	}
+
+	if sizeLimitFilter != nil {
+		fif = append(fif, sizeLimitFilter)
+	}
+
+	hardlinkMode, err := parseHardlinkMode()
+	if err != nil {
+		return nil, NewCLIError(
+			fmt.Errorf("invalid value for hardlinks flag: %s", err.Error()),
+		)
+	}
+
+	opts = append(opts, structure.WithHardlinkMode(hardlinkMode))
+
+	if followSymlinks {
+		opts = append(opts, structure.WithFollowSymlinks())
+	}
+
+	ignoreMode, err := parseIgnoreMode()
+	if err != nil {
+		return nil, NewCLIError(
+			fmt.Errorf("invalid value for ignore-files flag: %s", err.Error()),
+		)
+	}
+
+	if collapseDepth > 0 {
+		opts = append(opts, structure.WithMaxDepth(collapseDepth))
+	}
+
+	if ignoreMode != structure.IgnoreNone {
+		opts = append(opts, structure.WithIgnoreFiles(ignoreMode))
+	}
+
+	if noHidden {
+		fif = append(fif, drive.HiddenFilter)
+	}
 
-	return render.NewNavigation(tree), nil
+	return append(opts, structure.WithFileInfoFilter(fif)), nil
 }
 
 func printError(errMsg string) {
-	if _, err := os.Stdout.WriteString(errMsg + "\n"); err != nil {
+	if _, err := os.Stderr.WriteString(errMsg + "\n"); err != nil {
 		return
 	}
 }
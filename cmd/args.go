@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/crumbyte/noxdir/drive"
+	"github.com/crumbyte/noxdir/structure"
 )
 
 func parseSizeLimit() (drive.FileInfoFilter, error) {
@@ -24,45 +26,131 @@ func parseSizeLimit() (drive.FileInfoFilter, error) {
 		return nil, fmt.Errorf("check the usage example: %s", sizeLimit)
 	}
 
-	multiplier := map[string]int{"pb": 40, "tb": 30, "gb": 20, "mb": 10, "kb": 0}
+	minLimit, err := parseSize(limits[0])
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse min limit: %w", err)
+	}
 
-	// parse a single size raw value. If the part is empty a 0 limit will be
-	// returned.
-	readLimit := func(rawValue string) (int64, error) {
-		if rawValue = strings.ToLower(rawValue); len(rawValue) == 0 {
-			return 0, nil
-		}
+	maxLimit, err := parseSize(limits[1])
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse max limit: %w", err)
+	}
 
-		if len(rawValue) < 3 {
-			return 0, fmt.Errorf("invalid size-limit value: %s", rawValue)
-		}
+	if maxLimit != 0 && minLimit > maxLimit {
+		return nil, errors.New("min value is bigger than max value")
+	}
 
-		minLimit, err := strconv.ParseInt(rawValue[:len(rawValue)-2], 10, 64)
-		if err != nil {
-			return 0, errors.New("unknown size unit")
-		}
+	return drive.NewSizeFilter(minLimit, maxLimit).Filter, nil
+}
 
-		offset, ok := multiplier[rawValue[len(rawValue)-2:]]
-		if !ok {
-			return 0, errors.New("unknown size unit")
-		}
+// parseSize parses a single size value with a unit, e.g., "10mb". If the value
+// is empty, a 0 size will be returned.
+func parseSize(rawValue string) (int64, error) {
+	multiplier := map[string]int{"pb": 40, "tb": 30, "gb": 20, "mb": 10, "kb": 0}
 
-		return minLimit * 1024 << offset, nil
+	if rawValue = strings.ToLower(strings.TrimSpace(rawValue)); len(rawValue) == 0 {
+		return 0, nil
 	}
 
-	minLimit, err := readLimit(limits[0])
-	if err != nil {
-		return nil, fmt.Errorf("cannot parse min limit: %w", err)
+	if len(rawValue) < 3 {
+		return 0, fmt.Errorf("invalid size value: %s", rawValue)
 	}
 
-	maxLimit, err := readLimit(limits[1])
+	size, err := strconv.ParseInt(rawValue[:len(rawValue)-2], 10, 64)
 	if err != nil {
-		return nil, fmt.Errorf("cannot parse max limit: %w", err)
+		return 0, errors.New("unknown size unit")
 	}
 
-	if maxLimit != 0 && minLimit > maxLimit {
-		return nil, errors.New("min value is bigger than max value")
+	offset, ok := multiplier[rawValue[len(rawValue)-2:]]
+	if !ok {
+		return 0, errors.New("unknown size unit")
 	}
 
-	return drive.NewSizeFilter(minLimit, maxLimit).Filter, nil
+	return size * 1024 << offset, nil
+}
+
+func parseHardlinkMode() (structure.HardlinkMode, error) {
+	modes := map[string]structure.HardlinkMode{
+		"once":  structure.HardlinkOnce,
+		"all":   structure.HardlinkAll,
+		"split": structure.HardlinkSplit,
+	}
+
+	mode, ok := modes[strings.ToLower(strings.TrimSpace(hardlinks))]
+	if !ok {
+		return 0, fmt.Errorf("unknown mode: %s", hardlinks)
+	}
+
+	return mode, nil
+}
+
+func parseIgnoreMode() (structure.IgnoreMode, error) {
+	modes := map[string]structure.IgnoreMode{
+		"":      structure.IgnoreNone,
+		"skip":  structure.IgnoreSkip,
+		"group": structure.IgnoreGroup,
+	}
+
+	mode, ok := modes[strings.ToLower(strings.TrimSpace(ignoreFiles))]
+	if !ok {
+		return 0, fmt.Errorf("unknown mode: %s", ignoreFiles)
+	}
+
+	return mode, nil
+}
+
+// scanOptions describes the flags that affect the scanned tree's content. The
+// description is stored along with the cached tree, so it's known which options
+// the tree was built with.
+func scanOptions() string {
+	// the names are normalized the same way the tree does it, so the same
+	// rules written differently are described the same way
+	excludeNames := make([]string, len(exclude))
+
+	for i := range exclude {
+		excludeNames[i] = strings.ToLower(strings.TrimSpace(exclude[i]))
+	}
+
+	options := []string{
+		"exclude=" + strings.Join(excludeNames, ","),
+		"exclude-pattern=" + strings.Join(excludePatterns, ","),
+		"size-limit=" + strings.TrimSpace(sizeLimit),
+		"no-hidden=" + strconv.FormatBool(noHidden),
+		"hardlinks=" + strings.ToLower(strings.TrimSpace(hardlinks)),
+		"follow-symlinks=" + strconv.FormatBool(followSymlinks),
+		"ignore-files=" + strings.ToLower(strings.TrimSpace(ignoreFiles)),
+		"max-depth=" + strconv.Itoa(max(0, maxDepth)),
+	}
+
+	return strings.Join(options, " ")
+}
+
+// parseAge parses the age value used by the cache management commands. Besides
+// the units supported by time.ParseDuration, the "d" (days) and "w" (weeks) units
+// are supported, e.g., "30d" or "2w".
+func parseAge(rawValue string) (time.Duration, error) {
+	rawValue = strings.ToLower(strings.TrimSpace(rawValue))
+
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+
+	for suffix, unit := range units {
+		value, ok := strings.CutSuffix(rawValue, suffix)
+		if !ok {
+			continue
+		}
+
+		n, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("check the usage example: %s", rawValue)
+		}
+
+		return time.Duration(n) * unit, nil
+	}
+
+	age, err := time.ParseDuration(rawValue)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("check the usage example: %s", rawValue)
+	}
+
+	return age, nil
 }
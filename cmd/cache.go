@@ -0,0 +1,422 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/crumbyte/noxdir/drive"
+	"github.com/crumbyte/noxdir/pkg/cache"
+	"github.com/crumbyte/noxdir/render"
+	"github.com/crumbyte/noxdir/structure"
+
+	"github.com/spf13/cobra"
+)
+
+// cacheDirEnv defines the environment variable that overrides the default cache
+// directory if the "--cache-dir" flag is not provided.
+const cacheDirEnv = "NOXDIR_CACHE_DIR"
+
+var (
+	pruneOlderThan string
+	importRebind   bool
+
+	cacheCmd = &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the cached scan results.",
+		Long: `
+Manage the cached scan results created with the "--use-cache" flag. Each
+cache entry contains a single scanned drive or root directory, along with
+the file system it is located on and the scan options it was built with.
+
+On Linux, the cache was previously stored in "~/.noxdir/cache". It's stored
+in "$XDG_CACHE_HOME/noxdir" (or "~/.cache/noxdir") now, and the files from the
+previous directory are moved there once the cache is used with the default
+location. The files that already exist in the new directory are left in place.`,
+		PersistentPreRun: func(cmd *cobra.Command, _ []string) {
+			// the arguments are valid at this point, so the usage is not
+			// relevant for the errors that might occur further, and the errors
+			// are printed once the command exits.
+			cmd.SilenceUsage = true
+			cmd.SilenceErrors = true
+		},
+	}
+
+	cacheListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List all cache entries.",
+		Args:  cobra.NoArgs,
+		RunE:  subcommandRunE(runCacheList),
+	}
+
+	cacheShowCmd = &cobra.Command{
+		Use:   "show <root>",
+		Short: "Show the details of the root's cache entries.",
+		Args:  cobra.ExactArgs(1),
+		RunE:  subcommandRunE(runCacheShow),
+	}
+
+	cacheRmCmd = &cobra.Command{
+		Use:   "rm <root>",
+		Short: "Remove all cache entries of the root.",
+		Args:  cobra.ExactArgs(1),
+		RunE:  subcommandRunE(runCacheRm),
+	}
+
+	cachePruneCmd = &cobra.Command{
+		Use:   "prune",
+		Short: "Remove outdated and unreadable cache entries.",
+		Args:  cobra.NoArgs,
+		RunE:  subcommandRunE(runCachePrune),
+	}
+
+	cacheExportCmd = &cobra.Command{
+		Use:   "export <root> <file>",
+		Short: "Export the root's latest cache entry to a file.",
+		Long: `
+Export the root's latest cache entry to a file. The exported file is
+self-contained and can be imported on another machine. Use "-" as the file
+name to write the entry to the standard output.`,
+		Args: cobra.ExactArgs(2),
+		RunE: subcommandRunE(runCacheExport),
+	}
+
+	cacheImportCmd = &cobra.Command{
+		Use:   "import <file>",
+		Short: "Import an exported cache entry.",
+		Long: `
+Import an exported cache entry. The imported entry is used only for the same
+file system and scan options it was created with. Provide the "--rebind"
+flag to use the entry for the file system currently mounted at the entry's
+root path instead.`,
+		Args: cobra.ExactArgs(1),
+		RunE: subcommandRunE(runCacheImport),
+	}
+)
+
+func init() {
+	cachePruneCmd.Flags().StringVarP(
+		&pruneOlderThan,
+		"older-than",
+		"",
+		"30d",
+		`Remove the entries created earlier than the provided age. The age
+is a number followed by a unit: "m", "h", "d", or "w".
+
+Example: --older-than=12h
+`,
+	)
+
+	cacheImportCmd.Flags().BoolVarP(
+		&importRebind,
+		"rebind",
+		"",
+		false,
+		`Bind the imported entry to the file system currently mounted at the
+entry's root path.
+
+Example: --rebind (provide a flag)
+`,
+	)
+
+	cacheCmd.AddCommand(
+		cacheListCmd,
+		cacheShowCmd,
+		cacheRmCmd,
+		cachePruneCmd,
+		cacheExportCmd,
+		cacheImportCmd,
+	)
+
+	appCmd.AddCommand(cacheCmd)
+}
+
+// newCache creates the cache instance for the current scan options. The cache
+// directory is taken from the "--cache-dir" flag, the NOXDIR_CACHE_DIR
+// environment variable, or the default location, respectively.
+func newCache(clear bool) (*cache.Cache, error) {
+	opts := []cache.Option{
+		cache.WithCompress(),
+		cache.WithAppVersion(render.Version),
+		cache.WithScanOptions(scanOptions()),
+	}
+
+	dir := cacheDir
+	if len(dir) == 0 {
+		dir = os.Getenv(cacheDirEnv)
+	}
+
+	if len(dir) != 0 {
+		opts = append(opts, cache.WithCacheDir(dir))
+	}
+
+	if historySize > 0 {
+		opts = append(opts, cache.WithHistory(historySize, time.Hour))
+	}
+
+	return cache.NewCache(
+		func(w io.Writer) cache.Encoder {
+			return structure.NewEncoder(w)
+		},
+		func(r io.Reader) cache.Decoder {
+			return structure.NewDecoder(r)
+		},
+		clear,
+		opts...,
+	)
+}
+
+func runCacheList(cmd *cobra.Command, _ []string) error {
+	c, err := newCache(false)
+	if err != nil {
+		return err
+	}
+
+	files, err := c.Files()
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+
+	_, _ = fmt.Fprintln(tw, "ROOT\tSIZE\tENTRIES\tAGE\tSNAPSHOTS\tFILE SIZE")
+
+	for _, f := range files {
+		if f.Err != nil {
+			_, _ = fmt.Fprintf(tw, "%s (unreadable)\t-\t-\t-\t-\t%s\n", f.Name, render.FmtSize(f.Size, 0))
+
+			continue
+		}
+
+		snapshots, err := c.History(f.Name)
+		if err != nil {
+			return err
+		}
+
+		_, _ = fmt.Fprintf(
+			tw,
+			"%s\t%s\t%d\t%s\t%d\t%s\n",
+			f.Header.Path,
+			render.FmtSize(f.Header.Summary.Size, 0),
+			f.Header.Summary.Entries(),
+			render.FmtAge(time.Since(f.Header.CreatedAt)),
+			len(snapshots),
+			render.FmtSize(f.Size, 0),
+		)
+	}
+
+	return tw.Flush()
+}
+
+func runCacheShow(cmd *cobra.Command, args []string) error {
+	c, files, err := rootCacheFiles(args[0])
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+
+	for i, f := range files {
+		if i > 0 {
+			_, _ = fmt.Fprintln(tw)
+		}
+
+		checksum := "ok"
+		if err = c.Verify(f.Name); err != nil {
+			checksum = err.Error()
+		}
+
+		h, s := f.Header, f.Header.Summary
+
+		rows := [][2]string{
+			{"Root", h.Path},
+			{"Device", h.Device},
+			{"Host", h.Host},
+			{"Scan options", h.ScanOptions},
+			{"Created", h.CreatedAt.Format(time.DateTime) + " (" + render.FmtAge(time.Since(h.CreatedAt)) + ")"},
+			{"Written by", h.AppVersion},
+			{"Format version", strconv.Itoa(int(h.FormatVersion))},
+			{"Compressed", strconv.FormatBool(h.Compressed)},
+			{"File", filepath.Join(c.Dir(), f.Name)},
+			{"File size", render.FmtSize(f.Size, 0)},
+			{"Checksum", checksum},
+			{"Size", render.FmtSize(s.Size, 0)},
+			{"Disk usage", render.FmtSize(s.Usage, 0)},
+			{"Directories", strconv.FormatUint(s.Dirs, 10)},
+			{"Files", strconv.FormatUint(s.Files, 10)},
+		}
+
+		snapshots, err := c.History(f.Name)
+		if err != nil {
+			return err
+		}
+
+		for _, snapshot := range snapshots {
+			rows = append(rows, [2]string{"Snapshot", snapshotInfo(snapshot)})
+		}
+
+		for _, row := range rows {
+			_, _ = fmt.Fprintf(tw, "%s:\t%s\n", row[0], row[1])
+		}
+	}
+
+	return tw.Flush()
+}
+
+func runCacheRm(cmd *cobra.Command, args []string) error {
+	c, files, err := rootCacheFiles(args[0])
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		if err = c.Remove(f.Name); err != nil {
+			return err
+		}
+	}
+
+	_, _ = fmt.Fprintf(cmd.OutOrStdout(), "removed %d cache entries\n", len(files))
+
+	return nil
+}
+
+func runCachePrune(cmd *cobra.Command, _ []string) error {
+	age, err := parseAge(pruneOlderThan)
+	if err != nil {
+		return NewCLIError(
+			fmt.Errorf("invalid value for older-than flag: %s", err.Error()),
+		)
+	}
+
+	c, err := newCache(false)
+	if err != nil {
+		return err
+	}
+
+	removed, err := c.Prune(time.Now().Add(-age))
+	if err != nil {
+		return err
+	}
+
+	for _, f := range removed {
+		name := f.Header.Path
+		if f.Err != nil {
+			name = f.Name + " (unreadable)"
+		}
+
+		if f.Snapshot {
+			name += " (snapshot)"
+		}
+
+		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "removed %s\n", name)
+	}
+
+	_, _ = fmt.Fprintf(cmd.OutOrStdout(), "removed %d cache entries\n", len(removed))
+
+	return nil
+}
+
+func runCacheExport(cmd *cobra.Command, args []string) error {
+	c, files, err := rootCacheFiles(args[0])
+	if err != nil {
+		return err
+	}
+
+	if args[1] == "-" {
+		return c.Export(files[0].Name, cmd.OutOrStdout())
+	}
+
+	out, err := os.Create(args[1])
+	if err != nil {
+		return fmt.Errorf("create export file: %w", err)
+	}
+
+	if err = c.Export(files[0].Name, out); err != nil {
+		_ = out.Close()
+		_ = os.Remove(args[1])
+
+		return err
+	}
+
+	return out.Close()
+}
+
+func runCacheImport(cmd *cobra.Command, args []string) error {
+	c, err := newCache(false)
+	if err != nil {
+		return err
+	}
+
+	var device string
+
+	if importRebind {
+		// the header is validated by the import itself
+		h, err := cache.ReadHeader(args[0])
+		if err != nil {
+			return err
+		}
+
+		if device = drive.DeviceID(h.Path); len(device) == 0 {
+			return fmt.Errorf("rebind: %s is not accessible", h.Path)
+		}
+	}
+
+	h, err := c.Import(args[0], device)
+	if err != nil {
+		return fmt.Errorf("import %s: %w", args[0], err)
+	}
+
+	_, _ = fmt.Fprintf(cmd.OutOrStdout(), "imported %s (%s)\n", h.Path, h.ScanOptions)
+
+	return nil
+}
+
+// snapshotInfo describes the snapshot kept in the cache entry's history.
+func snapshotInfo(f cache.File) string {
+	if f.Err != nil {
+		return f.Name + " (unreadable)"
+	}
+
+	return fmt.Sprintf(
+		"%s (%s), %s",
+		f.Header.CreatedAt.Format(time.DateTime),
+		render.FmtAge(time.Since(f.Header.CreatedAt)),
+		render.FmtSize(f.Header.Summary.Size, 0),
+	)
+}
+
+// rootCacheFiles returns the cache files of the provided root, newest first. An
+// error will be returned if there are no cache files for the root.
+func rootCacheFiles(rootPath string) (*cache.Cache, []cache.File, error) {
+	c, err := newCache(false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if rootPath, err = filepath.Abs(rootPath); err != nil {
+		return nil, nil, fmt.Errorf("resolve absolute root path: %w", err)
+	}
+
+	files, err := c.Files()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rootFiles := make([]cache.File, 0)
+
+	for _, f := range files {
+		if f.Err == nil && f.Header.Path == rootPath {
+			rootFiles = append(rootFiles, f)
+		}
+	}
+
+	if len(rootFiles) == 0 {
+		return nil, nil, fmt.Errorf("%s: %w", rootPath, cache.ErrNoCache)
+	}
+
+	return c, rootFiles, nil
+}
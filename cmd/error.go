@@ -1,6 +1,11 @@
 package cmd
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
 
 type CLIError struct {
 	ctxErr error
@@ -13,3 +18,37 @@ func NewCLIError(err error) *CLIError {
 func (err CLIError) Error() string {
 	return fmt.Sprintf("error on reading CLI flags: %s", err.ctxErr.Error())
 }
+
+// CommandError wraps the errors of the subcommands that are caused by their
+// input or environment, e.g., a missing cache entry. Such errors are reported
+// as is, without the bug report.
+type CommandError struct {
+	ctxErr error
+}
+
+func NewCommandError(err error) *CommandError {
+	return &CommandError{ctxErr: err}
+}
+
+func (err CommandError) Error() string {
+	return err.ctxErr.Error()
+}
+
+func (err CommandError) Unwrap() error {
+	return err.ctxErr
+}
+
+// subcommandRunE wraps the errors of the subcommands, since they're caused by
+// their input or environment rather than by a bug, e.g., a missing cache entry.
+func subcommandRunE(run func(*cobra.Command, []string) error) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		var cliErr *CLIError
+
+		err := run(cmd, args)
+		if err == nil || errors.As(err, &cliErr) {
+			return err
+		}
+
+		return NewCommandError(err)
+	}
+}
@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/crumbyte/noxdir/export"
+	"github.com/crumbyte/noxdir/render"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportNCDU    bool
+	exportCSV     bool
+	exportTSV     bool
+	exportOutput  string
+	exportMinSize string
+	exportColumns []string
+	exportDepth   int
+
+	exportCmd = &cobra.Command{
+		Use:   "export [path]",
+		Short: "Scan a directory and export the tree for other tools.",
+		Long: `
+Scan a directory without starting the UI and export the scanned tree in the
+format of another tool. The current directory is scanned if no path is
+provided. The same flags as for the UI define the scanned content, while the
+"--depth" and "--min-size" flags prune the output only.
+
+The ncdu export is a JSON dump that can be browsed with "ncdu -f". The
+directories' sizes in the dump still include their pruned content and the
+grouped ignored entries. The hard-linked files are marked with the number of
+links, so ncdu counts each file once.
+
+The CSV and TSV exports are flat tables with a single row per entry, e.g., for
+spreadsheets. The first row contains the column names.`,
+		Example: `  noxdir export --ncdu /var > var.json && ncdu -f var.json
+  noxdir export --ncdu --output=home.json ~
+  noxdir export --csv --depth=2 --min-size=1gb --columns=path,size,files /srv`,
+		Args: cobra.MaximumNArgs(1),
+		PreRun: func(cmd *cobra.Command, _ []string) {
+			cmd.SilenceUsage = true
+			cmd.SilenceErrors = true
+		},
+		RunE: subcommandRunE(runExport),
+	}
+)
+
+func init() {
+	exportCmd.Flags().BoolVarP(
+		&exportNCDU,
+		"ncdu",
+		"",
+		false,
+		`Export the tree in the ncdu JSON dump format, which can be loaded
+with "ncdu -f".
+`,
+	)
+
+	exportCmd.Flags().BoolVarP(
+		&exportCSV,
+		"csv",
+		"",
+		false,
+		`Export the tree as a comma-separated table with a single row per
+entry.
+`,
+	)
+
+	exportCmd.Flags().BoolVarP(
+		&exportTSV,
+		"tsv",
+		"",
+		false,
+		`Export the tree as a tab-separated table with a single row per
+entry.
+`,
+	)
+
+	exportCmd.Flags().StringSliceVarP(
+		&exportColumns,
+		"columns",
+		"",
+		nil,
+		`Set the columns of the CSV and TSV exports and their order. All
+columns are exported by default:
+	path  - the entry's full path;
+	type  - the entry's type: dir, file, or link;
+	size  - the apparent size in bytes;
+	usage - the allocated size in bytes;
+	files - the total number of files within the directory;
+	dirs  - the total number of directories within the directory;
+	mtime - the modification time in the RFC 3339 format;
+	depth - the entry's depth, where the root is on depth 0.
+
+Example: --columns=path,size,mtime
+`,
+	)
+
+	exportCmd.Flags().IntVarP(
+		&exportDepth,
+		"depth",
+		"",
+		0,
+		`Omit the entries deeper than the provided depth from the output,
+where the root's child entries are on depth 1. Unlike "--max-depth",
+the directories are still scanned entirely. Zero value means no limit.
+
+Example: --depth=2
+`,
+	)
+
+	exportCmd.Flags().StringVarP(
+		&exportOutput,
+		"output",
+		"o",
+		"",
+		`Write the export to the provided file instead of the standard
+output. The file is overwritten if it already exists.
+
+Example: --output=export.json
+`,
+	)
+
+	exportCmd.Flags().StringVarP(
+		&exportMinSize,
+		"min-size",
+		"",
+		"",
+		`Omit the entries smaller than the provided size from the output,
+along with their content. The size units are the same as for the
+"--size-limit" flag.
+
+Example: --min-size=10mb
+`,
+	)
+
+	appCmd.AddCommand(exportCmd)
+}
+
+func runExport(cmd *cobra.Command, args []string) error {
+	format, err := exportFormat()
+	if err != nil {
+		return NewCLIError(err)
+	}
+
+	minSize, err := parseSize(exportMinSize)
+	if err != nil {
+		return NewCLIError(fmt.Errorf("invalid value for min-size flag: %s", err.Error()))
+	}
+
+	columns, err := export.ParseColumns(exportColumns)
+	if err != nil {
+		return NewCLIError(fmt.Errorf("invalid value for columns flag: %s", err.Error()))
+	}
+
+	if len(columns) != 0 && format == export.FormatNCDU {
+		return NewCLIError(errors.New("columns flag is supported by csv and tsv exports only"))
+	}
+
+	if exportDepth < 0 {
+		return NewCLIError(fmt.Errorf("invalid value for depth flag: %d", exportDepth))
+	}
+
+	t, err := scanTree(cmd, args, maxDepth)
+	if err != nil {
+		return err
+	}
+
+	return writeOutput(cmd, exportOutput, func(w io.Writer) error {
+		return export.Write(
+			w,
+			t.Root(),
+			format,
+			export.WithMaxDepth(exportDepth),
+			export.WithMinSize(minSize),
+			export.WithColumns(columns...),
+			export.WithAppVersion(render.Version),
+			export.WithHardLinks(t.HardLinks()),
+		)
+	})
+}
+
+// exportFormat returns the export format chosen by the format flags. An error
+// will be returned unless exactly one format flag is provided.
+func exportFormat() (export.Format, error) {
+	var formats []export.Format
+
+	for format, enabled := range map[export.Format]bool{
+		export.FormatNCDU: exportNCDU,
+		export.FormatCSV:  exportCSV,
+		export.FormatTSV:  exportTSV,
+	} {
+		if enabled {
+			formats = append(formats, format)
+		}
+	}
+
+	if len(formats) != 1 {
+		return "", errors.New("exactly one export format flag is required: --ncdu, --csv, or --tsv")
+	}
+
+	return formats[0], nil
+}
+
+// writeOutput calls the write function with the provided output file, or with
+// the command's standard output if the path is empty or "-".
+func writeOutput(cmd *cobra.Command, path string, write func(io.Writer) error) error {
+	if path == "" || path == "-" {
+		return write(cmd.OutOrStdout())
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create output file: %w", err)
+	}
+
+	if err = write(f); err != nil {
+		_ = f.Close()
+
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/crumbyte/noxdir/dump"
+	"github.com/crumbyte/noxdir/pkg/cache"
+	"github.com/crumbyte/noxdir/render"
+	"github.com/crumbyte/noxdir/structure"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	openFormat   string
+	openSnapshot bool
+
+	openCmd = &cobra.Command{
+		Use:   "open <file>",
+		Short: "Browse a disk usage dump written by ncdu or du.",
+		Long: `
+Open a disk usage dump collected on another machine and browse it the same way
+as the scanned directory. The supported formats are the ncdu JSON dump written
+by "ncdu -o" or "noxdir export --ncdu", and the listing written by "du -ab" or
+"du -k". The format is detected automatically unless the "--format" flag is
+provided. Provide "-" to read the dump from the standard input. The du listing
+contains no units, so by default it's read as "du -k" if all sizes are
+multiples of 4, and as "du -ab" otherwise.
+
+With the "--snapshot" flag, the file is read as a noxdir cache file, e.g.,
+written by "noxdir cache export" on another machine. The snapshot's origin,
+i.e., the host, the root, and the scan time, is shown in the status bar.
+
+The dump is opened in read-only mode: the navigation, the top files and
+directories, the chart, and the filters work, while the entries cannot be
+deleted, explored, or refreshed. The local file system is not accessed.`,
+		Example: `  noxdir open var.json
+  ssh server du -ab /var | noxdir open -
+  ssh server du -ak /var | noxdir open --format=du-k -
+  noxdir open --snapshot server.cache`,
+		Args: cobra.ExactArgs(1),
+		PreRun: func(cmd *cobra.Command, _ []string) {
+			cmd.SilenceUsage = true
+			cmd.SilenceErrors = true
+		},
+		RunE: runOpen,
+	}
+)
+
+func init() {
+	openCmd.Flags().StringVarP(
+		&openFormat,
+		"format",
+		"f",
+		"",
+		`Set the dump's format instead of detecting it:
+	ncdu - the JSON dump written by "ncdu -o";
+	du-b - the listing written by "du -ab";
+	du-k - the listing written by "du -k" or "du -ak".
+
+Example: --format=du-b
+`,
+	)
+
+	openCmd.Flags().BoolVarP(
+		&openSnapshot,
+		"snapshot",
+		"",
+		false,
+		`Read the file as a noxdir cache file, e.g., written by the
+"cache export" command, instead of a dump of another tool.
+`,
+	)
+
+	appCmd.AddCommand(openCmd)
+}
+
+func runOpen(cmd *cobra.Command, args []string) error {
+	format := dump.Format(strings.ToLower(strings.TrimSpace(openFormat)))
+
+	switch format {
+	case "", dump.FormatNCDU, dump.FormatDUBytes, dump.FormatDUKilobytes:
+	default:
+		return NewCLIError(fmt.Errorf("invalid value for format flag: %s", openFormat))
+	}
+
+	if openSnapshot {
+		if len(format) != 0 {
+			return NewCLIError(errors.New("format flag cannot be used with snapshot flag"))
+		}
+
+		if args[0] == "-" {
+			return NewCLIError(errors.New("snapshot cannot be read from the standard input"))
+		}
+
+		return runUI(func() (*render.Navigation, error) {
+			return snapshotNavigation(args[0])
+		})
+	}
+
+	return runUI(func() (*render.Navigation, error) {
+		rootEntry, err := readDump(cmd, args[0], format)
+		if err != nil {
+			return nil, NewCommandError(err)
+		}
+
+		return render.NewStaticNavigation(rootEntry), nil
+	})
+}
+
+// readDump reads the dump from the provided file, or from the command's input
+// if the path is "-". The dump's format is detected if it's not provided.
+func readDump(cmd *cobra.Command, path string, format dump.Format) (*structure.Entry, error) {
+	var r io.Reader = cmd.InOrStdin()
+
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("open dump: %w", err)
+		}
+
+		defer func() { _ = f.Close() }()
+
+		r = f
+	}
+
+	var (
+		rootEntry *structure.Entry
+		err       error
+	)
+
+	if len(format) == 0 {
+		rootEntry, _, err = dump.Read(r)
+	} else {
+		rootEntry, err = dump.ReadAs(r, format)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("read dump %s: %w", path, err)
+	}
+
+	return rootEntry, nil
+}
+
+// snapshotNavigation creates the read-only navigation for the cache file located
+// at the provided path. The file is read as is, regardless of the local cache
+// settings and file systems.
+func snapshotNavigation(path string) (*render.Navigation, error) {
+	rootEntry := structure.NewDirEntry("", 0)
+
+	h, err := cache.ReadFile(
+		path,
+		func(r io.Reader) cache.Decoder {
+			return structure.NewDecoder(r)
+		},
+		rootEntry,
+	)
+	if err != nil {
+		return nil, NewCommandError(fmt.Errorf("read snapshot: %w", err))
+	}
+
+	nav := render.NewStaticNavigation(rootEntry)
+	nav.SetOrigin(render.Origin{ScannedAt: h.CreatedAt, Host: h.Host, Root: h.Path})
+
+	return nav, nil
+}
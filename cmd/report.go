@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/crumbyte/noxdir/render"
+	"github.com/crumbyte/noxdir/report"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	reportHTML    string
+	reportMinSize string
+	reportDepth   int
+
+	reportCmd = &cobra.Command{
+		Use:   "report [path]",
+		Short: "Scan a directory and create a shareable HTML report.",
+		Long: `
+Scan a directory without starting the UI and create a single HTML file that
+can be opened in any browser, e.g., to attach it to a ticket. The current
+directory is scanned if no path is provided. The same flags as for the UI
+define the scanned content.
+
+The report contains a zoomable treemap, the lists of the biggest files and
+directories, and a sortable table of each directory's content. It doesn't load
+any external resources, so it can be viewed offline.
+
+The whole tree is embedded into the report, so the "--depth" and "--min-size"
+flags should be used to keep the report of a big tree small. They prune the
+report only; the directories' sizes still include their entire content.`,
+		Example: `  noxdir report --html=report.html
+  noxdir report --html=srv.html --depth=4 --min-size=10mb /srv`,
+		Args: cobra.MaximumNArgs(1),
+		PreRun: func(cmd *cobra.Command, _ []string) {
+			cmd.SilenceUsage = true
+			cmd.SilenceErrors = true
+		},
+		RunE: subcommandRunE(runReport),
+	}
+)
+
+func init() {
+	reportCmd.Flags().StringVarP(
+		&reportHTML,
+		"html",
+		"",
+		"",
+		`Write the HTML report to the provided file, or to the standard
+output if the file is "-". The file is overwritten if it already
+exists.
+
+Example: --html=report.html
+`,
+	)
+
+	reportCmd.Flags().IntVarP(
+		&reportDepth,
+		"depth",
+		"",
+		0,
+		`Omit the entries deeper than the provided depth from the report,
+where the root's child entries are on depth 1. Unlike "--max-depth",
+the directories are still scanned entirely. Zero value means no limit.
+
+Example: --depth=3
+`,
+	)
+
+	reportCmd.Flags().StringVarP(
+		&reportMinSize,
+		"min-size",
+		"",
+		"",
+		`Omit the entries smaller than the provided size from the report,
+along with their content. The size units are the same as for the
+"--size-limit" flag.
+
+Example: --min-size=10mb
+`,
+	)
+
+	appCmd.AddCommand(reportCmd)
+}
+
+func runReport(cmd *cobra.Command, args []string) error {
+	if len(reportHTML) == 0 {
+		return NewCLIError(errors.New("html flag is required"))
+	}
+
+	minSize, err := parseSize(reportMinSize)
+	if err != nil {
+		return NewCLIError(fmt.Errorf("invalid value for min-size flag: %s", err.Error()))
+	}
+
+	if reportDepth < 0 {
+		return NewCLIError(fmt.Errorf("invalid value for depth flag: %d", reportDepth))
+	}
+
+	t, err := scanTree(cmd, args, maxDepth)
+	if err != nil {
+		return err
+	}
+
+	return writeOutput(cmd, reportHTML, func(w io.Writer) error {
+		return report.WriteHTML(
+			w,
+			t.Root(),
+			report.WithMaxDepth(reportDepth),
+			report.WithMinSize(minSize),
+			report.WithAppVersion(render.Version),
+		)
+	})
+}
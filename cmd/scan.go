@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/crumbyte/noxdir/drive"
+	"github.com/crumbyte/noxdir/export"
+	"github.com/crumbyte/noxdir/structure"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	scanFormat  string
+	scanMinSize string
+
+	scanCmd = &cobra.Command{
+		Use:   "scan [path]",
+		Short: "Scan a directory and write the tree to the standard output.",
+		Long: `
+Scan a directory without starting the UI and write the scanned tree to the
+standard output. The current directory is scanned if no path is provided.
+The same flags as for the UI define the scanned content, e.g., "--exclude",
+"--size-limit", or "--no-hidden", while the "--max-depth" and "--min-size"
+flags prune the output only: the directories' sizes still include their
+entire content.
+
+The entries that cannot be read, e.g., due to missing permissions, are
+reported to the standard error output and do not fail the command.`,
+		Example: `  noxdir scan /var/log > log.json
+  noxdir scan ~ --format=ndjson --min-size=100mb | jq -r .path`,
+		Args: cobra.MaximumNArgs(1),
+		PreRun: func(cmd *cobra.Command, _ []string) {
+			cmd.SilenceUsage = true
+			cmd.SilenceErrors = true
+		},
+		RunE: subcommandRunE(runScan),
+	}
+)
+
+func init() {
+	scanCmd.Flags().StringVarP(
+		&scanFormat,
+		"format",
+		"f",
+		string(export.FormatJSON),
+		`Set the output format:
+	json   - a single JSON document with the nested entries;
+	ndjson - a single JSON object per line for each entry.
+
+Example: --format=ndjson
+`,
+	)
+
+	scanCmd.Flags().StringVarP(
+		&scanMinSize,
+		"min-size",
+		"",
+		"",
+		`Omit the entries smaller than the provided size from the output,
+along with their content. The size units are the same as for the
+"--size-limit" flag.
+
+Example: --min-size=10mb
+`,
+	)
+
+	appCmd.AddCommand(scanCmd)
+}
+
+func runScan(cmd *cobra.Command, args []string) error {
+	format := export.Format(strings.ToLower(strings.TrimSpace(scanFormat)))
+	if format != export.FormatJSON && format != export.FormatNDJSON {
+		return NewCLIError(fmt.Errorf("invalid value for format flag: %s", scanFormat))
+	}
+
+	minSize, err := parseSize(scanMinSize)
+	if err != nil {
+		return NewCLIError(fmt.Errorf("invalid value for min-size flag: %s", err.Error()))
+	}
+
+	// the depth limits the output only, so the directories' sizes include
+	// their entire content
+	t, err := scanTree(cmd, args, 0)
+	if err != nil {
+		return err
+	}
+
+	return export.Write(
+		cmd.OutOrStdout(),
+		t.Root(),
+		format,
+		export.WithMaxDepth(maxDepth),
+		export.WithMinSize(minSize),
+	)
+}
+
+// scanTree scans the root entry for the headless commands using the same tree
+// options as the UI, and collapses the directories on the provided depth. The
+// entries that cannot be read are reported to the command's error output.
+func scanTree(cmd *cobra.Command, args []string, collapseDepth int) (*structure.Tree, error) {
+	opts, err := treeOptions(collapseDepth)
+	if err != nil {
+		return nil, err
+	}
+
+	rootEntry, err := scanRoot(args)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	t := structure.NewTree(rootEntry, opts...)
+	done, errChan := t.TraverseAsync(ctx, true)
+
+	for err = range errChan {
+		_, _ = fmt.Fprintln(cmd.ErrOrStderr(), err.Error())
+	}
+
+	<-done
+
+	if t.Interrupted() {
+		return nil, errors.New("scan interrupted")
+	}
+
+	return t, nil
+}
+
+// scanRoot creates the root entry for the headless commands. The entry is the
+// provided path, or the current directory if no path is provided. An error will
+// be returned if the path is not a readable directory.
+func scanRoot(args []string) (*structure.Entry, error) {
+	path := "."
+	if len(args) != 0 {
+		path = args[0]
+	}
+
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("resolve absolute root path: %w", err)
+	}
+
+	fi, err := drive.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", path)
+	}
+
+	// the root's content is read by the traversal, so only the access is
+	// checked here.
+	dir, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	_ = dir.Close()
+
+	root := structure.NewDirEntry(path, fi.ModTime())
+	root.Ino = fi.InoKey()
+
+	return root, nil
+}
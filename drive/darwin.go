@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"sync"
 	"time"
 	"unsafe"
@@ -14,6 +15,10 @@ import (
 	"golang.org/x/sys/unix"
 )
 
+// statBlockSize defines the size of a single block reported by Stat_t.Blocks.
+// It does not depend on the file system's block size.
+const statBlockSize = 512
+
 var excludedVolumes = map[string]struct{}{
 	"/":                          {},
 	"/dev":                       {},
@@ -112,15 +117,20 @@ func ReadDirLegacy(path string) ([]FileInfo, error) {
 			}
 		}
 
-		fis = append(
-			fis,
-			FileInfo{
-				name:    child.Name(),
-				isDir:   child.IsDir(),
-				size:    child.Size(),
-				modTime: child.ModTime().Unix(),
-			},
-		)
+		fi := FileInfo{
+			name:    child.Name(),
+			isDir:   child.IsDir(),
+			isLink:  child.Mode()&os.ModeSymlink != 0,
+			size:    child.Size(),
+			usage:   child.Size(),
+			modTime: child.ModTime().Unix(),
+		}
+
+		if fi.isLink {
+			fi.target, _ = os.Readlink(filepath.Join(path, child.Name()))
+		}
+
+		fis = append(fis, fi)
 	}
 
 	return fis, nil
@@ -131,6 +141,10 @@ func NewFileInfo(name string, data *unix.Stat_t) FileInfo {
 		name:    name,
 		isDir:   data.Mode&unix.S_IFMT == unix.S_IFDIR,
 		size:    data.Size,
+		usage:   data.Blocks * statBlockSize,
+		ino:     InoKey{Dev: uint64(data.Dev), Ino: data.Ino},
+		links:   uint64(data.Nlink),
+		isLink:  data.Mode&unix.S_IFMT == unix.S_IFLNK,
 		modTime: time.Unix(int64(data.Mtim.Sec), int64(data.Mtim.Nsec)).Unix(),
 	}
 }
@@ -198,8 +212,14 @@ func ReadDir(path string) ([]FileInfo, error) {
 
 			err = unix.Fstatat(fd, name, &stat, unix.AT_SYMLINK_NOFOLLOW)
 			// TODO: consider making device check optional
-			if err == nil && InoFilterInstance.Add(stat.Ino) && rootStat.Dev == stat.Dev {
-				fis = append(fis, NewFileInfo(name, &stat))
+			if err == nil && stat.Dev == rootStat.Dev {
+				fi := NewFileInfo(name, &stat)
+
+				if fi.isLink {
+					fi.target = readLink(fd, name)
+				}
+
+				fis = append(fis, fi)
 			}
 
 			offset += int(dirent.Reclen)
@@ -241,6 +261,70 @@ func Explore(path string) error {
 	return nil
 }
 
+// Stat returns the FileInfo instance for the provided path. If the path is a
+// symbolic link, it will be resolved, and the info about the target will be
+// returned.
+func Stat(path string) (FileInfo, error) {
+	var stat unix.Stat_t
+
+	if err := unix.Stat(path, &stat); err != nil {
+		return FileInfo{}, fmt.Errorf("stat %s: %w", path, err)
+	}
+
+	return NewFileInfo(filepath.Base(path), &stat), nil
+}
+
+// Lstat returns the FileInfo instance for the provided path. Unlike Stat, the
+// symbolic links are not resolved, and the info about the link itself will be
+// returned along with its target.
+func Lstat(path string) (FileInfo, error) {
+	var stat unix.Stat_t
+
+	if err := unix.Lstat(path, &stat); err != nil {
+		return FileInfo{}, fmt.Errorf("lstat %s: %w", path, err)
+	}
+
+	fi := NewFileInfo(filepath.Base(path), &stat)
+
+	if fi.isLink {
+		fi.target, _ = os.Readlink(path)
+	}
+
+	return fi, nil
+}
+
+// DeviceID returns the identifier of the file system the path is located on. The
+// file system ID is used if it's known. Otherwise, the device number is used.
+// An empty string will be returned if the path cannot be read.
+func DeviceID(path string) string {
+	var statfs unix.Statfs_t
+
+	if err := unix.Statfs(path, &statfs); err == nil && statfs.Fsid.Val != [2]int32{} {
+		return fmt.Sprintf("fsid:%08x%08x", uint32(statfs.Fsid.Val[0]), uint32(statfs.Fsid.Val[1]))
+	}
+
+	var stat unix.Stat_t
+
+	if err := unix.Stat(path, &stat); err != nil {
+		return ""
+	}
+
+	return fmt.Sprintf("dev:%d", stat.Dev)
+}
+
+// readLink reads the target of the symbolic link relative to the directory file
+// descriptor. An empty string will be returned if the link cannot be read.
+func readLink(dirFd int, name string) string {
+	buf := make([]byte, unix.PathMax)
+
+	n, err := unix.Readlinkat(dirFd, name, buf)
+	if err != nil {
+		return ""
+	}
+
+	return string(buf[:n])
+}
+
 func bytePtrToString(b []byte) string {
 	for n := range b {
 		if b[n] == 0 {
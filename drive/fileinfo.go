@@ -4,13 +4,25 @@ import (
 	"os"
 )
 
+// InoKey identifies a single file on the system by its device and inode numbers.
+// The inode number alone is unique only within a single device.
+type InoKey struct {
+	Dev uint64
+	Ino uint64
+}
+
 // FileInfo defines a custom fs.FileInfo implementation for wrapping the results
 // from the file info system calls.
 type FileInfo struct {
 	name    string
 	modTime int64
 	size    int64
+	usage   int64
+	target  string
+	ino     InoKey
+	links   uint64
 	isDir   bool
+	isLink  bool
 }
 
 func (fi FileInfo) Name() string {
@@ -21,6 +33,25 @@ func (fi FileInfo) Size() int64 {
 	return fi.size
 }
 
+// Usage returns the number of bytes actually allocated for the file on the
+// disk. Depending on the file system, it can be bigger than the file size, e.g.,
+// for small files on large blocks, or smaller, e.g., for sparse files.
+func (fi FileInfo) Usage() int64 {
+	return fi.usage
+}
+
+// InoKey returns the device and inode numbers of the file. Both values will be
+// zero if the operating system does not provide them.
+func (fi FileInfo) InoKey() InoKey {
+	return fi.ino
+}
+
+// Links returns the number of hard links to the file. The value will be zero if
+// the operating system does not provide it.
+func (fi FileInfo) Links() uint64 {
+	return fi.links
+}
+
 func (fi FileInfo) Mode() os.FileMode {
 	// since we are not using the os.FileMode values we can skip the mapping
 	// from the Windows API file attributes.
@@ -35,6 +66,18 @@ func (fi FileInfo) IsDir() bool {
 	return fi.isDir
 }
 
+// IsSymlink reports whether the file is a symbolic link. A symbolic link is never
+// reported as a directory, even if its target is a directory.
+func (fi FileInfo) IsSymlink() bool {
+	return fi.isLink
+}
+
+// Target returns the path the symbolic link points to. The value will be empty
+// if the file is not a symbolic link or the link cannot be read.
+func (fi FileInfo) Target() string {
+	return fi.target
+}
+
 func (fi FileInfo) Sys() any {
 	return nil
 }
@@ -9,6 +9,7 @@ import (
 	"maps"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -21,6 +22,14 @@ const NTFSSbMagic = 0x5346544e
 
 const mountInfoPath = "/proc/self/mounts"
 
+// diskUUIDPath contains the symbolic links to the block devices named by the
+// UUIDs of their file systems.
+const diskUUIDPath = "/dev/disk/by-uuid"
+
+// statBlockSize defines the size of a single block reported by Stat_t.Blocks.
+// It does not depend on the file system's block size.
+const statBlockSize = 512
+
 var excludedFSTypes = map[int64]struct{}{
 	unix.CGROUP_SUPER_MAGIC:    {},
 	unix.CGROUP2_SUPER_MAGIC:   {},
@@ -83,6 +92,10 @@ func NewFileInfo(name string, data *unix.Stat_t) FileInfo {
 		name:    name,
 		isDir:   data.Mode&unix.S_IFMT == unix.S_IFDIR,
 		size:    data.Size,
+		usage:   data.Blocks * statBlockSize,
+		ino:     InoKey{Dev: data.Dev, Ino: data.Ino},
+		links:   data.Nlink,
+		isLink:  data.Mode&unix.S_IFMT == unix.S_IFLNK,
 		modTime: time.Unix(int64(data.Mtim.Sec), int64(data.Mtim.Nsec)).Unix(),
 	}
 }
@@ -223,8 +236,14 @@ func ReadDir(path string) ([]FileInfo, error) {
 			var stat unix.Stat_t
 
 			err = unix.Fstatat(fd, name, &stat, unix.AT_SYMLINK_NOFOLLOW)
-			if err == nil && InoFilterInstance.Add(stat.Ino) && stat.Dev == rootStat.Dev {
-				fis = append(fis, NewFileInfo(name, &stat))
+			if err == nil && stat.Dev == rootStat.Dev {
+				fi := NewFileInfo(name, &stat)
+
+				if fi.isLink {
+					fi.target = readLink(fd, name)
+				}
+
+				fis = append(fis, fi)
 			}
 
 			offset += int(dirent.Reclen)
@@ -266,6 +285,51 @@ func pathExcluded(path, name string) bool {
 	return false
 }
 
+// Stat returns the FileInfo instance for the provided path. If the path is a
+// symbolic link, it will be resolved, and the info about the target will be
+// returned.
+func Stat(path string) (FileInfo, error) {
+	var stat unix.Stat_t
+
+	if err := unix.Stat(path, &stat); err != nil {
+		return FileInfo{}, fmt.Errorf("stat %s: %w", path, err)
+	}
+
+	return NewFileInfo(filepath.Base(path), &stat), nil
+}
+
+// Lstat returns the FileInfo instance for the provided path. Unlike Stat, the
+// symbolic links are not resolved, and the info about the link itself will be
+// returned along with its target.
+func Lstat(path string) (FileInfo, error) {
+	var stat unix.Stat_t
+
+	if err := unix.Lstat(path, &stat); err != nil {
+		return FileInfo{}, fmt.Errorf("lstat %s: %w", path, err)
+	}
+
+	fi := NewFileInfo(filepath.Base(path), &stat)
+
+	if fi.isLink {
+		fi.target, _ = os.Readlink(path)
+	}
+
+	return fi, nil
+}
+
+// readLink reads the target of the symbolic link relative to the directory file
+// descriptor. An empty string will be returned if the link cannot be read.
+func readLink(dirFd int, name string) string {
+	buf := make([]byte, unix.PathMax)
+
+	n, err := unix.Readlinkat(dirFd, name, buf)
+	if err != nil {
+		return ""
+	}
+
+	return string(buf[:n])
+}
+
 func bytePtrToString(b []byte) string {
 	for n := range b {
 		if b[n] == 0 {
@@ -275,3 +339,67 @@ func bytePtrToString(b []byte) string {
 
 	return ""
 }
+
+// deviceIDs maps the device numbers to the identifiers already resolved by
+// DeviceID, so the block devices are listed once per file system.
+var deviceIDs = struct {
+	ids map[uint64]string
+	mx  sync.Mutex
+}{ids: make(map[uint64]string)}
+
+// DeviceID returns the identifier of the file system the path is located on. The
+// file system's UUID is used if it's known. Otherwise, the file system ID or the
+// device number is used. An empty string will be returned if the path cannot be
+// read. The identifiers are resolved once per device for the process lifetime.
+func DeviceID(path string) string {
+	var stat unix.Stat_t
+
+	if err := unix.Stat(path, &stat); err != nil {
+		return ""
+	}
+
+	deviceIDs.mx.Lock()
+	defer deviceIDs.mx.Unlock()
+
+	id, ok := deviceIDs.ids[stat.Dev]
+	if !ok {
+		id = resolveDeviceID(path, stat.Dev)
+		deviceIDs.ids[stat.Dev] = id
+	}
+
+	return id
+}
+
+func resolveDeviceID(path string, dev uint64) string {
+	if uuid := fsUUID(dev); len(uuid) != 0 {
+		return "uuid:" + uuid
+	}
+
+	var statfs unix.Statfs_t
+
+	if err := unix.Statfs(path, &statfs); err == nil && statfs.Fsid.Val != [2]int32{} {
+		return fmt.Sprintf("fsid:%08x%08x", uint32(statfs.Fsid.Val[0]), uint32(statfs.Fsid.Val[1]))
+	}
+
+	return fmt.Sprintf("dev:%d:%d", unix.Major(dev), unix.Minor(dev))
+}
+
+// fsUUID finds the UUID of the file system located on the block device with the
+// provided device number.
+func fsUUID(dev uint64) string {
+	links, err := os.ReadDir(diskUUIDPath)
+	if err != nil {
+		return ""
+	}
+
+	for _, link := range links {
+		var stat unix.Stat_t
+
+		err = unix.Stat(filepath.Join(diskUUIDPath, link.Name()), &stat)
+		if err == nil && stat.Mode&unix.S_IFMT == unix.S_IFBLK && stat.Rdev == dev {
+			return link.Name()
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,147 @@
+package drive
+
+import (
+	"errors"
+	"sync"
+)
+
+// WatchOp defines the kind of the file system change reported by the Watcher.
+type WatchOp uint8
+
+const (
+	// WatchCreate reports a new file or directory, including the ones moved
+	// into the watched tree.
+	WatchCreate WatchOp = iota + 1
+
+	// WatchRemove reports a removed file or directory, including the ones moved
+	// out of the watched tree.
+	WatchRemove
+
+	// WatchModify reports the changed content of the file.
+	WatchModify
+
+	// WatchRename reports a file or directory moved within the watched tree.
+	// The previous path is provided in WatchEvent.OldPath.
+	WatchRename
+)
+
+// WatchEvent contains a single file system change reported by the Watcher.
+type WatchEvent struct {
+	// Path contains the full path of the changed file or directory.
+	Path string
+
+	// OldPath contains the previous path of the renamed file or directory. It's
+	// empty for all operations except WatchRename.
+	OldPath string
+
+	// Op defines the kind of the change.
+	Op WatchOp
+}
+
+var (
+	// ErrWatchUnsupported is returned if the file system change notifications
+	// are not supported on the current system.
+	ErrWatchUnsupported = errors.New("drive: watching is not supported")
+
+	// ErrWatchOverflow is sent to the errors channel if the system dropped some
+	// change notifications. The watched tree must be rescanned to be accurate.
+	ErrWatchOverflow = errors.New("drive: watch events overflow")
+)
+
+// Watcher subscribes to the file system change notifications for the entire
+// directory tree and reports the changes via the events channel. The events
+// are reported in batches, and the changes of the same file within a single
+// batch are coalesced.
+//
+// The Watcher must be closed once it's not needed anymore, which also closes
+// the events and errors channels.
+type Watcher struct {
+	events chan []WatchEvent
+	errs   chan error
+	done   chan struct{}
+	close  func() error
+	once   sync.Once
+}
+
+func newWatcher() *Watcher {
+	return &Watcher{
+		events: make(chan []WatchEvent, 1),
+		errs:   make(chan error, 1),
+		done:   make(chan struct{}),
+	}
+}
+
+// Events returns the channel of the batched file system changes.
+func (w *Watcher) Events() <-chan []WatchEvent {
+	return w.events
+}
+
+// Errors returns the channel of the errors occurred while watching.
+func (w *Watcher) Errors() <-chan error {
+	return w.errs
+}
+
+// Done returns the channel that is closed once the Watcher is closed.
+func (w *Watcher) Done() <-chan struct{} {
+	return w.done
+}
+
+// Close stops watching and releases all related resources.
+func (w *Watcher) Close() error {
+	var err error
+
+	w.once.Do(func() {
+		close(w.done)
+
+		if w.close != nil {
+			err = w.close()
+		}
+	})
+
+	return err
+}
+
+func (w *Watcher) send(batch []WatchEvent) bool {
+	if len(batch) == 0 {
+		return true
+	}
+
+	select {
+	case w.events <- batch:
+		return true
+	case <-w.done:
+		return false
+	}
+}
+
+func (w *Watcher) sendErr(err error) {
+	select {
+	case w.errs <- err:
+	case <-w.done:
+	default:
+		// the errors are not critical and can be dropped if nobody reads them
+	}
+}
+
+// coalesce removes the repeated modifications of the same file within a single
+// batch, since only the final state of the file matters.
+func coalesce(batch []WatchEvent) []WatchEvent {
+	modified := make(map[string]struct{}, len(batch))
+	result := batch[:0]
+
+	for _, e := range batch {
+		if e.Op == WatchModify {
+			if _, ok := modified[e.Path]; ok {
+				continue
+			}
+
+			modified[e.Path] = struct{}{}
+		} else {
+			delete(modified, e.Path)
+		}
+
+		result = append(result, e)
+	}
+
+	return result
+}
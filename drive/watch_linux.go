@@ -0,0 +1,432 @@
+//go:build linux
+
+package drive
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	watchPollTimeout = 200
+	watchBufSize     = 64 << 10
+
+	fanotifyMetadataSize = int(unsafe.Sizeof(unix.FanotifyEventMetadata{}))
+
+	inotifyMask = unix.IN_CREATE | unix.IN_DELETE | unix.IN_MOVED_FROM |
+		unix.IN_MOVED_TO | unix.IN_MODIFY | unix.IN_ONLYDIR |
+		unix.IN_DONT_FOLLOW | unix.IN_EXCL_UNLINK
+
+	fanotifyMask = unix.FAN_CREATE | unix.FAN_DELETE | unix.FAN_MOVED_FROM |
+		unix.FAN_MOVED_TO | unix.FAN_MODIFY | unix.FAN_ONDIR
+)
+
+// NewWatcher starts watching the directory tree with the provided root. If the
+// root is a mount point, and the process has enough privileges, a single
+// fanotify mark for the entire file system is used. Otherwise, an inotify watch
+// is added to each directory within the tree.
+//
+// The number of inotify watches is limited by the system; hence, the tree might
+// be watched partially. In that case, the error will be sent to the errors
+// channel, but the watching continues.
+func NewWatcher(root string) (*Watcher, error) {
+	if isMountPoint(root) {
+		if w, err := newFanotifyWatcher(root); err == nil {
+			return w, nil
+		}
+	}
+
+	return newInotifyWatcher(root)
+}
+
+func isMountPoint(path string) bool {
+	if path == "/" {
+		return true
+	}
+
+	var stat, parentStat unix.Stat_t
+
+	if unix.Stat(path, &stat) != nil || unix.Stat(filepath.Dir(path), &parentStat) != nil {
+		return false
+	}
+
+	return stat.Dev != parentStat.Dev
+}
+
+// readLoop reads the notifications from the file descriptor until the watcher
+// is closed. The descriptor is polled with a timeout, so the closing of the
+// watcher is noticed even if there are no notifications.
+func (w *Watcher) readLoop(fd int, parse func([]byte) []WatchEvent, release func()) {
+	defer func() {
+		_ = unix.Close(fd)
+
+		if release != nil {
+			release()
+		}
+
+		close(w.events)
+		close(w.errs)
+	}()
+
+	var (
+		buf = make([]byte, watchBufSize)
+		fds = []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}} //nolint:gosec // fd fits
+	)
+
+	for {
+		select {
+		case <-w.done:
+			return
+		default:
+		}
+
+		n, err := unix.Poll(fds, watchPollTimeout)
+		if err != nil && !errors.Is(err, unix.EINTR) {
+			w.sendErr(fmt.Errorf("drive: poll watch events: %w", err))
+
+			return
+		}
+
+		if n <= 0 {
+			continue
+		}
+
+		n, err = unix.Read(fd, buf)
+		if err != nil {
+			if errors.Is(err, unix.EAGAIN) || errors.Is(err, unix.EINTR) {
+				continue
+			}
+
+			w.sendErr(fmt.Errorf("drive: read watch events: %w", err))
+
+			return
+		}
+
+		if !w.send(coalesce(parse(buf[:n]))) {
+			return
+		}
+	}
+}
+
+type inotifyWatcher struct {
+	*Watcher
+
+	// watches maps the watch descriptors to the watched directories. It's only
+	// accessed from the reading goroutine after the initial setup.
+	watches map[int]string
+	fd      int
+}
+
+type movedFrom struct {
+	idx   int
+	isDir bool
+}
+
+func newInotifyWatcher(root string) (*Watcher, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("drive: inotify init: %w", err)
+	}
+
+	iw := &inotifyWatcher{
+		Watcher: newWatcher(),
+		watches: make(map[int]string),
+		fd:      fd,
+	}
+
+	if _, err = unix.InotifyAddWatch(fd, root, inotifyMask); err != nil {
+		_ = unix.Close(fd)
+
+		return nil, fmt.Errorf("drive: inotify watch %s: %w", root, err)
+	}
+
+	iw.addTree(root)
+
+	go iw.readLoop(fd, iw.parse, nil)
+
+	return iw.Watcher, nil
+}
+
+// addTree adds the watches for the directory and all its subdirectories. The
+// unreadable directories are skipped.
+func (iw *inotifyWatcher) addTree(root string) {
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+
+		wd, err := unix.InotifyAddWatch(iw.fd, path, inotifyMask)
+		if err != nil {
+			if errors.Is(err, unix.ENOSPC) {
+				return err
+			}
+
+			return filepath.SkipDir
+		}
+
+		iw.watches[wd] = path
+
+		return nil
+	})
+	if err != nil {
+		iw.sendErr(fmt.Errorf("drive: inotify watches limit reached: %w", err))
+	}
+}
+
+func (iw *inotifyWatcher) parse(buf []byte) []WatchEvent {
+	var (
+		batch = make([]WatchEvent, 0, 16)
+		moved = make(map[uint32]movedFrom)
+	)
+
+	for offset := 0; offset+unix.SizeofInotifyEvent <= len(buf); {
+		event := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+		nameStart := offset + unix.SizeofInotifyEvent
+		offset = nameStart + int(event.Len)
+
+		if event.Mask&unix.IN_Q_OVERFLOW != 0 {
+			iw.sendErr(ErrWatchOverflow)
+
+			continue
+		}
+
+		if event.Mask&unix.IN_IGNORED != 0 {
+			delete(iw.watches, int(event.Wd))
+
+			continue
+		}
+
+		dir, ok := iw.watches[int(event.Wd)]
+		if !ok || event.Len == 0 {
+			continue
+		}
+
+		name := string(bytes.TrimRight(buf[nameStart:offset], "\x00"))
+		path := filepath.Join(dir, name)
+		isDir := event.Mask&unix.IN_ISDIR != 0
+
+		switch {
+		case event.Mask&unix.IN_MOVED_FROM != 0:
+			moved[event.Cookie] = movedFrom{idx: len(batch), isDir: isDir}
+			batch = append(batch, WatchEvent{Path: path, Op: WatchRemove})
+		case event.Mask&unix.IN_MOVED_TO != 0:
+			if from, ok := moved[event.Cookie]; ok {
+				delete(moved, event.Cookie)
+
+				oldPath := batch[from.idx].Path
+				batch[from.idx] = WatchEvent{Path: path, OldPath: oldPath, Op: WatchRename}
+
+				if isDir {
+					iw.renameWatches(oldPath, path)
+				}
+
+				continue
+			}
+
+			if isDir {
+				iw.addTree(path)
+			}
+
+			batch = append(batch, WatchEvent{Path: path, Op: WatchCreate})
+		case event.Mask&unix.IN_CREATE != 0:
+			if isDir {
+				iw.addTree(path)
+			}
+
+			batch = append(batch, WatchEvent{Path: path, Op: WatchCreate})
+		case event.Mask&unix.IN_DELETE != 0:
+			batch = append(batch, WatchEvent{Path: path, Op: WatchRemove})
+		case event.Mask&unix.IN_MODIFY != 0:
+			batch = append(batch, WatchEvent{Path: path, Op: WatchModify})
+		}
+	}
+
+	// the directories moved out of the watched tree must not be watched anymore
+	for _, from := range moved {
+		if from.isDir {
+			iw.removeWatches(batch[from.idx].Path)
+		}
+	}
+
+	return batch
+}
+
+func (iw *inotifyWatcher) renameWatches(oldPath, newPath string) {
+	for wd, path := range iw.watches {
+		if path == oldPath || strings.HasPrefix(path, oldPath+string(os.PathSeparator)) {
+			iw.watches[wd] = newPath + path[len(oldPath):]
+		}
+	}
+}
+
+func (iw *inotifyWatcher) removeWatches(root string) {
+	for wd, path := range iw.watches {
+		if path == root || strings.HasPrefix(path, root+string(os.PathSeparator)) {
+			//nolint:gosec // the watch descriptor fits
+			_, _ = unix.InotifyRmWatch(iw.fd, uint32(wd))
+			delete(iw.watches, wd)
+		}
+	}
+}
+
+type fanotifyWatcher struct {
+	*Watcher
+
+	root    string
+	mountFd int
+}
+
+func newFanotifyWatcher(root string) (*Watcher, error) {
+	fd, err := unix.FanotifyInit(
+		unix.FAN_CLASS_NOTIF|unix.FAN_CLOEXEC|unix.FAN_NONBLOCK|unix.FAN_REPORT_DFID_NAME,
+		unix.O_RDONLY|unix.O_LARGEFILE,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("drive: fanotify init: %w", err)
+	}
+
+	err = unix.FanotifyMark(
+		fd,
+		unix.FAN_MARK_ADD|unix.FAN_MARK_FILESYSTEM,
+		fanotifyMask,
+		unix.AT_FDCWD,
+		root,
+	)
+	if err != nil {
+		_ = unix.Close(fd)
+
+		return nil, fmt.Errorf("drive: fanotify mark %s: %w", root, err)
+	}
+
+	mountFd, err := unix.Open(root, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		_ = unix.Close(fd)
+
+		return nil, fmt.Errorf("drive: open mount %s: %w", root, err)
+	}
+
+	fw := &fanotifyWatcher{Watcher: newWatcher(), root: root, mountFd: mountFd}
+
+	go fw.readLoop(fd, fw.parse, func() { _ = unix.Close(mountFd) })
+
+	return fw.Watcher, nil
+}
+
+func (fw *fanotifyWatcher) parse(buf []byte) []WatchEvent {
+	batch := make([]WatchEvent, 0, 16)
+
+	for offset := 0; offset+fanotifyMetadataSize <= len(buf); {
+		meta := (*unix.FanotifyEventMetadata)(unsafe.Pointer(&buf[offset]))
+		if int(meta.Event_len) < fanotifyMetadataSize ||
+			offset+int(meta.Event_len) > len(buf) {
+			break
+		}
+
+		event := buf[offset+int(meta.Metadata_len) : offset+int(meta.Event_len)]
+		offset += int(meta.Event_len)
+
+		if meta.Fd >= 0 {
+			_ = unix.Close(int(meta.Fd))
+		}
+
+		if meta.Mask&unix.FAN_Q_OVERFLOW != 0 {
+			fw.sendErr(ErrWatchOverflow)
+
+			continue
+		}
+
+		path, ok := fw.eventPath(event)
+		if !ok {
+			continue
+		}
+
+		// the same event might contain several merged changes
+		if meta.Mask&(unix.FAN_DELETE|unix.FAN_MOVED_FROM) != 0 {
+			batch = append(batch, WatchEvent{Path: path, Op: WatchRemove})
+		}
+
+		if meta.Mask&(unix.FAN_CREATE|unix.FAN_MOVED_TO) != 0 {
+			batch = append(batch, WatchEvent{Path: path, Op: WatchCreate})
+		}
+
+		if meta.Mask&unix.FAN_MODIFY != 0 {
+			batch = append(batch, WatchEvent{Path: path, Op: WatchModify})
+		}
+	}
+
+	return batch
+}
+
+// eventPath resolves the full path of the changed entry from the event's info
+// records. The record contains the parent directory's file handle and the
+// entry's name. A "false" value will be returned if the path cannot be resolved
+// or is located outside the watched root.
+func (fw *fanotifyWatcher) eventPath(info []byte) (string, bool) {
+	// struct fanotify_event_info_header: info_type, pad, len
+	const headerSize, fsidSize, handleHeaderSize = 4, 8, 8
+
+	for len(info) >= headerSize {
+		infoType, infoLen := info[0], int(binary.NativeEndian.Uint16(info[2:4]))
+		if infoLen < headerSize || infoLen > len(info) {
+			return "", false
+		}
+
+		if infoType != unix.FAN_EVENT_INFO_TYPE_DFID_NAME {
+			info = info[infoLen:]
+
+			continue
+		}
+
+		record := info[headerSize+fsidSize : infoLen]
+		if len(record) < handleHeaderSize {
+			return "", false
+		}
+
+		handleSize := int(binary.NativeEndian.Uint32(record[0:4]))
+		handleType := int32(binary.NativeEndian.Uint32(record[4:8])) //nolint:gosec // int32 by design
+
+		if len(record) < handleHeaderSize+handleSize {
+			return "", false
+		}
+
+		handle := bytes.Clone(record[handleHeaderSize : handleHeaderSize+handleSize])
+		name := string(bytes.TrimRight(record[handleHeaderSize+handleSize:], "\x00"))
+
+		dirFd, err := unix.OpenByHandleAt(
+			fw.mountFd,
+			unix.NewFileHandle(handleType, handle),
+			unix.O_PATH|unix.O_CLOEXEC,
+		)
+		if err != nil {
+			return "", false
+		}
+
+		dir, err := os.Readlink("/proc/self/fd/" + strconv.Itoa(dirFd))
+		_ = unix.Close(dirFd)
+
+		if err != nil {
+			return "", false
+		}
+
+		path := filepath.Join(dir, name)
+
+		if fw.root != "/" && path != fw.root &&
+			!strings.HasPrefix(path, fw.root+string(os.PathSeparator)) {
+			return "", false
+		}
+
+		return path, true
+	}
+
+	return "", false
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package drive
+
+// NewWatcher is not supported on the current system and always returns the
+// ErrWatchUnsupported error.
+func NewWatcher(_ string) (*Watcher, error) {
+	return nil, ErrWatchUnsupported
+}
@@ -4,6 +4,8 @@ package drive
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"runtime"
 	"syscall"
 	"time"
@@ -48,15 +50,74 @@ type win32finddata1 struct {
 	AlternateFileName [14]uint16
 }
 
+// NewFileInfo maps the find data to a FileInfo instance. The find data does not
+// contain the allocation size, and requesting it separately for each file is too
+// expensive; hence, the allocated size always equals the apparent size.
+//
+// Symbolic links and directory junctions are reported as links rather than
+// directories. Other reparse points, e.g., cloud files placeholders, are
+// treated as regular entries.
 func NewFileInfo(data *win32finddata1) FileInfo {
+	size := int64(data.FileSizeHigh)<<32 + int64(data.FileSizeLow)
+
+	isLink := data.FileAttributes&winapi.FILE_ATTRIBUTE_REPARSE_POINT != 0 &&
+		(data.Reserved0 == winapi.IO_REPARSE_TAG_SYMLINK ||
+			data.Reserved0 == winapi.IO_REPARSE_TAG_MOUNT_POINT)
+
 	return FileInfo{
 		name:    syscall.UTF16ToString(data.FileName[:]),
-		isDir:   data.FileAttributes&16 != 0,
-		size:    int64(data.FileSizeHigh)<<32 + int64(data.FileSizeLow),
+		isDir:   data.FileAttributes&16 != 0 && !isLink,
+		isLink:  isLink,
+		size:    size,
+		usage:   size,
 		modTime: time.Unix(0, data.LastWriteTime.Nanoseconds()).Unix(),
 	}
 }
 
+// Stat returns the FileInfo instance for the provided path. If the path is a
+// symbolic link, it will be resolved, and the info about the target will be
+// returned. The device and inode numbers are not available; hence, the InoKey
+// value will always be empty.
+func Stat(path string) (FileInfo, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return FileInfo{}, fmt.Errorf("drive: stat %s: %w", path, err)
+	}
+
+	return FileInfo{
+		name:    fi.Name(),
+		isDir:   fi.IsDir(),
+		size:    fi.Size(),
+		usage:   fi.Size(),
+		modTime: fi.ModTime().Unix(),
+	}, nil
+}
+
+// Lstat returns the FileInfo instance for the provided path. Unlike Stat, the
+// symbolic links are not resolved, and the info about the link itself will be
+// returned along with its target.
+func Lstat(path string) (FileInfo, error) {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return FileInfo{}, fmt.Errorf("drive: lstat %s: %w", path, err)
+	}
+
+	info := FileInfo{
+		name:    fi.Name(),
+		isDir:   fi.IsDir(),
+		size:    fi.Size(),
+		usage:   fi.Size(),
+		modTime: fi.ModTime().Unix(),
+	}
+
+	if fi.Mode()&os.ModeSymlink != 0 {
+		info.isDir, info.isLink = false, true
+		info.target, _ = os.Readlink(path)
+	}
+
+	return info, nil
+}
+
 type handleWrapper struct {
 	handle syscall.Handle
 }
@@ -280,7 +341,13 @@ func ReadDir(path string) ([]FileInfo, error) {
 
 		//nolint:staticcheck // the alternative is even worse
 		if !(name[0] == '.' && (name[1] == 0 || (name[1] == '.' && name[2] == 0))) {
-			fis = append(fis, NewFileInfo(&data))
+			fi := NewFileInfo(&data)
+
+			if fi.isLink {
+				fi.target, _ = os.Readlink(path + "\\" + fi.name)
+			}
+
+			fis = append(fis, fi)
 		}
 
 		result, _, _ := syscall.SyscallN(
@@ -296,6 +363,25 @@ func ReadDir(path string) ([]FileInfo, error) {
 	return fis, nil
 }
 
+// DeviceID returns the identifier of the volume the path is located on. The
+// volume's serial number is used as the identifier. An empty string will be
+// returned if the volume information cannot be read.
+func DeviceID(path string) string {
+	var serial uint32
+
+	root, err := winapi.UTF16PtrFromString(filepath.VolumeName(path) + `\`)
+	if err != nil {
+		return ""
+	}
+
+	err = winapi.GetVolumeInformation(root, nil, 0, &serial, nil, nil, nil, 0)
+	if err != nil {
+		return ""
+	}
+
+	return fmt.Sprintf("serial:%08x", serial)
+}
+
 func toUintptr[T any](val *T) uintptr {
 	return uintptr(unsafe.Pointer(val))
 }
@@ -0,0 +1,189 @@
+package dump
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/crumbyte/noxdir/structure"
+)
+
+const maxDULineSize = 1024 * 1024
+
+// duUnits defines the units of the sizes in the du listing.
+type duUnits uint8
+
+const (
+	// duUnitsAuto detects the units by the sizes, see readDU.
+	duUnitsAuto duUnits = iota
+	duUnitsBytes
+	duUnitsKilobytes
+)
+
+type duLine struct {
+	path string
+	size int64
+}
+
+// readDU reads the du listing, where each line contains the entry's size and
+// path. The directories are listed after their content, so the last line is
+// the root. Any path that has listed child entries is a directory, and the
+// directory's size is the total size of its content.
+//
+// The listing doesn't contain the units, so unless they're provided, they're
+// detected by the sizes: the "du -k" sizes are the number of the allocated
+// kilobytes, which are multiples of the file system's block size. Hence, the
+// listing is read as "du -k" if all sizes are multiples of 4; otherwise, the
+// sizes are read as the apparent sizes in bytes written by "du -ab".
+//
+// The entries without content are read as files, unless the listing is written
+// by "du -k" without the "-a" flag, which lists the directories only. Such a
+// listing is recognized by the sizes as well: a directory takes at least one
+// block, so the listing that contains the empty entries contains the files.
+func readDU(r io.Reader, units duUnits) (*structure.Entry, error) {
+	lines, err := readDULines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(lines) == 0 {
+		return nil, errors.New("read du listing: no entries")
+	}
+
+	kilobytes := units == duUnitsKilobytes
+
+	if units == duUnitsAuto {
+		kilobytes = true
+
+		for _, l := range lines {
+			kilobytes = kilobytes && l.size%4 == 0
+		}
+	}
+
+	rootPath := lines[len(lines)-1].path
+
+	// the directories' sizes include their content, which is added to the
+	// directories once the tree is built.
+	entries := make(map[string]*structure.Entry, len(lines))
+	contentSize := make(map[string]int64, len(lines))
+
+	for _, l := range lines {
+		rel, err := filepath.Rel(rootPath, l.path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("read du listing: %s is not within %s", l.path, rootPath)
+		}
+
+		if rel != "." {
+			contentSize[filepath.Dir(rel)] += l.size
+		}
+	}
+
+	dirsOnly := kilobytes
+
+	for _, l := range lines {
+		rel, _ := filepath.Rel(rootPath, l.path)
+
+		if _, ok := contentSize[rel]; !ok && rel != "." && l.size == 0 {
+			dirsOnly = false
+		}
+	}
+
+	for _, l := range lines {
+		rel, _ := filepath.Rel(rootPath, l.path)
+
+		size := l.size
+		if kilobytes {
+			size *= 1024
+		}
+
+		if _, ok := contentSize[rel]; !ok && !dirsOnly {
+			entries[rel] = structure.NewFileEntry(l.path, size, 0)
+
+			continue
+		}
+
+		ownSize := max(0, l.size-contentSize[rel])
+		if kilobytes {
+			ownSize *= 1024
+		}
+
+		dir := structure.NewDirEntry(l.path, 0)
+		dir.Size, dir.Usage = ownSize, ownSize
+
+		entries[rel] = dir
+	}
+
+	root := entries["."]
+
+	if !root.IsDir {
+		return nil, errors.New("read du listing: root is not a directory")
+	}
+
+	for _, l := range lines {
+		rel, _ := filepath.Rel(rootPath, l.path)
+		if rel == "." {
+			continue
+		}
+
+		parent := ensureDUDir(entries, rootPath, filepath.Dir(rel))
+		parent.AddChild(entries[rel])
+	}
+
+	return root, nil
+}
+
+// ensureDUDir returns the directory entry by its relative path. The directory
+// that is not listed is created along with its missing parents.
+func ensureDUDir(entries map[string]*structure.Entry, rootPath, rel string) *structure.Entry {
+	if dir, ok := entries[rel]; ok {
+		return dir
+	}
+
+	dir := structure.NewDirEntry(filepath.Join(rootPath, rel), 0)
+	entries[rel] = dir
+
+	ensureDUDir(entries, rootPath, filepath.Dir(rel)).AddChild(dir)
+
+	return dir
+}
+
+func readDULines(r io.Reader) ([]duLine, error) {
+	var lines []duLine
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxDULineSize)
+
+	for scanner.Scan() {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if len(strings.TrimSpace(text)) == 0 {
+			continue
+		}
+
+		rawSize, path, ok := strings.Cut(text, "\t")
+		if !ok {
+			rawSize, path, ok = strings.Cut(text, " ")
+		}
+
+		size, err := strconv.ParseInt(strings.TrimSpace(rawSize), 10, 64)
+		if !ok || err != nil || size < 0 {
+			return nil, fmt.Errorf("read du listing: invalid line: %q", text)
+		}
+
+		lines = append(lines, duLine{path: filepath.Clean(path), size: size})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read du listing: %w", err)
+	}
+
+	// the grand total line written by "du -c" is not an entry
+	if n := len(lines); n > 1 && lines[n-1].path == "total" {
+		lines = lines[:n-1]
+	}
+
+	return lines, nil
+}
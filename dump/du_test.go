@@ -0,0 +1,123 @@
+package dump_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/crumbyte/noxdir/dump"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRead_DUBytes(t *testing.T) {
+	listing := strings.Join([]string{
+		"1001\t./a/file.txt",
+		"5097\t./a",
+		"7\t./b.bin",
+		"9200\t.",
+		"9200\ttotal",
+	}, "\n")
+
+	root, format, err := dump.Read(strings.NewReader("\n" + listing + "\n"))
+	require.NoError(t, err)
+	require.Equal(t, dump.FormatDU, format)
+
+	require.Equal(t, ".", root.Path())
+	require.EqualValues(t, 9200, root.Size)
+	require.EqualValues(t, 1, root.TotalDirs)
+	require.EqualValues(t, 2, root.TotalFiles)
+
+	a := root.GetChild("a")
+	require.NotNil(t, a)
+	require.True(t, a.IsDir)
+	require.EqualValues(t, 5097, a.Size)
+
+	file := a.GetChild("file.txt")
+	require.NotNil(t, file)
+	require.False(t, file.IsDir)
+	require.EqualValues(t, 1001, file.Size)
+}
+
+func TestRead_DUKilobytes(t *testing.T) {
+	listing := strings.Join([]string{
+		"8\t/var/log/apt",
+		"120\t/var/log",
+		"4\t/var/cache",
+		"200\t/var",
+	}, "\n")
+
+	root, _, err := dump.Read(strings.NewReader(listing))
+	require.NoError(t, err)
+
+	require.Equal(t, filepath.FromSlash("/var"), root.Path())
+	require.EqualValues(t, 200*1024, root.Size)
+	require.EqualValues(t, 200*1024, root.Usage)
+
+	// the listing contains only the directories
+	cache := root.GetChild("cache")
+	require.NotNil(t, cache)
+	require.True(t, cache.IsDir)
+	require.EqualValues(t, 4*1024, cache.Size)
+	require.EqualValues(t, 3, root.TotalDirs)
+}
+
+func TestReadAs_DU(t *testing.T) {
+	// the sizes are multiples of 4, so the units would be detected wrong
+	listing := strings.Join([]string{
+		"4096\t/srv/data/block.bin",
+		"8192\t/srv/data",
+		"12288\t/srv",
+	}, "\n")
+
+	root, err := dump.ReadAs(strings.NewReader(listing), dump.FormatDUBytes)
+	require.NoError(t, err)
+
+	require.EqualValues(t, 12288, root.Size)
+
+	block := root.GetChild("data").GetChild("block.bin")
+	require.NotNil(t, block)
+	require.False(t, block.IsDir)
+	require.EqualValues(t, 4096, block.Size)
+
+	root, err = dump.ReadAs(strings.NewReader(listing), dump.FormatDUKilobytes)
+	require.NoError(t, err)
+	require.EqualValues(t, 12288*1024, root.Size)
+
+	_, err = dump.ReadAs(strings.NewReader(listing), dump.Format("du-m"))
+	require.ErrorIs(t, err, dump.ErrUnknownFormat)
+}
+
+func TestRead_DUKilobytesWithFiles(t *testing.T) {
+	// the "du -ak" listing contains the files, including the empty ones
+	listing := strings.Join([]string{
+		"0\t/var/log/apt/lock",
+		"8\t/var/log/apt/history.log",
+		"12\t/var/log/apt",
+		"16\t/var/log",
+	}, "\n")
+
+	root, _, err := dump.Read(strings.NewReader(listing))
+	require.NoError(t, err)
+
+	apt := root.GetChild("apt")
+	require.NotNil(t, apt)
+	require.True(t, apt.IsDir)
+	require.EqualValues(t, 2, apt.LocalFiles)
+
+	history := apt.GetChild("history.log")
+	require.NotNil(t, history)
+	require.False(t, history.IsDir)
+	require.EqualValues(t, 8*1024, history.Size)
+}
+
+func TestRead_DUInvalid(t *testing.T) {
+	_, _, err := dump.Read(strings.NewReader("12\t/a\nbroken\n"))
+	require.Error(t, err)
+
+	_, _, err = dump.Read(strings.NewReader("12\t/a\n4\t/b\n"))
+	require.Error(t, err)
+
+	_, _, err = dump.Read(strings.NewReader("  "))
+	require.ErrorIs(t, err, dump.ErrUnknownFormat)
+}
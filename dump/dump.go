@@ -0,0 +1,97 @@
+// Package dump reads the disk usage listings produced by other tools, e.g., ncdu
+// or du, into the tree of entries, so they can be browsed the same way as the
+// scanned file system.
+package dump
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"unicode"
+
+	"github.com/crumbyte/noxdir/structure"
+)
+
+// ErrUnknownFormat is returned if the input is neither an ncdu JSON dump nor a
+// du listing.
+var ErrUnknownFormat = errors.New("unknown dump format")
+
+// Format defines the format of the dump.
+type Format string
+
+const (
+	// FormatNCDU is the JSON dump written by "ncdu -o" or "noxdir export --ncdu".
+	FormatNCDU Format = "ncdu"
+
+	// FormatDU is the listing written by "du -ab" or "du -k", where each line
+	// contains the entry's size and path separated by a tab. The sizes' units
+	// are detected by the sizes themselves; see FormatDUBytes and
+	// FormatDUKilobytes to read the listing with the known units.
+	FormatDU Format = "du"
+
+	// FormatDUBytes is the listing written by "du -ab", where the sizes are the
+	// apparent sizes in bytes.
+	FormatDUBytes Format = "du-b"
+
+	// FormatDUKilobytes is the listing written by "du -k" or "du -ak", where the
+	// sizes are the numbers of the allocated kilobytes.
+	FormatDUKilobytes Format = "du-k"
+)
+
+// Read reads the dump from the reader and returns the root entry of the tree
+// it describes along with the detected dump's format. The format is detected by
+// the first non-space character of the input: the ncdu dump is a JSON array,
+// while each du line starts with a number.
+func Read(r io.Reader) (*structure.Entry, Format, error) {
+	br := bufio.NewReader(r)
+
+	for {
+		c, _, err := br.ReadRune()
+		if errors.Is(err, io.EOF) {
+			return nil, "", ErrUnknownFormat
+		}
+
+		if err != nil {
+			return nil, "", err
+		}
+
+		if unicode.IsSpace(c) {
+			continue
+		}
+
+		if err = br.UnreadRune(); err != nil {
+			return nil, "", err
+		}
+
+		switch {
+		case c == '[':
+			root, err := readNCDU(br)
+
+			return root, FormatNCDU, err
+		case c >= '0' && c <= '9':
+			root, err := readDU(br, duUnitsAuto)
+
+			return root, FormatDU, err
+		}
+
+		return nil, "", ErrUnknownFormat
+	}
+}
+
+// ReadAs reads the dump of the provided format from the reader and returns the
+// root entry of the tree it describes. Unlike Read, the format is not detected,
+// e.g., to read the du listing whose sizes' units cannot be detected reliably.
+func ReadAs(r io.Reader, format Format) (*structure.Entry, error) {
+	switch format {
+	case FormatNCDU:
+		return readNCDU(r)
+	case FormatDU:
+		return readDU(r, duUnitsAuto)
+	case FormatDUBytes:
+		return readDU(r, duUnitsBytes)
+	case FormatDUKilobytes:
+		return readDU(r, duUnitsKilobytes)
+	}
+
+	return nil, ErrUnknownFormat
+}
@@ -0,0 +1,208 @@
+package dump
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/crumbyte/noxdir/drive"
+	"github.com/crumbyte/noxdir/structure"
+)
+
+// ncduInfo contains the ncdu dump's information block of a single entry.
+type ncduInfo struct {
+	Name     string
+	Excluded string
+	ASize    int64
+	DSize    int64
+	MTime    int64
+	Dev      uint64
+	Ino      uint64
+	NLink    uint64
+	HardLink bool
+}
+
+// ncduReader builds the tree from the ncdu dump. The files with multiple hard
+// links are counted only once, the same way ncdu does.
+type ncduReader struct {
+	dec       *json.Decoder
+	hardLinks map[drive.InoKey]struct{}
+}
+
+// readNCDU reads the ncdu JSON dump. The dump is an array of the format's major
+// and minor versions, the metadata, and the root directory. Each directory is
+// an array of its information block followed by its child entries, while the
+// rest of the entries are the information blocks only.
+func readNCDU(r io.Reader) (*structure.Entry, error) {
+	nr := &ncduReader{
+		dec:       json.NewDecoder(r),
+		hardLinks: make(map[drive.InoKey]struct{}),
+	}
+
+	if err := nr.expectDelim('['); err != nil {
+		return nil, err
+	}
+
+	var major, minor int
+
+	if err := nr.dec.Decode(&major); err != nil {
+		return nil, fmt.Errorf("read ncdu version: %w", err)
+	}
+
+	if major != 1 {
+		return nil, fmt.Errorf("unsupported ncdu dump version: %d", major)
+	}
+
+	var meta json.RawMessage
+
+	if err := errors.Join(nr.dec.Decode(&minor), nr.dec.Decode(&meta)); err != nil {
+		return nil, fmt.Errorf("read ncdu metadata: %w", err)
+	}
+
+	if err := nr.expectDelim('['); err != nil {
+		return nil, err
+	}
+
+	return nr.readDir(0)
+}
+
+// readDir reads the directory whose opening bracket was already read. The
+// directory's own size is kept as its initial size, so the totals match the
+// ones shown by ncdu.
+func (nr *ncduReader) readDir(parentDev uint64) (*structure.Entry, error) {
+	if err := nr.expectDelim('{'); err != nil {
+		return nil, err
+	}
+
+	info, err := nr.readInfo(parentDev)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := structure.NewDirEntry(info.Name, info.MTime)
+	dir.Size, dir.Usage = info.ASize, info.DSize
+	dir.Ino = drive.InoKey{Dev: info.Dev, Ino: info.Ino}
+
+	for nr.dec.More() {
+		token, err := nr.dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", info.Name, err)
+		}
+
+		var child *structure.Entry
+
+		switch token {
+		case json.Delim('['):
+			child, err = nr.readDir(info.Dev)
+		case json.Delim('{'):
+			child, err = nr.readItem(info.Dev)
+		default:
+			err = fmt.Errorf("unexpected token in %s: %v", info.Name, token)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if child != nil {
+			dir.AddChild(child)
+		}
+	}
+
+	return dir, nr.expectDelim(']')
+}
+
+// readItem reads the entry that is not a directory, or a directory that was
+// excluded from the scanning. It returns nil if the entry is a hard link to a
+// file that was already read.
+func (nr *ncduReader) readItem(parentDev uint64) (*structure.Entry, error) {
+	info, err := nr.readInfo(parentDev)
+	if err != nil {
+		return nil, err
+	}
+
+	key := drive.InoKey{Dev: info.Dev, Ino: info.Ino}
+
+	if len(info.Excluded) != 0 {
+		placeholder := structure.NewDirEntry(info.Name, info.MTime)
+		placeholder.IsExcluded, placeholder.Ino = true, key
+
+		return placeholder, nil
+	}
+
+	if (info.HardLink || info.NLink > 1) && info.Ino != 0 {
+		if _, ok := nr.hardLinks[key]; ok {
+			return nil, nil
+		}
+
+		nr.hardLinks[key] = struct{}{}
+	}
+
+	file := structure.NewFileEntry(info.Name, info.ASize, info.MTime)
+	file.Usage, file.Ino = info.DSize, key
+
+	return file, nil
+}
+
+// readInfo reads the information block whose opening brace was already read.
+// The unknown fields are skipped, and the device is inherited from the parent
+// if it's not set.
+func (nr *ncduReader) readInfo(parentDev uint64) (ncduInfo, error) {
+	info := ncduInfo{Dev: parentDev}
+
+	for nr.dec.More() {
+		token, err := nr.dec.Token()
+		if err != nil {
+			return info, fmt.Errorf("read ncdu entry: %w", err)
+		}
+
+		var value any
+
+		switch token {
+		case "name":
+			value = &info.Name
+		case "excluded":
+			value = &info.Excluded
+		case "asize":
+			value = &info.ASize
+		case "dsize":
+			value = &info.DSize
+		case "mtime":
+			value = &info.MTime
+		case "dev":
+			value = &info.Dev
+		case "ino":
+			value = &info.Ino
+		case "nlink":
+			value = &info.NLink
+		case "hlnkc":
+			value = &info.HardLink
+		default:
+			value = &json.RawMessage{}
+		}
+
+		if err = nr.dec.Decode(value); err != nil {
+			return info, fmt.Errorf("read ncdu entry field %v: %w", token, err)
+		}
+	}
+
+	if len(info.Name) == 0 {
+		return info, errors.New("read ncdu entry: missing name")
+	}
+
+	return info, nr.expectDelim('}')
+}
+
+func (nr *ncduReader) expectDelim(delim json.Delim) error {
+	token, err := nr.dec.Token()
+	if err != nil {
+		return fmt.Errorf("read ncdu dump: %w", err)
+	}
+
+	if token != delim {
+		return fmt.Errorf("read ncdu dump: expected %s, got %v", delim, token)
+	}
+
+	return nil
+}
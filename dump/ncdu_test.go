@@ -0,0 +1,82 @@
+package dump_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/crumbyte/noxdir/dump"
+	"github.com/crumbyte/noxdir/export"
+	"github.com/crumbyte/noxdir/structure"
+
+	"github.com/stretchr/testify/require"
+)
+
+const ncduDump = `[1,2,{"progname":"ncdu","progver":"1.19","timestamp":1700000000},
+[{"name":"/data","asize":4096,"dsize":4096,"dev":2049,"ino":2,"mtime":1700000000},
+{"name":"a.log","asize":1000,"dsize":4096,"ino":12,"mtime":1700000001,"extra":[1,{}]},
+[{"name":"nested","asize":4096,"dsize":4096,"ino":13,"mtime":1700000002},
+{"name":"link","asize":500,"dsize":4096,"ino":14,"hlnkc":true}],
+{"name":"copy","asize":500,"dsize":4096,"ino":14,"nlink":2},
+{"name":"proc","excluded":"kernfs"}]]`
+
+func TestRead_NCDU(t *testing.T) {
+	root, format, err := dump.Read(strings.NewReader(ncduDump))
+	require.NoError(t, err)
+	require.Equal(t, dump.FormatNCDU, format)
+
+	require.Equal(t, "/data", root.Path())
+	require.True(t, root.IsDir)
+
+	// the hard link is counted once, and the directories' own sizes are kept
+	require.EqualValues(t, 4096+1000+4096+500, root.Size)
+	require.EqualValues(t, 4096*4, root.Usage)
+	require.EqualValues(t, 2, root.TotalDirs)
+	require.EqualValues(t, 2, root.TotalFiles)
+
+	nested := root.GetChild("nested")
+	require.NotNil(t, nested)
+	require.EqualValues(t, 2049, nested.Ino.Dev)
+	require.EqualValues(t, 1700000002, nested.ModTime)
+
+	proc := root.GetChild("proc")
+	require.NotNil(t, proc)
+	require.True(t, proc.IsExcluded)
+	require.Nil(t, root.GetChild("copy"))
+}
+
+func TestRead_NCDUExported(t *testing.T) {
+	root := structure.NewDirEntry(filepath.Join(string(filepath.Separator), "root"), 0)
+	dir := structure.NewDirEntry("dir", 0)
+
+	root.AddChild(dir)
+	dir.AddChild(structure.NewFileEntry("file", 100, 10))
+	root.AddChild(structure.NewFileEntry("other", 10, 20))
+
+	var buf bytes.Buffer
+
+	require.NoError(t, export.Write(&buf, root, export.FormatNCDU, export.WithMaxDepth(1)))
+
+	restored, _, err := dump.Read(&buf)
+	require.NoError(t, err)
+	require.Equal(t, root.Path(), restored.Path())
+	require.Equal(t, root.Size, restored.Size)
+
+	// the pruned content is kept as the directory's own size
+	restoredDir := restored.GetChild("dir")
+	require.NotNil(t, restoredDir)
+	require.EqualValues(t, 100, restoredDir.Size)
+	require.False(t, restoredDir.HasChild())
+}
+
+func TestRead_NCDUInvalid(t *testing.T) {
+	_, _, err := dump.Read(strings.NewReader(`[2,0,{},[{"name":"/"}]]`))
+	require.Error(t, err)
+
+	_, _, err = dump.Read(strings.NewReader(`[1,2,{},[{"asize":1}]]`))
+	require.Error(t, err)
+
+	_, _, err = dump.Read(strings.NewReader(`{"name":"/"}`))
+	require.ErrorIs(t, err, dump.ErrUnknownFormat)
+}
@@ -0,0 +1,174 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/crumbyte/noxdir/structure"
+)
+
+// Column defines a column of the flat CSV and TSV exports.
+type Column string
+
+const (
+	// ColumnPath contains the entry's full path.
+	ColumnPath Column = "path"
+
+	// ColumnType contains the entry's type: "dir", "file", or "link".
+	ColumnType Column = "type"
+
+	// ColumnSize contains the entry's apparent size in bytes.
+	ColumnSize Column = "size"
+
+	// ColumnUsage contains the entry's allocated size in bytes.
+	ColumnUsage Column = "usage"
+
+	// ColumnFiles contains the total number of files within the directory. It's
+	// empty for other entries.
+	ColumnFiles Column = "files"
+
+	// ColumnDirs contains the total number of directories within the directory.
+	// It's empty for other entries.
+	ColumnDirs Column = "dirs"
+
+	// ColumnModTime contains the entry's modification time in the RFC 3339
+	// format. It's empty if the time is unknown.
+	ColumnModTime Column = "mtime"
+
+	// ColumnDepth contains the entry's depth, where the root is on depth 0.
+	ColumnDepth Column = "depth"
+)
+
+// DefaultColumns contains all columns of the flat exports in their default
+// order.
+var DefaultColumns = []Column{
+	ColumnPath,
+	ColumnType,
+	ColumnSize,
+	ColumnUsage,
+	ColumnFiles,
+	ColumnDirs,
+	ColumnModTime,
+	ColumnDepth,
+}
+
+// ParseColumns parses the column names, e.g., provided by the user. The names
+// are case-insensitive. An error will be returned if a name is unknown.
+func ParseColumns(names []string) ([]Column, error) {
+	columns := make([]Column, 0, len(names))
+
+	for _, name := range names {
+		c := Column(strings.ToLower(strings.TrimSpace(name)))
+
+		if !c.valid() {
+			return nil, fmt.Errorf("unknown column: %s", name)
+		}
+
+		columns = append(columns, c)
+	}
+
+	return columns, nil
+}
+
+func (c Column) valid() bool {
+	return slices.Contains(DefaultColumns, c)
+}
+
+func (c Column) value(e *structure.Entry, depth int) string {
+	switch c {
+	case ColumnPath:
+		return e.Path()
+	case ColumnType:
+		return entryType(e)
+	case ColumnSize:
+		return strconv.FormatInt(e.Size, 10)
+	case ColumnUsage:
+		return strconv.FormatInt(e.Usage, 10)
+	case ColumnFiles:
+		if e.IsDir {
+			_, files := e.Totals()
+
+			return strconv.FormatUint(files, 10)
+		}
+	case ColumnDirs:
+		if e.IsDir {
+			dirs, _ := e.Totals()
+
+			return strconv.FormatUint(dirs, 10)
+		}
+	case ColumnModTime:
+		if e.ModTime != 0 {
+			return time.Unix(e.ModTime, 0).Format(time.RFC3339)
+		}
+	case ColumnDepth:
+		return strconv.Itoa(depth)
+	}
+
+	return ""
+}
+
+// writeCSV writes a single row per entry, parents first, preceded by the header
+// row containing the column names. The values are separated by the provided
+// separator, e.g., a comma for CSV or a tab for TSV.
+func (e *exporter) writeCSV(w io.Writer, root *structure.Entry, separator rune) error {
+	columns := e.columns
+	if len(columns) == 0 {
+		columns = DefaultColumns
+	}
+
+	for _, c := range columns {
+		if !c.valid() {
+			return fmt.Errorf("unknown column: %s", c)
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Comma = separator
+
+	record := make([]string, len(columns))
+
+	for i, c := range columns {
+		record[i] = string(c)
+	}
+
+	if err := cw.Write(record); err != nil {
+		return err
+	}
+
+	var walk func(entry *structure.Entry, depth int) error
+
+	walk = func(entry *structure.Entry, depth int) error {
+		for i, c := range columns {
+			record[i] = c.value(entry, depth)
+		}
+
+		if err := cw.Write(record); err != nil {
+			return fmt.Errorf("write %s: %w", entry.Path(), err)
+		}
+
+		for child := range entry.Entries() {
+			if !e.include(child, depth+1) {
+				continue
+			}
+
+			if err := walk(child, depth+1); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if err := walk(root, 0); err != nil {
+		return err
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
@@ -0,0 +1,68 @@
+package export_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"path/filepath"
+	"testing"
+
+	"github.com/crumbyte/noxdir/export"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite_CSV(t *testing.T) {
+	var buf bytes.Buffer
+
+	require.NoError(t, export.Write(&buf, testTree(), export.FormatCSV))
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 8)
+	require.Equal(t, []string{"path", "type", "size", "usage", "files", "dirs", "mtime", "depth"}, records[0])
+	require.Equal(t, []string{testRootPath, "dir", "1111", "1111", "4", "2", "", "0"}, records[1])
+
+	nested := filepath.Join(testRootPath, "a", "b", "nested")
+	require.Equal(t, []string{nested, "file", "1000", "1000", "", "", "", "3"}, records[5])
+}
+
+func TestWrite_TSVColumns(t *testing.T) {
+	var buf bytes.Buffer
+
+	require.NoError(
+		t,
+		export.Write(
+			&buf,
+			testTree(),
+			export.FormatTSV,
+			export.WithColumns(export.ColumnDepth, export.ColumnPath),
+			export.WithMaxDepth(1),
+			export.WithMinSize(10),
+		),
+	)
+
+	r := csv.NewReader(&buf)
+	r.Comma = '\t'
+
+	records, err := r.ReadAll()
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		[][]string{
+			{"depth", "path"},
+			{"0", testRootPath},
+			{"1", filepath.Join(testRootPath, "a")},
+			{"1", filepath.Join(testRootPath, "c")},
+		},
+		records,
+	)
+}
+
+func TestParseColumns(t *testing.T) {
+	columns, err := export.ParseColumns([]string{"Path", " size "})
+	require.NoError(t, err)
+	require.Equal(t, []export.Column{export.ColumnPath, export.ColumnSize}, columns)
+
+	_, err = export.ParseColumns([]string{"path", "owner"})
+	require.Error(t, err)
+}
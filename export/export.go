@@ -0,0 +1,139 @@
+// Package export writes the scanned tree in the formats used by the other tools,
+// e.g., scripts and spreadsheets.
+package export
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/crumbyte/noxdir/drive"
+	"github.com/crumbyte/noxdir/structure"
+)
+
+// Format defines the output format of the exported tree.
+type Format string
+
+const (
+	// FormatJSON writes the tree as a single JSON document, where each directory
+	// contains its child entries.
+	FormatJSON Format = "json"
+
+	// FormatNDJSON writes a single JSON object per line for each entry, so the
+	// output can be processed as a stream.
+	FormatNDJSON Format = "ndjson"
+
+	// FormatNCDU writes the tree in the ncdu JSON dump format, so it can be
+	// browsed with "ncdu -f".
+	FormatNCDU Format = "ncdu"
+
+	// FormatCSV writes a single comma-separated row per entry, so the tree can
+	// be loaded into a spreadsheet.
+	FormatCSV Format = "csv"
+
+	// FormatTSV writes a single tab-separated row per entry.
+	FormatTSV Format = "tsv"
+)
+
+// Option defines a custom option for the exporter.
+type Option func(*exporter)
+
+// WithMaxDepth limits the depth of the exported entries, where the root's child
+// entries are on depth 1. The directories' sizes still include their entire
+// content. Zero value means no limit.
+func WithMaxDepth(depth int) Option {
+	return func(e *exporter) {
+		e.maxDepth = depth
+	}
+}
+
+// WithMinSize skips the entries whose size is less than the provided value,
+// along with their content. The root entry is always exported.
+func WithMinSize(size int64) Option {
+	return func(e *exporter) {
+		e.minSize = size
+	}
+}
+
+// WithSizeMode defines the size the entries are compared to the WithMinSize
+// value by.
+func WithSizeMode(sm structure.SizeMode) Option {
+	return func(e *exporter) {
+		e.sizeMode = sm
+	}
+}
+
+// WithAppVersion sets the application version written to the formats that
+// contain the generator's details, e.g., the ncdu dump's metadata.
+func WithAppVersion(version string) Option {
+	return func(e *exporter) {
+		e.appVersion = version
+	}
+}
+
+// WithHardLinks provides the files with multiple hard links found during the
+// scan, see structure.Tree.HardLinks. The formats supporting the hard links,
+// e.g., the ncdu dump, mark such files, so their size is counted only once.
+func WithHardLinks(links []*structure.HardLink) Option {
+	return func(e *exporter) {
+		e.hardLinks = make(map[drive.InoKey]*structure.HardLink, len(links))
+
+		for _, link := range links {
+			e.hardLinks[link.Ino] = link
+		}
+	}
+}
+
+// WithColumns defines the columns of the CSV and TSV exports and their order.
+// All DefaultColumns are written if no columns are provided.
+func WithColumns(columns ...Column) Option {
+	return func(e *exporter) {
+		e.columns = columns
+	}
+}
+
+type exporter struct {
+	hardLinks  map[drive.InoKey]*structure.HardLink
+	appVersion string
+	columns    []Column
+	maxDepth   int
+	minSize    int64
+	sizeMode   structure.SizeMode
+}
+
+// Write writes the tree starting from the provided root to the writer in the
+// provided format.
+func Write(w io.Writer, root *structure.Entry, format Format, opts ...Option) error {
+	e := &exporter{}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	switch format {
+	case FormatJSON:
+		return e.writeJSON(w, root)
+	case FormatNDJSON:
+		return e.writeNDJSON(w, root)
+	case FormatNCDU:
+		return e.writeNCDU(w, root)
+	case FormatCSV:
+		return e.writeCSV(w, root, ',')
+	case FormatTSV:
+		return e.writeCSV(w, root, '\t')
+	}
+
+	return fmt.Errorf("unknown export format: %s", format)
+}
+
+// include reports whether the entry on the provided depth must be exported.
+func (e *exporter) include(entry *structure.Entry, depth int) bool {
+	if depth == 0 {
+		return true
+	}
+
+	if e.maxDepth > 0 && depth > e.maxDepth {
+		return false
+	}
+
+	return entry.SizeBy(e.sizeMode) >= e.minSize
+}
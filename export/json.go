@@ -0,0 +1,165 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/crumbyte/noxdir/structure"
+)
+
+// jsonEntry contains the exported entry's details. The directory totals are set
+// for the directories only.
+type jsonEntry struct {
+	Dirs      *uint64 `json:"dirs,omitempty"`
+	Files     *uint64 `json:"files,omitempty"`
+	Name      string  `json:"name,omitempty"`
+	Path      string  `json:"path,omitempty"`
+	Type      string  `json:"type"`
+	Target    string  `json:"target,omitempty"`
+	Size      int64   `json:"size"`
+	Usage     int64   `json:"usage"`
+	ModTime   int64   `json:"mtime"`
+	Depth     int     `json:"depth,omitempty"`
+	Excluded  bool    `json:"excluded,omitempty"`
+	Ignored   bool    `json:"ignored,omitempty"`
+	Collapsed bool    `json:"collapsed,omitempty"`
+}
+
+func newJSONEntry(e *structure.Entry) jsonEntry {
+	je := jsonEntry{
+		Type:      entryType(e),
+		Size:      e.Size,
+		Usage:     e.Usage,
+		ModTime:   e.ModTime,
+		Excluded:  e.IsExcluded,
+		Ignored:   e.IsIgnored,
+		Collapsed: e.IsCollapsed,
+	}
+
+	if e.IsLink {
+		je.Target = e.LinkTarget()
+	}
+
+	if e.IsDir {
+		dirs, files := e.Totals()
+		je.Dirs, je.Files = &dirs, &files
+	}
+
+	return je
+}
+
+// writeJSON writes the tree as a single JSON document. The root entry contains
+// its full path, and the rest of the entries contain their names only. The
+// document is written as the tree is traversed, so it's never kept in memory
+// entirely.
+func (e *exporter) writeJSON(w io.Writer, root *structure.Entry) error {
+	bw := bufio.NewWriter(w)
+
+	if err := e.writeJSONEntry(bw, root, 0); err != nil {
+		return err
+	}
+
+	if err := bw.WriteByte('\n'); err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+func (e *exporter) writeJSONEntry(w *bufio.Writer, entry *structure.Entry, depth int) error {
+	je := newJSONEntry(entry)
+
+	je.Name = entry.Name()
+	if depth == 0 {
+		je.Name, je.Path = "", entry.Path()
+	}
+
+	data, err := json.Marshal(je)
+	if err != nil {
+		return fmt.Errorf("encode %s: %w", entry.Path(), err)
+	}
+
+	if !entry.IsDir {
+		_, err = w.Write(data)
+
+		return err
+	}
+
+	// the child entries are appended to the encoded object instead of its
+	// closing brace.
+	_, _ = w.Write(data[:len(data)-1])
+	_, _ = w.WriteString(`,"children":[`)
+
+	first := true
+
+	for child := range entry.Entries() {
+		if !e.include(child, depth+1) {
+			continue
+		}
+
+		if !first {
+			_ = w.WriteByte(',')
+		}
+
+		if err = e.writeJSONEntry(w, child, depth+1); err != nil {
+			return err
+		}
+
+		first = false
+	}
+
+	_, err = w.WriteString("]}")
+
+	return err
+}
+
+// writeNDJSON writes each entry as a separate JSON object on its own line. The
+// entries contain their full paths and depths, and the parent directories are
+// always written before their content.
+func (e *exporter) writeNDJSON(w io.Writer, root *structure.Entry) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+
+	var walk func(entry *structure.Entry, depth int) error
+
+	walk = func(entry *structure.Entry, depth int) error {
+		je := newJSONEntry(entry)
+		je.Path, je.Depth = entry.Path(), depth
+
+		if err := enc.Encode(je); err != nil {
+			return fmt.Errorf("encode %s: %w", entry.Path(), err)
+		}
+
+		for child := range entry.Entries() {
+			if !e.include(child, depth+1) {
+				continue
+			}
+
+			if err := walk(child, depth+1); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if err := walk(root, 0); err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+// entryType returns the entry's type name used by the exported formats.
+func entryType(e *structure.Entry) string {
+	switch {
+	case e.IsLink:
+		return "link"
+	case e.IsDir:
+		return "dir"
+	}
+
+	return "file"
+}
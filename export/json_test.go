@@ -0,0 +1,104 @@
+package export_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/crumbyte/noxdir/export"
+	"github.com/crumbyte/noxdir/structure"
+
+	"github.com/stretchr/testify/require"
+)
+
+type jsonNode struct {
+	Dirs     *uint64    `json:"dirs"`
+	Name     string     `json:"name"`
+	Path     string     `json:"path"`
+	Type     string     `json:"type"`
+	Children []jsonNode `json:"children"`
+	Size     int64      `json:"size"`
+	Depth    int        `json:"depth"`
+}
+
+func TestWrite_JSON(t *testing.T) {
+	var buf bytes.Buffer
+
+	require.NoError(t, export.Write(&buf, testTree(), export.FormatJSON))
+
+	var root jsonNode
+
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &root))
+	require.Equal(t, testRootPath, root.Path)
+	require.Equal(t, "dir", root.Type)
+	require.EqualValues(t, 1111, root.Size)
+	require.NotNil(t, root.Dirs)
+	require.EqualValues(t, 2, *root.Dirs)
+	require.Len(t, root.Children, 3)
+
+	nested := root.Children[0]
+	require.Equal(t, "a", nested.Name)
+	require.Empty(t, nested.Path)
+	require.Len(t, nested.Children, 2)
+	require.Equal(t, "file", nested.Children[0].Type)
+	require.Nil(t, nested.Children[0].Dirs)
+}
+
+func TestWrite_NDJSON(t *testing.T) {
+	var buf bytes.Buffer
+
+	require.NoError(
+		t,
+		export.Write(
+			&buf,
+			testTree(),
+			export.FormatNDJSON,
+			export.WithMaxDepth(1),
+			export.WithMinSize(10),
+		),
+	)
+
+	var lines []jsonNode
+
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var node jsonNode
+
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &node))
+
+		lines = append(lines, node)
+	}
+
+	// the small file and the entries below the first level are omitted, while
+	// the sizes still include them
+	require.Len(t, lines, 3)
+	require.Equal(t, testRootPath, lines[0].Path)
+	require.EqualValues(t, 1111, lines[0].Size)
+	require.Equal(t, filepath.Join(testRootPath, "a"), lines[1].Path)
+	require.EqualValues(t, 1100, lines[1].Size)
+	require.Equal(t, 1, lines[1].Depth)
+	require.Equal(t, filepath.Join(testRootPath, "c"), lines[2].Path)
+}
+
+func TestWrite_UnknownFormat(t *testing.T) {
+	require.Error(t, export.Write(&bytes.Buffer{}, testTree(), "xml"))
+}
+
+var testRootPath = filepath.Join(string(filepath.Separator), "root")
+
+func testTree() *structure.Entry {
+	root := structure.NewDirEntry(testRootPath, 0)
+	a := structure.NewDirEntry("a", 0)
+	b := structure.NewDirEntry("b", 0)
+
+	root.AddChild(a)
+	a.AddChild(structure.NewFileEntry("file", 100, 0))
+	a.AddChild(b)
+	b.AddChild(structure.NewFileEntry("nested", 1000, 0))
+	root.AddChild(structure.NewFileEntry("small", 1, 0))
+	root.AddChild(structure.NewFileEntry("c", 10, 0))
+
+	return root
+}
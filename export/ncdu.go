@@ -0,0 +1,148 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/crumbyte/noxdir/drive"
+	"github.com/crumbyte/noxdir/structure"
+)
+
+const (
+	ncduMajorVersion = 1
+	ncduMinorVersion = 2
+)
+
+// ncduMeta contains the ncdu dump's metadata block.
+type ncduMeta struct {
+	ProgName  string `json:"progname"`
+	ProgVer   string `json:"progver"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// ncduInfo contains the ncdu dump's information block of a single entry.
+type ncduInfo struct {
+	Name     string `json:"name"`
+	Excluded string `json:"excluded,omitempty"`
+	ASize    int64  `json:"asize,omitempty"`
+	DSize    int64  `json:"dsize,omitempty"`
+	Dev      uint64 `json:"dev,omitempty"`
+	Ino      uint64 `json:"ino,omitempty"`
+	MTime    int64  `json:"mtime,omitempty"`
+	NLink    uint64 `json:"nlink,omitempty"`
+	HardLink bool   `json:"hlnkc,omitempty"`
+	NotReg   bool   `json:"notreg,omitempty"`
+}
+
+// writeNCDU writes the tree in the ncdu JSON dump format, which can be loaded
+// with "ncdu -f". In this format, the directory's size is the sum of its
+// content and its own size. Hence, the size of the directory's content that is
+// not exported, e.g., pruned by the options or collapsed during the scan, is
+// written as the directory's own size, so the totals stay the same. The same
+// applies to the synthetic ignored entries groups, which are not exported.
+//
+// The files with multiple hard links provided by WithHardLinks are written with
+// their full size and the number of links, so ncdu counts each file once.
+func (e *exporter) writeNCDU(w io.Writer, root *structure.Entry) error {
+	bw := bufio.NewWriter(w)
+
+	meta, err := json.Marshal(ncduMeta{
+		ProgName:  "noxdir",
+		ProgVer:   e.appVersion,
+		Timestamp: time.Now().Unix(),
+	})
+	if err != nil {
+		return fmt.Errorf("encode ncdu metadata: %w", err)
+	}
+
+	_, _ = fmt.Fprintf(bw, "[%d,%d,%s,\n", ncduMajorVersion, ncduMinorVersion, meta)
+
+	if err = e.writeNCDUEntry(bw, root, nil, 0); err != nil {
+		return err
+	}
+
+	_, _ = bw.WriteString("]\n")
+
+	return bw.Flush()
+}
+
+func (e *exporter) writeNCDUEntry(w *bufio.Writer, entry, parent *structure.Entry, depth int) error {
+	info := ncduInfo{
+		Name:  entry.Name(),
+		Ino:   entry.Ino.Ino,
+		MTime: entry.ModTime,
+	}
+
+	if depth == 0 {
+		info.Name = entry.Path()
+	}
+
+	// the device is written only if it differs from the parent's one
+	if entry.Ino != (drive.InoKey{}) && (parent == nil || parent.Ino.Dev != entry.Ino.Dev) {
+		info.Dev = entry.Ino.Dev
+	}
+
+	switch {
+	case entry.IsExcluded:
+		info.Excluded = "pattern"
+	case entry.IsLink && !entry.IsDir:
+		// the symbolic link's size is the target's size only if the link
+		// was followed
+		info.NotReg, info.ASize, info.DSize = entry.Size == 0, entry.Size, entry.Usage
+	case !entry.IsDir:
+		info.ASize, info.DSize = entry.Size, entry.Usage
+
+		if link, ok := e.hardLinks[entry.Ino]; ok && entry.Ino != (drive.InoKey{}) {
+			info.ASize, info.DSize = link.Size, link.Usage
+			info.NLink, info.HardLink = link.Links, true
+		}
+	}
+
+	if !entry.IsDir || entry.IsExcluded {
+		return writeNCDUInfo(w, info)
+	}
+
+	// the directory's own size covers its content that is not exported
+	info.ASize, info.DSize = entry.Size, entry.Usage
+
+	children := make([]*structure.Entry, 0, entry.ChildCount())
+
+	for child := range entry.Entries() {
+		if !child.IsIgnored && e.include(child, depth+1) {
+			children = append(children, child)
+			info.ASize, info.DSize = info.ASize-child.Size, info.DSize-child.Usage
+		}
+	}
+
+	info.ASize, info.DSize = max(0, info.ASize), max(0, info.DSize)
+
+	_ = w.WriteByte('[')
+
+	if err := writeNCDUInfo(w, info); err != nil {
+		return err
+	}
+
+	for _, child := range children {
+		_, _ = w.WriteString(",\n")
+
+		if err := e.writeNCDUEntry(w, child, entry, depth+1); err != nil {
+			return err
+		}
+	}
+
+	return w.WriteByte(']')
+}
+
+func writeNCDUInfo(w *bufio.Writer, info ncduInfo) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return fmt.Errorf("encode %s: %w", info.Name, err)
+	}
+
+	_, err = w.Write(data)
+
+	return err
+}
@@ -0,0 +1,168 @@
+package export_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/crumbyte/noxdir/drive"
+	"github.com/crumbyte/noxdir/export"
+	"github.com/crumbyte/noxdir/structure"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite_NCDU(t *testing.T) {
+	var buf bytes.Buffer
+
+	require.NoError(
+		t,
+		export.Write(&buf, testTree(), export.FormatNCDU, export.WithAppVersion("v1")),
+	)
+
+	var dump []json.RawMessage
+
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &dump))
+	require.Len(t, dump, 4)
+	require.JSONEq(t, "1", string(dump[0]))
+	require.JSONEq(t, "2", string(dump[1]))
+
+	var meta map[string]any
+
+	require.NoError(t, json.Unmarshal(dump[2], &meta))
+	require.Equal(t, "noxdir", meta["progname"])
+	require.Equal(t, "v1", meta["progver"])
+
+	var root []any
+
+	require.NoError(t, json.Unmarshal(dump[3], &root))
+	require.Len(t, root, 4)
+	require.Equal(t, testRootPath, ncduName(root))
+	require.EqualValues(t, 1111, ncduSize(root))
+
+	// the directories are arrays, and the files are objects
+	a, ok := root[1].([]any)
+	require.True(t, ok)
+	require.Equal(t, "a", ncduName(a))
+	require.Len(t, a, 3)
+
+	file, ok := a[1].(map[string]any)
+	require.True(t, ok)
+	require.Equal(t, "file", file["name"])
+	require.EqualValues(t, 100, file["asize"])
+}
+
+func TestWrite_NCDUPruned(t *testing.T) {
+	var buf bytes.Buffer
+
+	require.NoError(
+		t,
+		export.Write(
+			&buf,
+			testTree(),
+			export.FormatNCDU,
+			export.WithMaxDepth(1),
+			export.WithMinSize(10),
+		),
+	)
+
+	var dump []json.RawMessage
+
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &dump))
+
+	var root []any
+
+	require.NoError(t, json.Unmarshal(dump[3], &root))
+
+	// the pruned entries' sizes are kept as the directories' own sizes
+	require.Len(t, root, 3)
+	require.EqualValues(t, 1111, ncduSize(root))
+
+	a, ok := root[1].([]any)
+	require.True(t, ok)
+	require.Len(t, a, 1)
+	require.EqualValues(t, 1100, ncduSize(a))
+}
+
+func TestWrite_NCDUHardLinksIgnored(t *testing.T) {
+	var (
+		buf  bytes.Buffer
+		ino  = drive.InoKey{Dev: 1, Ino: 10}
+		root = structure.NewDirEntry(testRootPath, 0)
+	)
+
+	// the file's size is split between its links
+	for _, name := range []string{"a", "b"} {
+		link := structure.NewFileEntry(name, 50, 0)
+		link.Ino = ino
+
+		root.AddChild(link)
+	}
+
+	group := structure.NewDirEntry(structure.IgnoredGroupName, 0)
+	group.IsIgnored = true
+
+	root.AddChild(group)
+	group.AddChild(structure.NewFileEntry("ignored", 30, 0))
+
+	require.NoError(
+		t,
+		export.Write(
+			&buf,
+			root,
+			export.FormatNCDU,
+			export.WithHardLinks([]*structure.HardLink{{Size: 100, Links: 2, Ino: ino}}),
+		),
+	)
+
+	var dump []json.RawMessage
+
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &dump))
+
+	var dir []any
+
+	require.NoError(t, json.Unmarshal(dump[3], &dir))
+
+	// the ignored entries group is not exported, but its size is kept as the
+	// directory's own size
+	require.Len(t, dir, 3)
+
+	info, ok := dir[0].(map[string]any)
+	require.True(t, ok)
+	require.EqualValues(t, 30, info["asize"])
+
+	for _, item := range dir[1:] {
+		link, ok := item.(map[string]any)
+		require.True(t, ok)
+		require.EqualValues(t, 100, link["asize"])
+		require.EqualValues(t, 2, link["nlink"])
+		require.EqualValues(t, 10, link["ino"])
+		require.Equal(t, true, link["hlnkc"])
+	}
+}
+
+func ncduName(dir []any) string {
+	info, _ := dir[0].(map[string]any)
+	name, _ := info["name"].(string)
+
+	return name
+}
+
+// ncduSize sums the apparent sizes of the ncdu dump's item in the same way
+// ncdu does.
+func ncduSize(item any) float64 {
+	if info, ok := item.(map[string]any); ok {
+		size, _ := info["asize"].(float64)
+
+		return size
+	}
+
+	var total float64
+
+	items, _ := item.([]any)
+	for _, child := range items {
+		total += ncduSize(child)
+	}
+
+	return total
+}
@@ -71,7 +71,9 @@ func (edf *EmptyDirFilter) ID() ID {
 }
 
 func (edf *EmptyDirFilter) Filter(e *structure.Entry) bool {
-	return !e.IsDir || e.TotalFiles > 0
+	_, files := e.Totals()
+
+	return !e.IsDir || files > 0
 }
 
 // NameFilter filters a single instance of the *structure.Entry by its path value.
@@ -7,15 +7,19 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
+	"time"
 
 	"github.com/klauspost/compress/zstd"
 )
 
 const (
 	//TODO: must be injected when the full config will be implemented
+	appName   = "noxdir"
 	configDir = ".noxdir"
 	cacheDir  = "cache"
 )
@@ -24,6 +28,17 @@ const (
 // not found.
 var ErrNoCache = errors.New("cache entry not found")
 
+// Key identifies a cache entry. The same path may refer to different file
+// systems over time, e.g., when another disk is mounted at the same mount point;
+// hence, the file system is a part of the key.
+type Key struct {
+	// Path contains the path of the cached tree's root.
+	Path string
+
+	// Device identifies the file system the root is located on.
+	Device string
+}
+
 // Encoder defines a basic interface for the Encode implementations. The Cache
 // uses the Encoder instance when persisting the state.
 type Encoder interface {
@@ -59,14 +74,42 @@ func WithCompress() Option {
 	}
 }
 
+// WithAppVersion sets the application version that will be stored in the header
+// of each written cache file.
+func WithAppVersion(version string) Option {
+	return func(c *Cache) {
+		c.appVersion = version
+	}
+}
+
+// WithScanOptions sets the description of the scan options that will be stored
+// in the header of each written cache file.
+func WithScanOptions(options string) Option {
+	return func(c *Cache) {
+		c.scanOptions = options
+	}
+}
+
+// WithCacheDir overrides the default directory the cache files are stored in,
+// which is returned by DefaultDir.
+func WithCacheDir(path string) Option {
+	return func(c *Cache) {
+		c.cachePath = path
+	}
+}
+
 // Cache provides a file cache API. It saves and restores an arbitrary data types
 // which can marshaled/unmarshalled as JSON into file cache. The cache entries
 // can be restored by the corresponding key. The cache files will be stored at
-// configured path or default DefaultCachePath will be used.
+// configured path or default DefaultDir will be used.
 type Cache struct {
 	ei                 NewEncoder
 	di                 NewDecoder
 	cachePath          string
+	appVersion         string
+	scanOptions        string
+	historyInterval    time.Duration
+	historySize        int
 	compressionEnabled bool
 }
 
@@ -80,14 +123,18 @@ func NewCache(ne NewEncoder, nd NewDecoder, clearCache bool, opts ...Option) (*C
 		opt(c)
 	}
 
-	cachePath, err := resolveCacheDir(configDir, cacheDir)
-	if err != nil {
-		return nil, fmt.Errorf("resolve cache dir: %w", err)
-	}
+	if len(c.cachePath) == 0 {
+		cachePath, err := resolveCacheDir(configDir, cacheDir)
+		if err != nil {
+			return nil, fmt.Errorf("resolve cache dir: %w", err)
+		}
+
+		c.cachePath = cachePath
 
-	c.cachePath = cachePath
+		migrateLegacyDir(cachePath)
+	}
 
-	if err = c.initCacheDir(clearCache); err != nil {
+	if err := c.initCacheDir(clearCache); err != nil {
 		return nil, err
 	}
 
@@ -95,16 +142,16 @@ func NewCache(ne NewEncoder, nd NewDecoder, clearCache bool, opts ...Option) (*C
 }
 
 // Get retrieves a cache entry by its key and maps data to the provided target.
-// The target instance must be a pointer type and support JSON unmarshalling.
-// If the corresponding cache entry was not found the ErrNoCache error will be
-// returned.
-func (c *Cache) Get(key string, target any) error {
-	var (
-		r                io.Reader
-		compressedReader *zstd.Decoder
-	)
+// The target instance must be a pointer type supported by the Decoder. If the
+// corresponding cache entry was not found the ErrNoCache error will be returned.
+//
+// The cache file's checksum is verified before the data is decoded; hence, the
+// target is not modified if the file is corrupt. The corrupt and incompatible
+// files are removed, and the ErrCorrupt or ErrIncompatible error is returned.
+func (c *Cache) Get(key Key, target any) error {
+	cachePath := filepath.Join(c.cachePath, c.keyHash(key))
 
-	cacheFile, err := os.Open(filepath.Join(c.cachePath, c.keyHash(key)))
+	cacheFile, err := os.Open(cachePath)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
 			return ErrNoCache
@@ -113,62 +160,181 @@ func (c *Cache) Get(key string, target any) error {
 		return err
 	}
 
-	r = bufio.NewReaderSize(cacheFile, 5<<20)
-
 	defer func() {
 		_ = cacheFile.Close()
 	}()
 
-	if c.compressionEnabled {
-		compressedReader, err = zstd.NewReader(r)
-		if err != nil {
+	if err = c.decode(cacheFile, key, target); err != nil {
+		if errors.Is(err, ErrCorrupt) || errors.Is(err, ErrIncompatible) {
+			_ = os.Remove(cachePath)
+		}
+
+		return fmt.Errorf("cache file %s: %w", cachePath, err)
+	}
+
+	return nil
+}
+
+// Header reads the header of the cache entry without decoding the cached data.
+// If the corresponding cache entry was not found the ErrNoCache error will be
+// returned. The checksum is not verified, since it requires reading the entire
+// file, but the header itself must match the key and the scan options.
+func (c *Cache) Header(key Key) (Header, error) {
+	header, err := c.readFileHeader(c.keyHash(key))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Header{}, ErrNoCache
+		}
+
+		return header, err
+	}
+
+	return header, c.checkHeader(header, key)
+}
+
+// Set stores the value by the provided key. The data is written to a temporary
+// file first, which then replaces the existing cache file, so an interrupted
+// write never leaves a broken cache entry. If the value implements Summarizer,
+// its summary is stored in the header. If the history is enabled by WithHistory,
+// the written entry is added to the history as well.
+func (c *Cache) Set(key Key, val any) error {
+	name := c.keyHash(key)
+
+	err := c.writeFile(name, func(w io.Writer) error {
+		return c.encode(w, key, val)
+	})
+	if err != nil || c.historySize <= 0 {
+		return err
+	}
+
+	return c.addSnapshot(name)
+}
+
+func (c *Cache) encode(w io.Writer, key Key, val any) error {
+	cw := newChecksumWriter(w)
+
+	// the host is informational only, so it's left empty if it's unknown
+	host, _ := os.Hostname()
+
+	header := Header{
+		CreatedAt:   time.Now(),
+		Host:        host,
+		Path:        key.Path,
+		Device:      key.Device,
+		AppVersion:  c.appVersion,
+		ScanOptions: c.scanOptions,
+		Compressed:  c.compressionEnabled,
+	}
+
+	if s, ok := val.(Summarizer); ok {
+		header.Summary = s.CacheSummary()
+	}
+
+	if err := writeHeader(cw, header); err != nil {
+		return fmt.Errorf("write cache header: %w", err)
+	}
+
+	if !c.compressionEnabled {
+		if err := c.ei(cw).Encode(val); err != nil {
 			return err
 		}
 
-		r = compressedReader
-		defer compressedReader.Close()
+		return cw.writeTrailer()
+	}
+
+	compressedWriter, err := zstd.NewWriter(cw)
+	if err != nil {
+		return err
+	}
+
+	if err = c.ei(compressedWriter).Encode(val); err != nil {
+		_ = compressedWriter.Close()
+
+		return err
 	}
 
-	return c.di(r).Decode(target)
+	if err = compressedWriter.Close(); err != nil {
+		return fmt.Errorf("compress cache data: %w", err)
+	}
+
+	return cw.writeTrailer()
 }
 
-func (c *Cache) Set(key string, val any) error {
-	var (
-		w                io.Writer
-		compressedWriter *zstd.Encoder
-	)
+func (c *Cache) decode(f *os.File, key Key, target any) error {
+	// the header is validated first, so the files of other versions are
+	// reported as incompatible rather than corrupt.
+	header, err := readHeader(bufio.NewReader(f))
+	if err != nil {
+		return err
+	}
+
+	if err = c.checkHeader(header, key); err != nil {
+		return err
+	}
 
-	cacheFile, err := c.initEntryCache(key)
+	return decodeData(f, header, c.di, target)
+}
+
+// decodeData verifies the checksum of the cache file whose header was already
+// read, and decodes its data to the target.
+func decodeData(f *os.File, header Header, nd NewDecoder, target any) error {
+	fi, err := f.Stat()
 	if err != nil {
 		return err
 	}
 
-	bufferedWriter := bufio.NewWriterSize(cacheFile, 5<<20)
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
 
-	defer func() {
-		_ = bufferedWriter.Flush()
-		_ = cacheFile.Close()
-	}()
+	if err = verifyChecksum(f, fi.Size()); err != nil {
+		return err
+	}
 
-	w = bufferedWriter
+	if _, err = f.Seek(header.size, io.SeekStart); err != nil {
+		return err
+	}
 
-	if c.compressionEnabled {
-		compressedWriter, err = zstd.NewWriter(w)
+	// the checksum must not be passed to the Decoder
+	r := io.LimitReader(
+		bufio.NewReaderSize(f, 5<<20),
+		fi.Size()-header.size-checksumSize,
+	)
+
+	if header.Compressed {
+		compressedReader, err := zstd.NewReader(r)
 		if err != nil {
 			return err
 		}
 
-		w = compressedWriter
+		defer compressedReader.Close()
+
+		r = compressedReader
+	}
+
+	if err = nd(r).Decode(target); err != nil {
+		return fmt.Errorf("%w: %w", ErrCorrupt, err)
+	}
 
-		defer func() {
-			_ = compressedWriter.Close()
-		}()
+	return nil
+}
+
+// checkHeader verifies that the cache file was written for the provided key and
+// with the current scan options.
+func (c *Cache) checkHeader(h Header, key Key) error {
+	switch {
+	case h.Path != key.Path:
+		return fmt.Errorf("%w: written for %s", ErrIncompatible, h.Path)
+	case h.Device != key.Device:
+		return fmt.Errorf("%w: written for device %q", ErrIncompatible, h.Device)
+	case h.ScanOptions != c.scanOptions:
+		return fmt.Errorf("%w: written with options %q", ErrIncompatible, h.ScanOptions)
 	}
 
-	return c.ei(w).Encode(val)
+	return nil
 }
 
-func (c *Cache) Has(key string) bool {
+func (c *Cache) Has(key Key) bool {
 	fi, err := os.Lstat(filepath.Join(c.cachePath, c.keyHash(key)))
 
 	return err == nil && fi.Mode().IsRegular()
@@ -188,30 +354,123 @@ func (c *Cache) initCacheDir(clearCache bool) error {
 	return nil
 }
 
-func (c *Cache) initEntryCache(key string) (io.WriteCloser, error) {
-	cachePath := filepath.Join(c.cachePath, c.keyHash(key))
+func (c *Cache) keyHash(key Key) string {
+	return fileName(key, c.scanOptions)
+}
+
+// fileName returns the cache file name for the provided key. The scan options are
+// a part of the name, so the trees built with different options do not replace
+// each other.
+func fileName(key Key, scanOptions string) string {
+	if len(key.Path) == 0 {
+		return ""
+	}
+
+	h := sha256.New()
+	h.Write([]byte(key.Path))
+	h.Write([]byte{0})
+	h.Write([]byte(key.Device))
+	h.Write([]byte{0})
+	h.Write([]byte(scanOptions))
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// DefaultDir returns the default directory the cache files are stored in. On
+// Linux, the directory follows the XDG base directory specification.
+func DefaultDir() (string, error) {
+	return resolveCacheDir(configDir, cacheDir)
+}
+
+// migrateLegacyDir moves the cache files from the "~/.noxdir/cache" directory,
+// where they were stored on Linux before the cache was moved to the XDG cache
+// directory, to the provided cache directory. The files that already exist in
+// the target directory or cannot be moved are left in place. The directories
+// are removed only once they're empty, so no files are deleted. The migration
+// is done on a best effort basis; hence, the errors are ignored.
+func migrateLegacyDir(cachePath string) {
+	if runtime.GOOS != "linux" {
+		return
+	}
 
-	cacheFile, err := os.Create(cachePath)
+	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		return nil, err
+		return
+	}
+
+	legacyDir := filepath.Join(homeDir, configDir, cacheDir)
+
+	if _, err = os.Stat(legacyDir); err != nil {
+		return
+	}
+
+	var dirs []string
+
+	_ = filepath.WalkDir(legacyDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+
+		if d.IsDir() {
+			dirs = append(dirs, path)
+
+			return nil
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(legacyDir, path)
+		if err != nil {
+			return nil
+		}
+
+		_ = moveFile(path, filepath.Join(cachePath, relPath))
+
+		return nil
+	})
+
+	// the nested directories are removed before their parents
+	for _, dir := range slices.Backward(dirs) {
+		_ = os.Remove(dir)
 	}
 
-	return cacheFile, nil
+	_ = os.Remove(filepath.Dir(legacyDir))
 }
 
-func (c *Cache) keyHash(key string) string {
-	if len(key) == 0 {
-		return ""
+// moveFile moves the file to the provided destination unless it already exists.
+// The file is copied if it cannot be renamed, e.g., the destination is located
+// on another file system.
+func moveFile(src, dst string) error {
+	if _, err := os.Lstat(dst); !errors.Is(err, os.ErrNotExist) {
+		return os.ErrExist
 	}
 
-	h := sha256.New()
-	h.Write([]byte(key))
+	if err := os.MkdirAll(filepath.Dir(dst), 0750); err != nil {
+		return err
+	}
 
-	return hex.EncodeToString(h.Sum(nil))
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	if err := copyFile(src, dst); err != nil {
+		return err
+	}
+
+	return os.Remove(src)
 }
 
 func resolveCacheDir(configDir, cacheDir string) (string, error) {
 	switch runtime.GOOS {
+	case "linux":
+		userCacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return "", fmt.Errorf("get user cache dir: %w", err)
+		}
+
+		return filepath.Join(userCacheDir, appName), nil
 	case "windows":
 		localAppData := os.Getenv("LocalAppData")
 		if len(localAppData) == 0 {
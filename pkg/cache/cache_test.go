@@ -0,0 +1,228 @@
+package cache_test
+
+import (
+	"encoding/gob"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/crumbyte/noxdir/pkg/cache"
+
+	"github.com/stretchr/testify/require"
+)
+
+var testKey = cache.Key{Path: "/root", Device: "uuid:1234"}
+
+type testValue struct {
+	Name  string
+	Sizes []int64
+}
+
+func newTestCache(t *testing.T, opts ...cache.Option) (*cache.Cache, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	c, err := cache.NewCache(
+		func(w io.Writer) cache.Encoder { return gob.NewEncoder(w) },
+		func(r io.Reader) cache.Decoder { return gob.NewDecoder(r) },
+		false,
+		append(opts, cache.WithCacheDir(dir))...,
+	)
+	require.NoError(t, err)
+
+	return c, dir
+}
+
+// cacheFile returns the path of the only cache file in the directory.
+func cacheFile(t *testing.T, dir string) string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	return filepath.Join(dir, entries[0].Name())
+}
+
+func TestNewCache_MigrateLegacyDir(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the cache directory was moved on linux only")
+	}
+
+	homeDir, cacheHome := t.TempDir(), t.TempDir()
+
+	t.Setenv("HOME", homeDir)
+	t.Setenv("XDG_CACHE_HOME", cacheHome)
+
+	var (
+		legacyDir = filepath.Join(homeDir, ".noxdir", "cache")
+		cacheDir  = filepath.Join(cacheHome, "noxdir")
+	)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(legacyDir, "history"), 0750))
+	require.NoError(t, os.MkdirAll(cacheDir, 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(legacyDir, "tree"), []byte("tree"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(legacyDir, "history", "tree.1"), []byte("snapshot"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(legacyDir, "other"), []byte("legacy"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(cacheDir, "other"), []byte("current"), 0600))
+
+	newCache := func() {
+		_, err := cache.NewCache(
+			func(w io.Writer) cache.Encoder { return gob.NewEncoder(w) },
+			func(r io.Reader) cache.Decoder { return gob.NewDecoder(r) },
+			false,
+		)
+		require.NoError(t, err)
+	}
+
+	newCache()
+
+	for name, content := range map[string]string{
+		"tree":                             "tree",
+		filepath.Join("history", "tree.1"): "snapshot",
+		"other":                            "current",
+	} {
+		data, err := os.ReadFile(filepath.Join(cacheDir, name))
+		require.NoError(t, err)
+		require.Equal(t, content, string(data))
+	}
+
+	// the file existing in both directories is not replaced or removed
+	require.FileExists(t, filepath.Join(legacyDir, "other"))
+	require.NoDirExists(t, filepath.Join(legacyDir, "history"))
+
+	require.NoError(t, os.Remove(filepath.Join(legacyDir, "other")))
+
+	newCache()
+
+	require.NoDirExists(t, filepath.Join(homeDir, ".noxdir"))
+}
+
+func TestCache_SetGet(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		var opts []cache.Option
+
+		if compress {
+			opts = append(opts, cache.WithCompress())
+		}
+
+		c, dir := newTestCache(
+			t,
+			append(opts, cache.WithAppVersion("v1.2.3"), cache.WithScanOptions("max-depth=2"))...,
+		)
+
+		val := testValue{Name: "root", Sizes: []int64{1 << 40, 42}}
+
+		require.NoError(t, c.Set(testKey, val))
+		require.NoError(t, c.Set(testKey, val))
+
+		// the temporary files are not left behind
+		cacheFile(t, dir)
+
+		var restored testValue
+
+		require.NoError(t, c.Get(testKey, &restored))
+		require.Equal(t, val, restored)
+
+		h, err := c.Header(testKey)
+		require.NoError(t, err)
+		require.Equal(t, testKey.Path, h.Path)
+		require.Equal(t, testKey.Device, h.Device)
+		require.Equal(t, "v1.2.3", h.AppVersion)
+		require.Equal(t, "max-depth=2", h.ScanOptions)
+		require.Equal(t, cache.FormatVersion, h.FormatVersion)
+		require.Equal(t, compress, h.Compressed)
+		require.False(t, h.CreatedAt.IsZero())
+
+		require.ErrorIs(t, c.Get(cache.Key{Path: "/other"}, &restored), cache.ErrNoCache)
+	}
+}
+
+func TestCache_KeyIdentity(t *testing.T) {
+	dir := t.TempDir()
+
+	newCache := func(options string) *cache.Cache {
+		c, err := cache.NewCache(
+			func(w io.Writer) cache.Encoder { return gob.NewEncoder(w) },
+			func(r io.Reader) cache.Decoder { return gob.NewDecoder(r) },
+			false,
+			cache.WithCacheDir(dir),
+			cache.WithScanOptions(options),
+		)
+		require.NoError(t, err)
+
+		return c
+	}
+
+	c := newCache("max-depth=0")
+
+	require.NoError(t, c.Set(testKey, testValue{Name: "root"}))
+	require.True(t, c.Has(testKey))
+
+	// another disk mounted at the same path
+	require.False(t, c.Has(cache.Key{Path: testKey.Path, Device: "uuid:5678"}))
+
+	// the same root scanned with other options
+	other := newCache("max-depth=2")
+	require.False(t, other.Has(testKey))
+
+	_, err := other.Header(testKey)
+	require.ErrorIs(t, err, cache.ErrNoCache)
+}
+
+func TestCache_GetRejected(t *testing.T) {
+	tests := map[string]struct {
+		corrupt func([]byte) []byte
+		err     error
+	}{
+		"flipped byte": {
+			corrupt: func(b []byte) []byte {
+				b[len(b)/2] ^= 0xff
+
+				return b
+			},
+			err: cache.ErrCorrupt,
+		},
+		"truncated": {
+			corrupt: func(b []byte) []byte { return b[:len(b)-10] },
+			err:     cache.ErrCorrupt,
+		},
+		"legacy format": {
+			corrupt: func([]byte) []byte { return []byte("legacy cache data without header") },
+			err:     cache.ErrIncompatible,
+		},
+		"unsupported version": {
+			corrupt: func(b []byte) []byte {
+				b[8]++
+
+				return b
+			},
+			err: cache.ErrIncompatible,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			c, dir := newTestCache(t)
+
+			require.NoError(t, c.Set(testKey, testValue{Name: "root", Sizes: make([]int64, 100)}))
+
+			path := cacheFile(t, dir)
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			require.NoError(t, os.WriteFile(path, tc.corrupt(data), 0600))
+
+			restored := testValue{Name: "untouched"}
+
+			require.ErrorIs(t, c.Get(testKey, &restored), tc.err)
+			require.Equal(t, "untouched", restored.Name)
+
+			// the rejected file is removed, so the next run starts from scratch
+			require.False(t, c.Has(testKey))
+		})
+	}
+}
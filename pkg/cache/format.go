@@ -0,0 +1,229 @@
+package cache
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"time"
+)
+
+// FormatVersion defines the current version of the cache file format. The files
+// written with a different format version are rejected.
+const FormatVersion uint16 = 2
+
+const (
+	// maxHeaderSize limits the size of the encoded header, so a corrupted length
+	// value does not lead to a huge allocation.
+	maxHeaderSize = 1 << 20
+
+	// prefixSize contains the size of the magic value, the format version, and
+	// the header length.
+	prefixSize = len(magic) + 2 + 4
+
+	// checksumSize contains the size of the trailing CRC-32C checksum.
+	checksumSize = 4
+)
+
+// magic identifies the noxdir cache files.
+var magic = [8]byte{'N', 'O', 'X', 'D', 'I', 'R', 'C', 0}
+
+var (
+	// ErrIncompatible defines an error that occurs if the cache file was written
+	// by another application or with an unsupported format version.
+	ErrIncompatible = errors.New("incompatible cache file")
+
+	// ErrCorrupt defines an error that occurs if the cache file is truncated or
+	// its content does not match the checksum.
+	ErrCorrupt = errors.New("corrupt cache file")
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Header contains the cache file metadata stored before the cached data. The
+// header is not compressed, so it can be read without decoding the data.
+type Header struct {
+	// CreatedAt contains the time the cache file was written.
+	CreatedAt time.Time `json:"createdAt"`
+
+	// Path contains the path of the cached tree's root.
+	Path string `json:"path"`
+
+	// Host contains the name of the machine the cache file was written on.
+	Host string `json:"host,omitempty"`
+
+	// Device identifies the file system the cached tree's root is located on.
+	Device string `json:"device"`
+
+	// AppVersion contains the version of the application that wrote the file.
+	AppVersion string `json:"appVersion"`
+
+	// ScanOptions contains the description of the scan options the cached data
+	// was built with.
+	ScanOptions string `json:"scanOptions"`
+
+	// FormatVersion contains the version of the cache file format.
+	FormatVersion uint16 `json:"-"`
+
+	// Summary contains the totals of the cached data if the cached value
+	// implements the Summarizer interface.
+	Summary Summary `json:"summary"`
+
+	// Compressed defines whether the cached data is compressed.
+	Compressed bool `json:"compressed"`
+
+	// size contains the number of bytes the header occupies in the file.
+	size int64
+}
+
+// Summary contains the totals of the cached tree.
+type Summary struct {
+	// Size contains the total apparent size of the files.
+	Size int64 `json:"size"`
+
+	// Usage contains the total allocated size of the files.
+	Usage int64 `json:"usage"`
+
+	// Dirs contains the total number of directories.
+	Dirs uint64 `json:"dirs"`
+
+	// Files contains the total number of files.
+	Files uint64 `json:"files"`
+}
+
+// Entries returns the total number of directories and files.
+func (s Summary) Entries() uint64 {
+	return s.Dirs + s.Files
+}
+
+// Summarizer is implemented by the cached values that can describe their content.
+// The summary is stored in the header, so it's available without decoding the
+// cached data.
+type Summarizer interface {
+	CacheSummary() Summary
+}
+
+// checksumWriter calculates the checksum of all bytes written to the underlying
+// writer.
+type checksumWriter struct {
+	w   io.Writer
+	crc hash.Hash32
+}
+
+func newChecksumWriter(w io.Writer) *checksumWriter {
+	return &checksumWriter{w: w, crc: crc32.New(crcTable)}
+}
+
+func (cw *checksumWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.crc.Write(p[:n])
+
+	return n, err
+}
+
+// writeTrailer writes the checksum of all previously written bytes. The
+// checksum itself is not a part of the calculation.
+func (cw *checksumWriter) writeTrailer() error {
+	_, err := cw.w.Write(binary.LittleEndian.AppendUint32(nil, cw.crc.Sum32()))
+
+	return err
+}
+
+// writeHeader writes the magic value, the format version, and the JSON-encoded
+// header.
+func writeHeader(w io.Writer, h Header) error {
+	rawHeader, err := json.Marshal(h)
+	if err != nil {
+		return fmt.Errorf("encode header: %w", err)
+	}
+
+	prefix := make([]byte, 0, prefixSize)
+	prefix = append(prefix, magic[:]...)
+	prefix = binary.LittleEndian.AppendUint16(prefix, FormatVersion)
+	//nolint:gosec // the header is small
+	prefix = binary.LittleEndian.AppendUint32(prefix, uint32(len(rawHeader)))
+
+	if _, err = w.Write(prefix); err != nil {
+		return err
+	}
+
+	_, err = w.Write(rawHeader)
+
+	return err
+}
+
+// readHeader reads and validates the header. The reader must be positioned at
+// the beginning of the cache file.
+func readHeader(r io.Reader) (Header, error) {
+	var h Header
+
+	prefix := make([]byte, prefixSize)
+
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		return h, fmt.Errorf("%w: read header: %w", ErrCorrupt, err)
+	}
+
+	if [8]byte(prefix[:len(magic)]) != magic {
+		return h, fmt.Errorf("%w: not a noxdir cache file", ErrIncompatible)
+	}
+
+	h.FormatVersion = binary.LittleEndian.Uint16(prefix[len(magic):])
+	if h.FormatVersion != FormatVersion {
+		return h, fmt.Errorf(
+			"%w: format version %d is not supported, expected %d",
+			ErrIncompatible,
+			h.FormatVersion,
+			FormatVersion,
+		)
+	}
+
+	headerSize := binary.LittleEndian.Uint32(prefix[len(magic)+2:])
+	if headerSize > maxHeaderSize {
+		return h, fmt.Errorf("%w: header size %d", ErrCorrupt, headerSize)
+	}
+
+	rawHeader := make([]byte, headerSize)
+
+	if _, err := io.ReadFull(r, rawHeader); err != nil {
+		return h, fmt.Errorf("%w: read header: %w", ErrCorrupt, err)
+	}
+
+	if err := json.Unmarshal(rawHeader, &h); err != nil {
+		return h, fmt.Errorf("%w: decode header: %w", ErrCorrupt, err)
+	}
+
+	h.size = int64(prefixSize) + int64(headerSize)
+
+	return h, nil
+}
+
+// verifyChecksum checks the trailing checksum of the cache file with the provided
+// size.
+func verifyChecksum(r io.Reader, size int64) error {
+	if size < int64(prefixSize+checksumSize) {
+		return fmt.Errorf("%w: file is truncated", ErrCorrupt)
+	}
+
+	crc := crc32.New(crcTable)
+	br := bufio.NewReaderSize(r, 1<<20)
+
+	if _, err := io.CopyN(crc, br, size-checksumSize); err != nil {
+		return fmt.Errorf("%w: read data: %w", ErrCorrupt, err)
+	}
+
+	trailer := make([]byte, checksumSize)
+
+	if _, err := io.ReadFull(br, trailer); err != nil {
+		return fmt.Errorf("%w: read checksum: %w", ErrCorrupt, err)
+	}
+
+	if binary.LittleEndian.Uint32(trailer) != crc.Sum32() {
+		return fmt.Errorf("%w: checksum mismatch", ErrCorrupt)
+	}
+
+	return nil
+}
@@ -0,0 +1,219 @@
+package cache
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// historyDir defines the name of the directory within the cache directory the
+// snapshots are stored in.
+const historyDir = "history"
+
+// WithHistory enables keeping the previous states of the cache entries. Each
+// time an entry is written, its snapshot is added to the entry's history, where
+// at most the provided number of snapshots is kept. No snapshot is added within
+// the provided interval since the latest one; hence, the frequent writes do not
+// push the older snapshots out of the history.
+func WithHistory(size int, interval time.Duration) Option {
+	return func(c *Cache) {
+		c.historySize = size
+		c.historyInterval = interval
+	}
+}
+
+// FileName returns the name of the cache file for the provided key, which can be
+// used to get the entry's history.
+func (c *Cache) FileName(key Key) string {
+	return c.keyHash(key)
+}
+
+// History returns the snapshots of the cache file with the provided name, newest
+// first. The snapshot names can be used with GetSnapshot, Remove, Verify, and
+// Export the same way as the cache file names.
+func (c *Cache) History(name string) ([]File, error) {
+	files, err := c.historyFiles(filepath.Base(name) + ".")
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(files, func(a, b File) int {
+		return b.Header.CreatedAt.Compare(a.Header.CreatedAt)
+	})
+
+	return files, nil
+}
+
+// GetSnapshot retrieves the snapshot with the provided name, which belongs to the
+// provided key's history, and maps data to the target the same way as Get does.
+func (c *Cache) GetSnapshot(key Key, name string, target any) error {
+	snapshotPath := c.filePath(name)
+
+	snapshotFile, err := os.Open(snapshotPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return ErrNoCache
+		}
+
+		return err
+	}
+
+	defer func() {
+		_ = snapshotFile.Close()
+	}()
+
+	if err = c.decode(snapshotFile, key, target); err != nil {
+		if errors.Is(err, ErrCorrupt) || errors.Is(err, ErrIncompatible) {
+			_ = os.Remove(snapshotPath)
+		}
+
+		return fmt.Errorf("snapshot %s: %w", snapshotPath, err)
+	}
+
+	return nil
+}
+
+// addSnapshot adds the current state of the cache file with the provided name to
+// its history, and removes the snapshots exceeding the history size. Nothing is
+// added if the latest snapshot was created within the history interval. The cache
+// files are never modified in place; hence, the snapshot is a hard link to the
+// cache file if the file system supports it.
+func (c *Cache) addSnapshot(name string) error {
+	if err := os.MkdirAll(filepath.Join(c.cachePath, historyDir), 0750); err != nil {
+		return fmt.Errorf("create history dir: %w", err)
+	}
+
+	header, err := c.readFileHeader(name)
+	if err != nil {
+		return err
+	}
+
+	snapshots, err := c.History(name)
+	if err != nil {
+		return err
+	}
+
+	// the latest snapshot is too recent, so the current state is not added
+	if len(snapshots) > 0 &&
+		header.CreatedAt.Sub(snapshots[0].Header.CreatedAt) < c.historyInterval {
+		return nil
+	}
+
+	src := filepath.Join(c.cachePath, name)
+	dst := c.filePath(snapshotName(name, header.CreatedAt))
+
+	if err = os.Link(src, dst); err != nil {
+		if err = copyFile(src, dst); err != nil {
+			return fmt.Errorf("add snapshot: %w", err)
+		}
+	}
+
+	for _, outdated := range snapshots[min(len(snapshots), max(0, c.historySize-1)):] {
+		if err = c.Remove(outdated.Name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// historyFiles returns the snapshots whose names start with the provided prefix,
+// in no particular order.
+func (c *Cache) historyFiles(prefix string) ([]File, error) {
+	dirEntries, err := os.ReadDir(filepath.Join(c.cachePath, historyDir))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("read history dir: %w", err)
+	}
+
+	files := make([]File, 0)
+
+	for _, de := range dirEntries {
+		if !de.Type().IsRegular() || !strings.HasPrefix(de.Name(), prefix) ||
+			strings.HasSuffix(de.Name(), tmpSuffix) {
+			continue
+		}
+
+		fi, err := de.Info()
+		if err != nil {
+			continue
+		}
+
+		f := File{
+			Name:     filepath.Join(historyDir, de.Name()),
+			Size:     fi.Size(),
+			ModTime:  fi.ModTime(),
+			Snapshot: true,
+		}
+		f.Header, f.Err = c.readFileHeader(f.Name)
+
+		files = append(files, f)
+	}
+
+	slices.SortFunc(files, func(a, b File) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	return files, nil
+}
+
+// filePath returns the path of the cache file or the snapshot with the provided
+// name. The names are never resolved outside the cache directory.
+func (c *Cache) filePath(name string) string {
+	dir, base := filepath.Split(filepath.Clean(name))
+
+	if filepath.Clean(dir) == historyDir {
+		return filepath.Join(c.cachePath, historyDir, base)
+	}
+
+	return filepath.Join(c.cachePath, base)
+}
+
+// isSnapshot reports whether the name refers to a snapshot rather than a cache
+// file.
+func isSnapshot(name string) bool {
+	dir, _ := filepath.Split(filepath.Clean(name))
+
+	return filepath.Clean(dir) == historyDir
+}
+
+// snapshotName returns the name of the cache file's snapshot created at the
+// provided time.
+func snapshotName(name string, createdAt time.Time) string {
+	return filepath.Join(historyDir, name+"."+strconv.FormatInt(createdAt.UnixNano(), 10))
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = in.Close()
+	}()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(out, in); err != nil {
+		_ = out.Close()
+		_ = os.Remove(dst)
+
+		return err
+	}
+
+	return out.Close()
+}
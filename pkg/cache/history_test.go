@@ -0,0 +1,76 @@
+package cache_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/crumbyte/noxdir/pkg/cache"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache_History(t *testing.T) {
+	c, _ := newTestCache(t, cache.WithHistory(2, 0))
+
+	for _, name := range []string{"first", "second", "third"} {
+		require.NoError(t, c.Set(testKey, testValue{Name: name}))
+	}
+
+	snapshots, err := c.History(c.FileName(testKey))
+	require.NoError(t, err)
+	require.Len(t, snapshots, 2)
+	require.True(t, snapshots[0].Snapshot)
+	require.True(t, snapshots[0].Header.CreatedAt.After(snapshots[1].Header.CreatedAt))
+
+	var restored testValue
+
+	require.NoError(t, c.GetSnapshot(testKey, snapshots[1].Name, &restored))
+	require.Equal(t, "second", restored.Name)
+	require.NoError(t, c.Verify(snapshots[1].Name))
+
+	// the snapshots are not listed as the cache entries
+	files, err := c.Files()
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	require.NoError(t, c.Remove(files[0].Name))
+
+	snapshots, err = c.History(c.FileName(testKey))
+	require.NoError(t, err)
+	require.Empty(t, snapshots)
+}
+
+func TestCache_HistoryInterval(t *testing.T) {
+	const interval = 150 * time.Millisecond
+
+	c, _ := newTestCache(t, cache.WithHistory(10, interval))
+
+	// the writes are more frequent than the interval, so only some of them
+	// are kept, but the history still builds up
+	for i := range 8 {
+		require.NoError(t, c.Set(testKey, testValue{Name: strconv.Itoa(i)}))
+
+		time.Sleep(interval / 3)
+	}
+
+	snapshots, err := c.History(c.FileName(testKey))
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, len(snapshots), 2)
+	require.Less(t, len(snapshots), 8)
+
+	for i := 1; i < len(snapshots); i++ {
+		gap := snapshots[i-1].Header.CreatedAt.Sub(snapshots[i].Header.CreatedAt)
+		require.GreaterOrEqual(t, gap, interval)
+	}
+
+	// the first write is kept rather than replaced by the later ones
+	var restored testValue
+
+	require.NoError(t, c.GetSnapshot(testKey, snapshots[len(snapshots)-1].Name, &restored))
+	require.Equal(t, "0", restored.Name)
+
+	removed, err := c.Prune(time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, removed, len(snapshots)+1)
+}
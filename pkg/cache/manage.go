@@ -0,0 +1,356 @@
+package cache
+
+import (
+	"bufio"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// tmpSuffix defines the suffix of the temporary files the cache entries are
+// written to before they replace the actual cache files.
+const tmpSuffix = ".tmp"
+
+// File describes a single file stored in the cache directory.
+type File struct {
+	// ModTime contains the time the file was last modified.
+	ModTime time.Time
+
+	// Err contains the error that occurred while reading the file's header. The
+	// Header is empty in this case.
+	Err error
+
+	// Name contains the file's name within the cache directory.
+	Name string
+
+	// Header contains the file's header.
+	Header Header
+
+	// Size contains the file's size on the disk.
+	Size int64
+
+	// Snapshot defines whether the file is a snapshot from the cache entry's
+	// history rather than the cache entry itself.
+	Snapshot bool
+}
+
+// Dir returns the directory the cache files are stored in.
+func (c *Cache) Dir() string {
+	return c.cachePath
+}
+
+// Files returns all files stored in the cache directory, sorted by the cached
+// root's path and then by the creation time, newest first. The files whose
+// header cannot be read are returned as well, with the corresponding error.
+func (c *Cache) Files() ([]File, error) {
+	dirEntries, err := os.ReadDir(c.cachePath)
+	if err != nil {
+		return nil, fmt.Errorf("read cache dir: %w", err)
+	}
+
+	files := make([]File, 0, len(dirEntries))
+
+	for _, de := range dirEntries {
+		if !de.Type().IsRegular() || strings.HasSuffix(de.Name(), tmpSuffix) {
+			continue
+		}
+
+		fi, err := de.Info()
+		if err != nil {
+			continue
+		}
+
+		f := File{Name: de.Name(), Size: fi.Size(), ModTime: fi.ModTime()}
+		f.Header, f.Err = c.readFileHeader(de.Name())
+
+		files = append(files, f)
+	}
+
+	slices.SortFunc(files, func(a, b File) int {
+		return cmp.Or(
+			cmp.Compare(a.Header.Path, b.Header.Path),
+			b.Header.CreatedAt.Compare(a.Header.CreatedAt),
+		)
+	})
+
+	return files, nil
+}
+
+// Remove removes the cache file with the provided name along with its history,
+// or a single snapshot if the name refers to the snapshot.
+func (c *Cache) Remove(name string) error {
+	if err := os.Remove(c.filePath(name)); err != nil {
+		return fmt.Errorf("remove cache file: %w", err)
+	}
+
+	if isSnapshot(name) {
+		return nil
+	}
+
+	snapshots, err := c.historyFiles(filepath.Base(name) + ".")
+	if err != nil {
+		return err
+	}
+
+	for _, s := range snapshots {
+		if err = os.Remove(c.filePath(s.Name)); err != nil {
+			return fmt.Errorf("remove snapshot: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Verify checks the header and the checksum of the cache file with the provided
+// name. The file is not removed if it's invalid.
+func (c *Cache) Verify(name string) error {
+	f, err := os.Open(c.filePath(name))
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = f.Close()
+	}()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	if _, err = readHeader(bufio.NewReader(f)); err != nil {
+		return err
+	}
+
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	return verifyChecksum(f, fi.Size())
+}
+
+// Export writes the cache file with the provided name to the writer as is. The
+// exported file is self-contained and can be imported by Import on another
+// machine. The file is verified before it's exported.
+func (c *Cache) Export(name string, w io.Writer) error {
+	if err := c.Verify(name); err != nil {
+		return err
+	}
+
+	f, err := os.Open(c.filePath(name))
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = f.Close()
+	}()
+
+	if _, err = io.Copy(w, f); err != nil {
+		return fmt.Errorf("export cache file: %w", err)
+	}
+
+	return nil
+}
+
+// Import verifies the exported cache file located at the provided path and
+// adds it to the cache. If the device value is not empty, it replaces the file
+// system the snapshot was created for, so the snapshot is used for the local
+// file system mounted at the same path. The imported file's header is returned.
+//
+// The imported file replaces the existing cache entry for the same root,
+// device, and scan options.
+func (c *Cache) Import(src, device string) (Header, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return Header{}, err
+	}
+
+	defer func() {
+		_ = f.Close()
+	}()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return Header{}, err
+	}
+
+	header, err := readHeader(bufio.NewReader(f))
+	if err != nil {
+		return header, err
+	}
+
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		return header, err
+	}
+
+	if err = verifyChecksum(f, fi.Size()); err != nil {
+		return header, err
+	}
+
+	if len(device) != 0 {
+		header.Device = device
+	}
+
+	if _, err = f.Seek(header.size, io.SeekStart); err != nil {
+		return header, err
+	}
+
+	name := fileName(Key{Path: header.Path, Device: header.Device}, header.ScanOptions)
+
+	// the header is written again, since the device might have changed, and
+	// the checksum is recalculated respectively.
+	err = c.writeFile(name, func(w io.Writer) error {
+		cw := newChecksumWriter(w)
+
+		if err := writeHeader(cw, header); err != nil {
+			return fmt.Errorf("write cache header: %w", err)
+		}
+
+		if _, err := io.CopyN(cw, f, fi.Size()-header.size-checksumSize); err != nil {
+			return fmt.Errorf("copy cache data: %w", err)
+		}
+
+		return cw.writeTrailer()
+	})
+
+	return header, err
+}
+
+// Prune removes the cache files and the snapshots created before the provided
+// time, the files whose header cannot be read, and the leftover temporary files.
+// It returns the removed files. The snapshots are never newer than their cache
+// file; hence, the entire history of the removed cache file is removed as well.
+func (c *Cache) Prune(before time.Time) ([]File, error) {
+	files, err := c.Files()
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots, err := c.historyFiles("")
+	if err != nil {
+		return nil, err
+	}
+
+	files = append(files, snapshots...)
+
+	removed := make([]File, 0)
+
+	for _, f := range files {
+		if f.Err == nil && !f.Header.CreatedAt.Before(before) {
+			continue
+		}
+
+		// the snapshot was removed along with its cache file
+		if err = c.Remove(f.Name); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return removed, err
+		}
+
+		removed = append(removed, f)
+	}
+
+	tmpFiles, err := filepath.Glob(filepath.Join(c.cachePath, "*"+tmpSuffix))
+	if err != nil {
+		return removed, err
+	}
+
+	for _, tmpFile := range tmpFiles {
+		if fi, err := os.Stat(tmpFile); err == nil && fi.ModTime().Before(before) {
+			_ = os.Remove(tmpFile)
+		}
+	}
+
+	return removed, nil
+}
+
+// ReadHeader reads the header of the cache file located at the provided path,
+// e.g., an exported cache file. The checksum is not verified.
+func ReadHeader(path string) (Header, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Header{}, err
+	}
+
+	defer func() {
+		_ = f.Close()
+	}()
+
+	return readHeader(bufio.NewReader(f))
+}
+
+// ReadFile reads the cache file located at the provided path, e.g., an exported
+// cache file copied from another machine, and maps its data to the target using
+// the provided decoder. Unlike Get, the header is not matched against the key
+// or the scan options, but the checksum is still verified. The file's header is
+// returned.
+func ReadFile(path string, nd NewDecoder, target any) (Header, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Header{}, err
+	}
+
+	defer func() {
+		_ = f.Close()
+	}()
+
+	header, err := readHeader(bufio.NewReader(f))
+	if err != nil {
+		return header, err
+	}
+
+	if err = decodeData(f, header, nd, target); err != nil {
+		return header, fmt.Errorf("cache file %s: %w", path, err)
+	}
+
+	return header, nil
+}
+
+// writeFile writes the cache file with the provided name using a temporary file
+// that replaces the existing file once it's completely written.
+func (c *Cache) writeFile(name string, write func(io.Writer) error) error {
+	tmpFile, err := os.CreateTemp(c.cachePath, name+".*"+tmpSuffix)
+	if err != nil {
+		return fmt.Errorf("create cache file: %w", err)
+	}
+
+	defer func() {
+		_ = tmpFile.Close()
+		_ = os.Remove(tmpFile.Name())
+	}()
+
+	bufferedWriter := bufio.NewWriterSize(tmpFile, 5<<20)
+
+	if err = write(bufferedWriter); err != nil {
+		return err
+	}
+
+	if err = bufferedWriter.Flush(); err != nil {
+		return fmt.Errorf("write cache file: %w", err)
+	}
+
+	if err = tmpFile.Sync(); err != nil {
+		return fmt.Errorf("sync cache file: %w", err)
+	}
+
+	if err = tmpFile.Close(); err != nil {
+		return fmt.Errorf("close cache file: %w", err)
+	}
+
+	if err = os.Rename(tmpFile.Name(), filepath.Join(c.cachePath, name)); err != nil {
+		return fmt.Errorf("replace cache file: %w", err)
+	}
+
+	return nil
+}
+
+// readFileHeader reads the header of the cache file or the snapshot with the
+// provided name.
+func (c *Cache) readFileHeader(name string) (Header, error) {
+	return ReadHeader(c.filePath(name))
+}
@@ -0,0 +1,105 @@
+package cache_test
+
+import (
+	"bytes"
+	"encoding/gob"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/crumbyte/noxdir/pkg/cache"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache_Files(t *testing.T) {
+	c, dir := newTestCache(t, cache.WithCompress())
+
+	require.NoError(t, c.Set(cache.Key{Path: "/b", Device: "uuid:1"}, testValue{Name: "b"}))
+	require.NoError(t, c.Set(cache.Key{Path: "/a", Device: "uuid:1"}, testValue{Name: "a"}))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken"), []byte("x"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "entry.123.tmp"), []byte("x"), 0600))
+
+	files, err := c.Files()
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+
+	// the unreadable files have no path and go first
+	require.Equal(t, "broken", files[0].Name)
+	require.ErrorIs(t, files[0].Err, cache.ErrCorrupt)
+
+	require.Equal(t, "/a", files[1].Header.Path)
+	require.Equal(t, "/b", files[2].Header.Path)
+	require.NoError(t, c.Verify(files[1].Name))
+
+	removed, err := c.Prune(time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	require.Len(t, removed, 1)
+	require.Equal(t, "broken", removed[0].Name)
+
+	removed, err = c.Prune(time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, removed, 2)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+func TestCache_ExportImport(t *testing.T) {
+	src, srcDir := newTestCache(t, cache.WithCompress(), cache.WithScanOptions("max-depth=0"))
+	dst, _ := newTestCache(t, cache.WithScanOptions("max-depth=0"))
+
+	val := testValue{Name: "root", Sizes: []int64{1, 2, 3}}
+
+	require.NoError(t, src.Set(testKey, val))
+
+	exported := new(bytes.Buffer)
+
+	require.NoError(t, src.Export(filepath.Base(cacheFile(t, srcDir)), exported))
+
+	exportPath := filepath.Join(t.TempDir(), "export")
+	require.NoError(t, os.WriteFile(exportPath, exported.Bytes(), 0600))
+
+	h, err := cache.ReadHeader(exportPath)
+	require.NoError(t, err)
+	require.Equal(t, testKey.Path, h.Path)
+
+	// the exported file is read without the cache and its scan options
+	var offline testValue
+
+	h, err = cache.ReadFile(exportPath, newGobDecoder, &offline)
+	require.NoError(t, err)
+	require.Equal(t, val, offline)
+
+	hostname, _ := os.Hostname()
+	require.Equal(t, hostname, h.Host)
+
+	// the snapshot is bound to the local file system
+	rebound := cache.Key{Path: testKey.Path, Device: "uuid:5678"}
+
+	h, err = dst.Import(exportPath, rebound.Device)
+	require.NoError(t, err)
+	require.Equal(t, rebound.Device, h.Device)
+	require.False(t, dst.Has(testKey))
+
+	var restored testValue
+
+	require.NoError(t, dst.Get(rebound, &restored))
+	require.Equal(t, val, restored)
+
+	exported.Bytes()[exported.Len()/2] ^= 0xff
+	require.NoError(t, os.WriteFile(exportPath, exported.Bytes(), 0600))
+
+	_, err = dst.Import(exportPath, "")
+	require.ErrorIs(t, err, cache.ErrCorrupt)
+
+	_, err = cache.ReadFile(exportPath, newGobDecoder, &offline)
+	require.ErrorIs(t, err, cache.ErrCorrupt)
+}
+
+func newGobDecoder(r io.Reader) cache.Decoder {
+	return gob.NewDecoder(r)
+}
@@ -0,0 +1,284 @@
+package exclude
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// regexPrefix defines a prefix for the rules that must be treated as regular
+// expressions rather than glob patterns.
+const regexPrefix = "re:"
+
+// Option defines a type for providing configuration options for Matcher instance.
+type Option func(*Matcher)
+
+// WithCaseSensitive overrides the default case sensitivity of the rules. By
+// default, the rules are case-insensitive on Windows and macOS, and
+// case-sensitive on other systems.
+func WithCaseSensitive(caseSensitive bool) Option {
+	return func(m *Matcher) {
+		m.caseSensitive = caseSensitive
+	}
+}
+
+// WithRegexp defines whether the rules with the "re:" prefix are treated as
+// regular expressions. If disabled, such rules are treated as regular glob
+// patterns, which is required for the gitignore compatibility. The regular
+// expressions are enabled by default.
+func WithRegexp(enabled bool) Option {
+	return func(m *Matcher) {
+		m.regexp = enabled
+	}
+}
+
+type rule struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// Matcher matches relative paths against a list of exclusion rules. The rules
+// follow the gitignore syntax:
+//
+//   - "*" matches anything except the separator, "?" matches any single
+//     character except the separator, and "[a-z]" matches a character range;
+//   - "**" matches any number of directories, e.g., "**/logs", "logs/**",
+//     "a/**/b", while "**" next to other characters works as "*", e.g., "a**b";
+//   - a pattern without a separator matches the name at any level, e.g.,
+//     "node_modules", while a pattern with a leading or a middle separator is
+//     anchored to the base directory, e.g., "/build" or "src/gen";
+//   - a pattern with a trailing separator matches directories only, e.g., "tmp/";
+//   - a pattern with a leading "!" negates the rule and re-includes the path
+//     excluded by the previous rules, e.g., "!keep/";
+//   - a pattern with a leading "re:" is a regular expression matched against the
+//     full relative path, e.g., "re:^cache-[0-9]+$";
+//   - the trailing spaces are ignored unless they're escaped with a backslash,
+//     e.g., "name\ ", while the leading spaces are a part of the pattern.
+//
+// The last matching rule defines the result.
+type Matcher struct {
+	rules         []rule
+	caseSensitive bool
+	regexp        bool
+}
+
+// New creates a new Matcher instance for the provided list of rules. Empty rules
+// and rules starting with "#" are ignored. An error will be returned if any of
+// the rules cannot be compiled.
+func New(patterns []string, opts ...Option) (*Matcher, error) {
+	m := &Matcher{
+		rules:         make([]rule, 0, len(patterns)),
+		caseSensitive: runtime.GOOS != "windows" && runtime.GOOS != "darwin",
+		regexp:        true,
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	for _, pattern := range patterns {
+		r, ok, err := m.compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("exclude: compile rule %q: %w", pattern, err)
+		}
+
+		if ok {
+			m.rules = append(m.rules, r)
+		}
+	}
+
+	return m, nil
+}
+
+// ReadPatterns reads the rules from the provided reader, one rule per line, as
+// they are defined in the gitignore files. The rules can be passed to the New
+// function as is since the empty lines and comments are skipped there.
+func ReadPatterns(r io.Reader) ([]string, error) {
+	var patterns []string
+
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		patterns = append(patterns, strings.TrimSuffix(scanner.Text(), "\r"))
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("exclude: read patterns: %w", err)
+	}
+
+	return patterns, nil
+}
+
+// Empty reports whether the matcher has no rules.
+func (m *Matcher) Empty() bool {
+	return m == nil || len(m.rules) == 0
+}
+
+// Match reports whether the provided path must be excluded. The path must be
+// relative to the base directory the rules are defined for. Both "/" and the
+// OS-specific separators are supported.
+func (m *Matcher) Match(relPath string, isDir bool) bool {
+	excluded, _ := m.MatchResult(relPath, isDir)
+
+	return excluded
+}
+
+// MatchResult works like Match but additionally reports whether any of the
+// rules matched the path. It allows combining several matchers, e.g., defined
+// on different directory levels, where the deepest matched one must win even if
+// it re-includes the path.
+func (m *Matcher) MatchResult(relPath string, isDir bool) (bool, bool) {
+	if m.Empty() {
+		return false, false
+	}
+
+	relPath = strings.Trim(filepath.ToSlash(relPath), "/")
+	excluded, matched := false, false
+
+	for i := range m.rules {
+		if m.rules[i].dirOnly && !isDir {
+			continue
+		}
+
+		if m.rules[i].re.MatchString(relPath) {
+			excluded, matched = !m.rules[i].negate, true
+		}
+	}
+
+	return excluded, matched
+}
+
+func (m *Matcher) compile(pattern string) (rule, bool, error) {
+	var r rule
+
+	pattern = trimTrailingSpaces(pattern)
+
+	if len(pattern) == 0 || pattern[0] == '#' {
+		return r, false, nil
+	}
+
+	if pattern[0] == '!' {
+		r.negate, pattern = true, pattern[1:]
+	}
+
+	expr := ""
+
+	if m.regexp && strings.HasPrefix(pattern, regexPrefix) {
+		expr = strings.TrimPrefix(pattern, regexPrefix)
+	} else {
+		pattern = filepath.ToSlash(pattern)
+
+		if strings.HasSuffix(pattern, "/") {
+			r.dirOnly, pattern = true, strings.TrimRight(pattern, "/")
+		}
+
+		if len(pattern) == 0 {
+			return r, false, nil
+		}
+
+		expr = globToRegexp(pattern)
+	}
+
+	if !m.caseSensitive {
+		expr = "(?i)" + expr
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return r, false, err
+	}
+
+	r.re = re
+
+	return r, true, nil
+}
+
+// globToRegexp converts a single gitignore-style glob pattern to the equivalent
+// regular expression matching the full relative path.
+func globToRegexp(pattern string) string {
+	var sb strings.Builder
+
+	sb.WriteByte('^')
+
+	// a pattern without a separator (except the trailing one) matches at any
+	// level, otherwise it's anchored to the base directory.
+	if !strings.Contains(pattern, "/") {
+		sb.WriteString("(?:.*/)?")
+	}
+
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/") && (i == 0 || pattern[i-1] == '/'):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case pattern[i:] == "**" && (i == 0 || pattern[i-1] == '/'):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			// the asterisks that are not a separate path segment match
+			// within a single segment
+			for i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+			}
+
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end == -1 {
+				sb.WriteString(`\[`)
+
+				continue
+			}
+
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+
+			sb.WriteString("[" + class + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(pattern):
+			sb.WriteString(regexp.QuoteMeta(string(pattern[i+1])))
+			i++
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	sb.WriteByte('$')
+
+	return sb.String()
+}
+
+// trimTrailingSpaces removes the trailing spaces from the pattern unless they're
+// escaped with a backslash, i.e., preceded by an odd number of backslashes.
+func trimTrailingSpaces(pattern string) string {
+	end := len(pattern)
+
+	for end > 0 && pattern[end-1] == ' ' {
+		backslashes := 0
+
+		for j := end - 2; j >= 0 && pattern[j] == '\\'; j-- {
+			backslashes++
+		}
+
+		if backslashes%2 == 1 {
+			break
+		}
+
+		end--
+	}
+
+	return pattern[:end]
+}
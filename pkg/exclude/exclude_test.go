@@ -0,0 +1,117 @@
+package exclude_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/crumbyte/noxdir/pkg/exclude"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatcher_Match(t *testing.T) {
+	tableData := []struct {
+		patterns []string
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{[]string{"sub"}, "sub", true, true},
+		{[]string{"sub"}, "a/b/sub", true, true},
+		{[]string{"sub"}, "subversion", true, false},
+		{[]string{"sub"}, "home/x/subtitles", true, false},
+		{[]string{"/sub"}, "sub", true, true},
+		{[]string{"/sub"}, "a/sub", true, false},
+		{[]string{"src/gen"}, "src/gen", true, true},
+		{[]string{"src/gen"}, "a/src/gen", true, false},
+		{[]string{"tmp/"}, "a/tmp", true, true},
+		{[]string{"tmp/"}, "a/tmp", false, false},
+		{[]string{"*.log"}, "var/app.log", false, true},
+		{[]string{"*.log"}, "var/app.log.1", false, false},
+		{[]string{"cache-?"}, "cache-1", true, true},
+		{[]string{"cache-[0-9]"}, "cache-a", true, false},
+		{[]string{"cache-[!0-9]"}, "cache-a", true, true},
+		{[]string{"**/logs"}, "logs", true, true},
+		{[]string{"**/logs"}, "a/b/logs", true, true},
+		{[]string{"logs/**"}, "logs/a/b", false, true},
+		{[]string{"logs/**"}, "logs", true, false},
+		{[]string{"a/**/b"}, "a/b", true, true},
+		{[]string{"a/**/b"}, "a/x/y/b", true, true},
+		{[]string{"a/*/b"}, "a/x/y/b", true, false},
+		{[]string{"a**b"}, "axyb", true, true},
+		{[]string{"a**b"}, "a/x/b", true, false},
+		{[]string{"/a/**b"}, "a/xb", true, true},
+		{[]string{"/a/**b"}, "a/x/b", true, false},
+		{[]string{"**.log"}, "var/app.log", false, true},
+		{[]string{"build  "}, "build", true, true},
+		{[]string{`build\ `}, "build ", true, true},
+		{[]string{`build\ `}, "build", true, false},
+		{[]string{" build"}, "build", true, false},
+		{[]string{" build"}, " build", true, true},
+		{[]string{"build*", "!build-keep/"}, "build-keep", true, false},
+		{[]string{"build*", "!build-keep/"}, "build-tmp", true, true},
+		{[]string{"re:^cache-[0-9]+$"}, "cache-123", true, true},
+		{[]string{"re:^cache-[0-9]+$"}, "a/cache-123", true, false},
+		{[]string{"# comment", ""}, "# comment", true, false},
+	}
+
+	for _, data := range tableData {
+		m, err := exclude.New(data.patterns, exclude.WithCaseSensitive(true))
+		require.NoError(t, err)
+
+		require.Equal(
+			t,
+			data.expected,
+			m.Match(data.path, data.isDir),
+			"patterns: %v, path: %s",
+			data.patterns,
+			data.path,
+		)
+	}
+}
+
+func TestMatcher_CaseSensitive(t *testing.T) {
+	m, err := exclude.New([]string{"Build"}, exclude.WithCaseSensitive(true))
+	require.NoError(t, err)
+	require.False(t, m.Match("build", true))
+
+	m, err = exclude.New([]string{"Build"}, exclude.WithCaseSensitive(false))
+	require.NoError(t, err)
+	require.True(t, m.Match("build", true))
+}
+
+func TestNew_InvalidRegexp(t *testing.T) {
+	_, err := exclude.New([]string{"re:[a-"})
+	require.Error(t, err)
+}
+
+func TestMatcher_MatchResult(t *testing.T) {
+	m, err := exclude.New([]string{"*.log", "!keep.log"})
+	require.NoError(t, err)
+
+	excluded, matched := m.MatchResult("keep.log", false)
+	require.False(t, excluded)
+	require.True(t, matched)
+
+	excluded, matched = m.MatchResult("main.go", false)
+	require.False(t, excluded)
+	require.False(t, matched)
+}
+
+func TestMatcher_WithoutRegexp(t *testing.T) {
+	m, err := exclude.New([]string{"re:[a-"}, exclude.WithRegexp(false))
+	require.NoError(t, err)
+	require.True(t, m.Match("re:[a-", false))
+}
+
+func TestReadPatterns(t *testing.T) {
+	patterns, err := exclude.ReadPatterns(
+		strings.NewReader("# build output\r\n/build\r\n\n*.log\n!keep.log"),
+	)
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		[]string{"# build output", "/build", "", "*.log", "!keep.log"},
+		patterns,
+	)
+}
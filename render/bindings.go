@@ -15,9 +15,11 @@ const (
 	backspace         bindingKey = "backspace"
 	quit              bindingKey = "q"
 	cancel            bindingKey = "ctrl+c"
+	escape            bindingKey = "esc"
 	enter             bindingKey = "enter"
 	explore           bindingKey = "e"
 	refresh           bindingKey = "r"
+	refreshChanged    bindingKey = "ctrl+r"
 	remove            bindingKey = "!"
 	sortTotalCap      bindingKey = "alt+t"
 	sortTotalUsed     bindingKey = "alt+u"
@@ -25,10 +27,15 @@ const (
 	sortTotalUsedP    bindingKey = "alt+g"
 	toggleTopFiles    bindingKey = "ctrl+q"
 	toggleTopDirs     bindingKey = "ctrl+e"
+	toggleHardLinks   bindingKey = "ctrl+l"
+	toggleGrowth      bindingKey = "ctrl+g"
+	toggleDiff        bindingKey = "ctrl+d"
+	exportDir         bindingKey = "ctrl+x"
 	toggleDirsFilter  bindingKey = "."
 	toggleFilesFilter bindingKey = ","
 	toggleNameFilter  bindingKey = "ctrl+f"
 	toggleChart       bindingKey = "ctrl+w"
+	toggleSizeMode    bindingKey = "u"
 	toggleHelp        bindingKey = "?"
 	left              bindingKey = "left"
 	right             bindingKey = "right"
@@ -163,6 +170,13 @@ func DirsKeyMap() [][]key.Binding {
 					style.Help().Render(" - quit"),
 				),
 			),
+			key.NewBinding(
+				key.WithKeys(escape.String(), cancel.String()),
+				key.WithHelp(
+					style.BindKey().Render(escape.String()+"/"+cancel.String()),
+					style.Help().Render(" - cancel scan"),
+				),
+			),
 		},
 		{
 			key.NewBinding(
@@ -179,6 +193,34 @@ func DirsKeyMap() [][]key.Binding {
 					style.Help().Render(" - toggle top dirs"),
 				),
 			),
+			key.NewBinding(
+				key.WithKeys(toggleHardLinks.String()),
+				key.WithHelp(
+					style.BindKey().Render(toggleHardLinks.String()),
+					style.Help().Render(" - toggle hard links"),
+				),
+			),
+			key.NewBinding(
+				key.WithKeys(toggleGrowth.String()),
+				key.WithHelp(
+					style.BindKey().Render(toggleGrowth.String()),
+					style.Help().Render(" - toggle fastest growing"),
+				),
+			),
+			key.NewBinding(
+				key.WithKeys(toggleDiff.String()),
+				key.WithHelp(
+					style.BindKey().Render(toggleDiff.String()),
+					style.Help().Render(" - compare with snapshot"),
+				),
+			),
+			key.NewBinding(
+				key.WithKeys(exportDir.String()),
+				key.WithHelp(
+					style.BindKey().Render(exportDir.String()),
+					style.Help().Render(" - export current dir"),
+				),
+			),
 			key.NewBinding(
 				key.WithKeys(toggleNameFilter.String()),
 				key.WithHelp(
@@ -193,6 +235,13 @@ func DirsKeyMap() [][]key.Binding {
 					style.Help().Render(" - usage chart"),
 				),
 			),
+			key.NewBinding(
+				key.WithKeys(toggleSizeMode.String()),
+				key.WithHelp(
+					style.BindKey().Render(toggleSizeMode.String()),
+					style.Help().Render(" - toggle apparent/on-disk size"),
+				),
+			),
 		},
 		{
 			key.NewBinding(
@@ -216,6 +265,13 @@ func DirsKeyMap() [][]key.Binding {
 					style.Help().Render(" - refresh"),
 				),
 			),
+			key.NewBinding(
+				key.WithKeys(refreshChanged.String()),
+				key.WithHelp(
+					style.BindKey().Render(refreshChanged.String()),
+					style.Help().Render(" - refresh changed dirs"),
+				),
+			),
 			key.NewBinding(
 				key.WithKeys(remove.String()),
 				key.WithHelp(
@@ -67,6 +67,11 @@ type ColorSchema struct {
 	ActiveButtonText  string          `json:"activeButtonText"`
 	ActiveButtonBG    string          `json:"activeButtonBackground"`
 	FilterText        string          `json:"filterText"`
+	ExcludedText      string          `json:"excludedText"`
+	AddedText         string          `json:"addedText"`
+	RemovedText       string          `json:"removedText"`
+	MovedText         string          `json:"movedText"`
+	ModifiedText      string          `json:"modifiedText"`
 	ScanProgressBar   PG              `json:"scanProgressBar"`
 	UsageProgressBar  PG              `json:"usageProgressBar"`
 }
@@ -149,5 +154,10 @@ func DefaultColorSchema() ColorSchema {
 		ActiveButtonText:  "#FFFDF5",
 		ActiveButtonBG:    "#FF8531",
 		FilterText:        "#EBBD34",
+		ExcludedText:      "240",
+		AddedText:         "#8AC926",
+		RemovedText:       "#FF303E",
+		MovedText:         "#1982C4",
+		ModifiedText:      "#FFCA3A",
 	}
 }
@@ -1,18 +1,24 @@
 package render
 
 import (
+	"cmp"
 	"container/heap"
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
 	"runtime"
 	"slices"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/crumbyte/noxdir/drive"
 	"github.com/crumbyte/noxdir/filter"
 	"github.com/crumbyte/noxdir/render/table"
 	"github.com/crumbyte/noxdir/structure"
 
+	"github.com/charmbracelet/bubbles/progress"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
@@ -26,30 +32,42 @@ const (
 type Mode string
 
 const (
-	PENDING Mode = "PENDING"
-	READY   Mode = "READY"
-	INPUT   Mode = "INPUT"
-	DELETE  Mode = "DELETE"
+	PENDING  Mode = "PENDING"
+	READY    Mode = "READY"
+	INPUT    Mode = "INPUT"
+	DELETE   Mode = "DELETE"
+	SNAPSHOT Mode = "SNAPSHOT"
+	EXPORT   Mode = "EXPORT"
 )
 
 type DirModel struct {
-	columns       []Column
-	dirsTable     *table.Model
-	topFilesTable *table.Model
-	topDirsTable  *table.Model
-	deleteDialog  *DeleteDialogModel
-	nav           *Navigation
-	scanPG        *PG
-	usagePG       *PG
-	filters       filter.FiltersList
-	mode          Mode
-	lastErr       []error
-	height        int
-	width         int
-	showTopFiles  bool
-	showTopDirs   bool
-	fullHelp      bool
-	showCart      bool
+	columns        []Column
+	dirsTable      *table.Model
+	topFilesTable  *table.Model
+	topDirsTable   *table.Model
+	linksTable     *table.Model
+	growthTable    *table.Model
+	deleteDialog   *DeleteDialogModel
+	snapshotDialog *SnapshotDialogModel
+	exportDialog   *ExportDialogModel
+	nav            *Navigation
+	scanPG         *PG
+	usagePG        *PG
+	filters        filter.FiltersList
+	mode           Mode
+	lastExport     string
+	sizeMode       structure.SizeMode
+	lastErr        []error
+	watchErr       error
+	height         int
+	width          int
+	showTopFiles   bool
+	showTopDirs    bool
+	showLinks      bool
+	showGrowth     bool
+	fullHelp       bool
+	showCart       bool
+	watchStale     bool
 }
 
 func NewDirModel(nav *Navigation, filters ...filter.EntryFilter) *DirModel {
@@ -81,6 +99,8 @@ func NewDirModel(nav *Navigation, filters ...filter.EntryFilter) *DirModel {
 		dirsTable:     buildTable(),
 		topFilesTable: buildTable(),
 		topDirsTable:  buildTable(),
+		linksTable:    buildTable(),
+		growthTable:   buildTable(),
 		mode:          PENDING,
 		nav:           nav,
 		scanPG:        &style.CS().ScanProgressBar,
@@ -98,6 +118,12 @@ func NewDirModel(nav *Navigation, filters ...filter.EntryFilter) *DirModel {
 	dm.topDirsTable.SetStyles(s)
 	dm.topDirsTable.SetHeight(topFilesTableHeight)
 
+	dm.linksTable.SetStyles(s)
+	dm.linksTable.SetHeight(topFilesTableHeight)
+
+	dm.growthTable.SetStyles(s)
+	dm.growthTable.SetHeight(topFilesTableHeight)
+
 	return dm
 }
 
@@ -119,31 +145,61 @@ func (dm *DirModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				teaProg.Send(EnqueueRefresh{})
 			}()
 		}
+	case SnapshotSelected:
+		dm.mode, dm.snapshotDialog = READY, nil
+
+		if msg.Snapshot != nil {
+			if err := dm.nav.CompareWith(*msg.Snapshot); err != nil {
+				dm.lastErr = append(dm.lastErr, err)
+			}
+		}
+
+		dm.updateTableData()
+	case ExportFinished:
+		dm.mode, dm.exportDialog = READY, nil
+
+		if msg.Err != nil {
+			dm.lastErr = append(dm.lastErr, msg.Err)
+		} else if len(msg.Path) != 0 {
+			dm.lastExport = msg.Path
+		}
 	case UpdateDirState:
 		dm.mode = PENDING
-		runtime.GC()
-		dm.nav.tree.CalculateSize()
 
 		dm.updateTableData()
+
+		if dm.topShown() {
+			dm.updateTopEntries()
+		}
 	case ScanFinished:
 		dm.mode = READY
 
+		// only the rescan of the entire tree brings back the dropped changes
+		if dm.nav.Entry() == dm.nav.tree.Root() {
+			dm.watchStale = false
+		}
+
 		runtime.GC()
-		dm.nav.tree.CalculateSize()
 		dm.updateTableData()
-
-		dm.topFilesTable.SetRows(nil)
-		dm.topDirsTable.SetRows(nil)
-
-		structure.TopEntriesInstance.ScanFiles(dm.nav.Entry())
-		structure.TopEntriesInstance.ScanDirs(dm.nav.Entry())
-
-		dm.fillTopEntries(structure.TopEntriesInstance.Files(), dm.topFilesTable)
-		dm.fillTopEntries(structure.TopEntriesInstance.Dirs(), dm.topDirsTable)
+		dm.updateTopEntries()
+	case WatchError:
+		dm.watchFailed(msg.Err)
+	case WatchUpdate:
+		if dm.mode == READY && !dm.nav.OnDrives() {
+			dm.updateTableData()
+			dm.fillGrowth()
+		}
 	case tea.WindowSizeMsg:
 		dm.updateSize(msg.Width, msg.Height)
 		dm.filters.Update(msg)
 	case tea.KeyMsg:
+		// the export dialog's command writes the file in the background
+		if dm.mode == EXPORT && !dm.nav.OnDrives() {
+			_, cmd = dm.exportDialog.Update(msg)
+
+			return dm, cmd
+		}
+
 		if dm.nav.OnDrives() || dm.handleKeyBindings(msg) {
 			return dm, nil
 		}
@@ -229,29 +285,62 @@ func (dm *DirModel) View() string {
 		)
 	}
 
+	if dm.mode == SNAPSHOT {
+		return OverlayCenter(
+			dm.width,
+			dm.height,
+			bg,
+			dm.snapshotDialog.View(),
+		)
+	}
+
+	if dm.mode == EXPORT {
+		return OverlayCenter(
+			dm.width,
+			dm.height,
+			bg,
+			dm.exportDialog.View(),
+		)
+	}
+
 	return bg
 }
 
 func (dm *DirModel) viewTop() (string, bool) {
-	if !dm.showTopDirs && !dm.showTopFiles {
+	if !dm.showTopDirs && !dm.showTopFiles && !dm.showLinks && !dm.showGrowth {
 		return "", false
 	}
 
 	topTable := dm.topFilesTable
-	if dm.showTopDirs {
+
+	switch {
+	case dm.showTopDirs:
 		topTable = dm.topDirsTable
+	case dm.showLinks:
+		topTable = dm.linksTable
+	case dm.showGrowth:
+		topTable = dm.growthTable
 	}
 
 	return topTable.View(), true
 }
 
 func (dm *DirModel) handleKeyBindings(msg tea.KeyMsg) bool {
+	bk := bindingKey(strings.ToLower(msg.String()))
+
+	// the tree can be browsed while it's being scanned, so only the actions
+	// that don't change the tree or the mode are allowed.
 	if dm.mode == PENDING {
+		if bk == escape || bk == cancel {
+			dm.nav.CancelScan()
+		}
+
+		dm.handleToggles(bk)
+
 		return false
 	}
 
-	bk := bindingKey(strings.ToLower(msg.String()))
-	if bk == toggleNameFilter {
+	if bk == toggleNameFilter && (dm.mode == READY || dm.mode == INPUT) {
 		if dm.mode == READY {
 			dm.mode = INPUT
 		} else {
@@ -261,7 +350,8 @@ func (dm *DirModel) handleKeyBindings(msg tea.KeyMsg) bool {
 		dm.filters.ToggleFilter(filter.NameFilterID)
 	}
 
-	if dm.handleDeletion(bk, msg) {
+	if dm.handleDeletion(bk, msg) || dm.handleSnapshot(bk, msg) ||
+		dm.handleExport(bk) {
 		return true
 	}
 
@@ -272,21 +362,41 @@ func (dm *DirModel) handleKeyBindings(msg tea.KeyMsg) bool {
 		return true
 	}
 
+	if bk == explore && !dm.nav.ReadOnly() && dm.handleExploreKey() {
+		return true
+	}
+
+	dm.handleToggles(bk)
+
+	return false
+}
+
+// handleToggles handles the key bindings that only change the way the current
+// entry is shown, e.g., the chart or the top files.
+func (dm *DirModel) handleToggles(bk bindingKey) {
 	switch bk {
 	case toggleChart:
 		dm.showCart = !dm.showCart
 		dm.updateTableData()
 	case toggleHelp:
 		dm.fullHelp = !dm.fullHelp
-	case explore:
-		if dm.handleExploreKey() {
-			return true
-		}
+	case toggleSizeMode:
+		dm.toggleSizeMode()
 	case toggleTopFiles:
-		dm.showTopFiles = !dm.showTopFiles && !dm.showTopDirs
+		dm.showTopFiles = !dm.showTopFiles && !dm.showTopDirs && !dm.showLinks &&
+			!dm.showGrowth
 		dm.updateSize(dm.width, dm.height)
 	case toggleTopDirs:
-		dm.showTopDirs = !dm.showTopDirs && !dm.showTopFiles
+		dm.showTopDirs = !dm.showTopDirs && !dm.showTopFiles && !dm.showLinks &&
+			!dm.showGrowth
+		dm.updateSize(dm.width, dm.height)
+	case toggleHardLinks:
+		dm.showLinks = !dm.showLinks && !dm.showTopFiles && !dm.showTopDirs &&
+			!dm.showGrowth
+		dm.updateSize(dm.width, dm.height)
+	case toggleGrowth:
+		dm.showGrowth = !dm.showGrowth && !dm.showTopFiles && !dm.showTopDirs &&
+			!dm.showLinks
 		dm.updateSize(dm.width, dm.height)
 	case toggleDirsFilter:
 		dm.filters.ToggleFilter(filter.DirsOnlyFilterID)
@@ -296,16 +406,24 @@ func (dm *DirModel) handleKeyBindings(msg tea.KeyMsg) bool {
 		dm.updateTableData()
 	}
 
-	return false
+	// the top entries are not collected until the scan is complete
+	if dm.mode == PENDING && dm.topShown() {
+		dm.updateTopEntries()
+	}
+}
+
+// topShown reports whether any of the top entries tables is shown.
+func (dm *DirModel) topShown() bool {
+	return dm.showTopFiles || dm.showTopDirs || dm.showLinks
 }
 
 func (dm *DirModel) viewChart() string {
-	chartSectors := make([]RawChartSector, 0, len(dm.nav.entry.Child))
+	var chartSectors []RawChartSector
 
-	for _, child := range dm.nav.entry.Child {
+	for child := range dm.nav.entry.Entries() {
 		chartSectors = append(chartSectors, RawChartSector{
 			Label: child.Name(),
-			Size:  child.Size,
+			Size:  child.SizeBy(dm.sizeMode),
 		})
 	}
 
@@ -314,7 +432,7 @@ func (dm *DirModel) viewChart() string {
 			dm.width/2,
 			dm.height/2,
 			dm.height/2,
-			dm.nav.entry.Size,
+			dm.nav.entry.SizeBy(dm.sizeMode),
 			chartSectors,
 			style.ChartColors(),
 		),
@@ -331,7 +449,7 @@ func (dm *DirModel) handleExploreKey() bool {
 }
 
 func (dm *DirModel) handleDeletion(bk bindingKey, msg tea.Msg) bool {
-	if bk == remove && dm.mode == READY {
+	if bk == remove && dm.mode == READY && !dm.nav.ReadOnly() {
 		sr := dm.dirsTable.SelectedRow()
 
 		dm.mode = DELETE
@@ -352,6 +470,57 @@ func (dm *DirModel) handleDeletion(bk bindingKey, msg tea.Msg) bool {
 	return false
 }
 
+// handleSnapshot opens the dialog for choosing the snapshot the current state
+// will be compared with, or stops the comparison if it's already active.
+func (dm *DirModel) handleSnapshot(bk bindingKey, msg tea.Msg) bool {
+	if bk == toggleDiff && dm.mode == READY {
+		if _, ok := dm.nav.Comparing(); ok {
+			dm.nav.StopCompare()
+			dm.updateTableData()
+
+			return true
+		}
+
+		dm.mode = SNAPSHOT
+		dm.snapshotDialog = NewSnapshotDialogModel(dm.nav)
+
+		return true
+	}
+
+	if dm.mode == SNAPSHOT {
+		dm.snapshotDialog.Update(msg)
+
+		return true
+	}
+
+	return false
+}
+
+// handleExport opens the dialog for exporting the current directory's subtree
+// to a file.
+func (dm *DirModel) handleExport(bk bindingKey) bool {
+	if bk == exportDir && dm.mode == READY {
+		dm.mode = EXPORT
+		dm.exportDialog = NewExportDialogModel(dm.nav)
+
+		return true
+	}
+
+	return false
+}
+
+// dialogOpen reports whether one of the dialogs is open, so the navigation keys
+// are handled by the dialog.
+func (dm *DirModel) dialogOpen() bool {
+	return dm.mode == DELETE || dm.mode == SNAPSHOT || dm.mode == EXPORT
+}
+
+// typing reports whether the keys are handled as the user's text input, so
+// they must not trigger any of the global key bindings.
+func (dm *DirModel) typing() bool {
+	return dm.mode == INPUT || dm.mode == EXPORT
+}
+
 func (dm *DirModel) updateTableData() {
 	if dm.nav.OnDrives() || dm.nav.Entry() == nil || !dm.nav.Entry().IsDir {
 		return
@@ -370,6 +539,15 @@ func (dm *DirModel) updateTableData() {
 		columns[i] = table.Column{Title: c.Title, Width: colWidth}
 	}
 
+	if dm.sizeMode == structure.DiskUsage {
+		columns[3].Title = "Size on Disk"
+	}
+
+	_, comparing := dm.nav.Comparing()
+	if comparing {
+		columns[4].Title, columns[5].Title = "Change", "Before"
+	}
+
 	columns[0].Width = iconWidth
 	columns[1].Width = 0
 	columns[2].Width = nameWidth
@@ -379,34 +557,54 @@ func (dm *DirModel) updateTableData() {
 
 	fillProgress := dm.usagePG.New(progressWidth)
 
-	rows := make([]table.Row, 0, len(dm.nav.Entry().Child))
-	dm.nav.Entry().SortChild()
+	if comparing {
+		dm.dirsTable.SetRows(dm.diffRows(nameWidth, fillProgress))
+		dm.dirsTable.SetCursor(dm.nav.cursor)
+
+		return
+	}
+
+	rows := make([]table.Row, 0, dm.nav.Entry().ChildCount())
+	dm.nav.Entry().SortChildBy(dm.sizeMode)
 
-	for _, child := range dm.nav.Entry().Child {
+	for child := range dm.nav.Entry().Entries() {
 		if !dm.filters.Valid(child) {
 			continue
 		}
 
+		if child.IsExcluded {
+			rows = append(rows, excludedRow(child, nameWidth))
+
+			continue
+		}
+
 		totalDirs, totalFiles := "", ""
 
 		if child.IsDir {
-			totalDirs = strconv.FormatUint(child.TotalDirs, 10)
-			totalFiles = strconv.FormatUint(child.TotalFiles, 10)
+			dirs, files := child.Totals()
+			totalDirs = strconv.FormatUint(dirs, 10)
+			totalFiles = strconv.FormatUint(files, 10)
 		}
 
-		parentUsage := float64(child.Size) / float64(dm.nav.ParentSize())
+		parentUsage := float64(child.SizeBy(dm.sizeMode)) /
+			float64(dm.nav.ParentSize(dm.sizeMode))
 		pgBar := fillProgress.ViewAs(parentUsage)
 
+		icon := EntryIcon(child)
+		if child.IsDir && dm.nav.Scanning(child) {
+			icon = "⏳"
+		}
+
 		rows = append(
 			rows,
 			table.Row{
-				EntryIcon(child),
+				icon,
 				child.Name(),
-				FmtName(child.Name(), nameWidth),
-				FmtSize(child.Size, entrySizeWidth),
+				FmtName(entryName(child), nameWidth),
+				FmtSize(child.SizeBy(dm.sizeMode), entrySizeWidth),
 				totalDirs,
 				totalFiles,
-				time.Unix(child.ModTime, 0).Format("2006-01-02 15:04"),
+				FmtModTime(child.ModTime),
 				FmtUsage(parentUsage),
 				pgBar,
 			},
@@ -417,27 +615,274 @@ func (dm *DirModel) updateTableData() {
 	dm.dirsTable.SetCursor(dm.nav.cursor)
 }
 
+// diffRows renders the current entry's content compared with the same directory
+// within the baseline snapshot. The entries are sorted by their growth, and the
+// removed entries are listed along with the present ones.
+func (dm *DirModel) diffRows(nameWidth int, fillProgress progress.Model) []table.Row {
+	diff, _ := dm.nav.Changes()
+	dirPath := dm.nav.Entry().Path()
+
+	rowChanges := make([]rowChange, 0, len(diff.Same)+len(diff.Added)+len(diff.Removed))
+
+	for _, c := range diff.Changes() {
+		if rc := newRowChange(c, dirPath, dm.sizeMode); dm.filters.Valid(rc.entry) {
+			rowChanges = append(rowChanges, rc)
+		}
+	}
+
+	slices.SortFunc(rowChanges, func(a, b rowChange) int {
+		return cmp.Or(
+			cmp.Compare(b.delta, a.delta),
+			cmp.Compare(a.entry.Name(), b.entry.Name()),
+		)
+	})
+
+	rows := make([]table.Row, 0, len(rowChanges))
+
+	for _, rc := range rowChanges {
+		size, before, usage, pgBar := "-", "-", "", ""
+
+		if rc.current != nil {
+			parentUsage := float64(rc.current.SizeBy(dm.sizeMode)) /
+				float64(dm.nav.ParentSize(dm.sizeMode))
+
+			size = FmtSize(rc.current.SizeBy(dm.sizeMode), entrySizeWidth)
+			usage, pgBar = FmtUsage(parentUsage), fillProgress.ViewAs(parentUsage)
+		}
+
+		if rc.previous != nil {
+			before = FmtSize(rc.previous.SizeBy(dm.sizeMode), entrySizeWidth)
+		}
+
+		rows = append(
+			rows,
+			table.Row{
+				EntryIcon(rc.entry),
+				rc.entry.Name(),
+				rc.style.Render(FmtName(rc.name, nameWidth)),
+				rc.style.Render(size),
+				rc.style.Render(FmtDelta(rc.delta, 0)),
+				rc.style.Render(before),
+				rc.style.Render(FmtModTime(rc.entry.ModTime)),
+				usage,
+				pgBar,
+			},
+		)
+	}
+
+	return rows
+}
+
+// rowChange describes a single row of the diff view. The current and previous
+// states refer to the entry located within the displayed directory, so the
+// entries moved in or out of the directory have only one of them.
+type rowChange struct {
+	entry    *structure.Entry
+	current  *structure.Entry
+	previous *structure.Entry
+	style    lipgloss.Style
+	name     string
+	delta    int64
+}
+
+func newRowChange(c structure.Change, dirPath string, sm structure.SizeMode) rowChange {
+	rc := rowChange{
+		entry:    c.Entry(),
+		current:  c.New,
+		previous: c.Old,
+		style:    lipgloss.NewStyle(),
+	}
+
+	switch c.Kind {
+	case structure.ChangeNone:
+		rc.name = entryName(c.New)
+	case structure.ChangeAdded:
+		rc.name, rc.style = entryName(c.New)+" (new)", *style.AddedRow()
+	case structure.ChangeRemoved:
+		rc.name, rc.style = entryName(c.Old)+" (removed)", *style.RemovedRow()
+	case structure.ChangeModified:
+		rc.name, rc.style = entryName(c.New)+" (modified)", *style.ModifiedRow()
+	case structure.ChangeMoved:
+		rc.style = *style.MovedRow()
+
+		switch {
+		case c.Renamed():
+			rc.name = entryName(c.New) + " (renamed from " + c.Old.Name() + ")"
+		case filepath.Dir(c.New.Path()) == dirPath:
+			rc.name = entryName(c.New) + " (moved from " + c.Old.Path() + ")"
+			rc.previous = nil
+		default:
+			rc.name = entryName(c.Old) + " (moved to " + c.New.Path() + ")"
+			rc.entry, rc.current = c.Old, nil
+		}
+	}
+
+	rc.delta = structure.Change{Old: rc.previous, New: rc.current}.Delta(sm)
+
+	return rc
+}
+
+// excludedRow renders a greyed placeholder row for the entry excluded from the
+// scanning. The excluded entries have no size or content, so only the name and
+// the modification time are shown.
+func excludedRow(e *structure.Entry, nameWidth int) table.Row {
+	return table.Row{
+		EntryIcon(e),
+		e.Name(),
+		style.ExcludedRow().Render(FmtName(e.Name()+" (excluded)", nameWidth)),
+		style.ExcludedRow().Render("-"),
+		"",
+		"",
+		style.ExcludedRow().Render(FmtModTime(e.ModTime)),
+		"",
+		"",
+	}
+}
+
+// entryName returns the entry's name to display. The symbolic links are displayed
+// along with their targets, and the collapsed directories are marked, since
+// their content will be scanned on demand.
+func entryName(e *structure.Entry) string {
+	if target := e.LinkTarget(); e.IsLink && len(target) != 0 {
+		return e.Name() + " → " + target
+	}
+
+	if e.IsCollapsed {
+		return e.Name() + " (collapsed)"
+	}
+
+	return e.Name()
+}
+
 func (dm *DirModel) dirsSummary() string {
+	sizeLabel := "SIZE"
+	if dm.sizeMode == structure.DiskUsage {
+		sizeLabel = "ON DISK"
+	}
+
+	localDirs, localFiles := dm.nav.Entry().LocalCounts()
+
 	items := []*BarItem{
 		NewBarItem(Version, style.cs.StatusBar.VersionBG, 0),
 		NewBarItem("PATH", style.cs.StatusBar.Dirs.PathBG, 0),
-		NewBarItem(dm.nav.Entry().Path, style.cs.StatusBar.BG, -1),
+		NewBarItem(dm.nav.Entry().Path(), style.cs.StatusBar.BG, -1),
 		NewBarItem(string(dm.mode), style.cs.StatusBar.Dirs.ModeBG, 0),
-		NewBarItem("SIZE", style.cs.StatusBar.Dirs.SizeBG, 0),
-		NewBarItem(FmtSize(dm.nav.Entry().Size, 0), style.cs.StatusBar.BG, 0),
+		NewBarItem(sizeLabel, style.cs.StatusBar.Dirs.SizeBG, 0),
+		NewBarItem(
+			FmtSize(dm.nav.Entry().SizeBy(dm.sizeMode), 0),
+			style.cs.StatusBar.BG,
+			0,
+		),
 		NewBarItem("DIRS", style.cs.StatusBar.Dirs.DirsBG, 0),
-		NewBarItem(unitFmt(dm.nav.Entry().LocalDirs), style.cs.StatusBar.BG, 0),
+		NewBarItem(unitFmt(localDirs), style.cs.StatusBar.BG, 0),
 		NewBarItem("FILES", style.cs.StatusBar.Dirs.FilesBG, 0),
-		NewBarItem(unitFmt(dm.nav.Entry().LocalFiles), style.cs.StatusBar.BG, 0),
+		NewBarItem(unitFmt(localFiles), style.cs.StatusBar.BG, 0),
+	}
+
+	if dm.nav.ReadOnly() {
+		items = append(
+			items,
+			NewBarItem("READ-ONLY", style.cs.StatusBar.Dirs.ModeBG, 0),
+		)
+	}
+
+	if origin, ok := dm.nav.Origin(); ok {
+		scannedAt := origin.ScannedAt.Format("2006-01-02 15:04") +
+			" (" + FmtAge(time.Since(origin.ScannedAt)) + ")"
+
+		items = append(
+			items,
+			NewBarItem("ORIGIN", style.cs.StatusBar.Dirs.PathBG, 0),
+			NewBarItem(cmp.Or(origin.Host, "unknown host")+":"+origin.Root, style.cs.StatusBar.BG, 0),
+			NewBarItem("SCANNED", style.cs.StatusBar.Dirs.DirsBG, 0),
+			NewBarItem(scannedAt, style.cs.StatusBar.BG, 0),
+		)
+	}
+
+	if dm.nav.Watching() {
+		items = append(
+			items,
+			NewBarItem("WATCHING", style.cs.StatusBar.Dirs.ModeBG, 0),
+		)
+	}
+
+	switch {
+	case dm.watchStale:
+		items = append(
+			items,
+			NewBarItem("STALE", style.cs.StatusBar.Dirs.ErrorBG, 0),
+			NewBarItem("changes were dropped, refresh the root", style.cs.StatusBar.BG, 0),
+		)
+	case dm.watchErr != nil:
+		items = append(
+			items,
+			NewBarItem("WATCH FAILED", style.cs.StatusBar.Dirs.ErrorBG, 0),
+			NewBarItem(dm.watchErr.Error(), style.cs.StatusBar.BG, 0),
+		)
+	}
+
+	if stats, ok := dm.nav.RescanStats(); ok {
+		items = append(
+			items,
+			NewBarItem("REUSED", style.cs.StatusBar.Dirs.DirsBG, 0),
+			NewBarItem(unitFmt(stats.Reused), style.cs.StatusBar.BG, 0),
+			NewBarItem("READ", style.cs.StatusBar.Dirs.DirsBG, 0),
+			NewBarItem(unitFmt(stats.Reread), style.cs.StatusBar.BG, 0),
+		)
+	}
+
+	if since, ok := dm.nav.Comparing(); ok {
+		diff, old := dm.nav.Changes()
+		delta := structure.Change{Old: old, New: dm.nav.Entry()}.Delta(dm.sizeMode)
+
+		items = append(
+			items,
+			NewBarItem("SINCE", style.cs.StatusBar.Dirs.ModeBG, 0),
+			NewBarItem(FmtAge(time.Since(since)), style.cs.StatusBar.BG, 0),
+			NewBarItem("CHANGE", style.cs.StatusBar.Dirs.SizeBG, 0),
+			NewBarItem(cmp.Or(FmtDelta(delta, 0), "0"), style.cs.StatusBar.BG, 0),
+			NewBarItem("ADDED", style.cs.StatusBar.Dirs.DirsBG, 0),
+			NewBarItem(unitFmt(uint64(len(diff.Added))), style.cs.StatusBar.BG, 0),
+			NewBarItem("REMOVED", style.cs.StatusBar.Dirs.FilesBG, 0),
+			NewBarItem(unitFmt(uint64(len(diff.Removed))), style.cs.StatusBar.BG, 0),
+			NewBarItem("MOVED", style.cs.StatusBar.Dirs.DirsBG, 0),
+			NewBarItem(unitFmt(uint64(len(diff.Moved))), style.cs.StatusBar.BG, 0),
+			NewBarItem("MODIFIED", style.cs.StatusBar.Dirs.FilesBG, 0),
+			NewBarItem(unitFmt(uint64(len(diff.Modified))), style.cs.StatusBar.BG, 0),
+		)
+	}
+
+	if len(dm.lastExport) != 0 {
+		items = append(
+			items,
+			NewBarItem("EXPORTED", style.cs.StatusBar.Dirs.PathBG, 0),
+			NewBarItem(dm.lastExport, style.cs.StatusBar.BG, 0),
+		)
+	}
+
+	items = append(
+		items,
 		NewBarItem("ERRORS", style.cs.StatusBar.Dirs.ErrorBG, 0),
 		NewBarItem(unitFmt(uint64(len(dm.lastErr))), style.cs.StatusBar.BG, 0),
-	}
+	)
 
 	return style.StatusBar().Margin(1, 0, 1, 0).Render(
 		NewStatusBar(items, dm.width),
 	)
 }
 
+// watchFailed preserves the error occurred while watching, so it's shown in the
+// status bar. If the change notifications were dropped, the tree is marked as
+// stale until the entire tree is refreshed.
+func (dm *DirModel) watchFailed(err error) {
+	dm.lastErr = append(dm.lastErr, err)
+	dm.watchErr = err
+
+	if errors.Is(err, drive.ErrWatchOverflow) {
+		dm.watchStale = true
+	}
+}
+
 func (dm *DirModel) fillTopEntries(entries heap.Interface, tm *table.Model) {
 	iconWidth := 5
 	colSize := int(float64(dm.width-iconWidth) * colWidthRatio)
@@ -467,23 +912,23 @@ func (dm *DirModel) fillTopEntries(entries heap.Interface, tm *table.Model) {
 			continue
 		}
 
-		rootPath := dm.nav.Entry().Path + string(os.PathSeparator)
+		rootPath := dm.nav.Entry().Path() + string(os.PathSeparator)
 
 		if dm.nav.currentDrive != nil {
 			rootPath = dm.nav.currentDrive.Path
 		}
 
 		path := strings.TrimSuffix(
-			strings.TrimPrefix(file.Path, rootPath),
+			strings.TrimPrefix(file.Path(), rootPath),
 			file.Name(),
 		)
 
 		rows[i] = table.Row{
 			EntryIcon(file),
-			file.Path,
+			file.Path(),
 			path + style.TopFiles().Render(file.Name()),
-			FmtSize(file.Size, entrySizeWidth),
-			time.Unix(file.ModTime, 0).Format("2006-01-02 15:04"),
+			FmtSize(file.SizeBy(dm.sizeMode), entrySizeWidth),
+			FmtModTime(file.ModTime),
 		}
 	}
 
@@ -491,21 +936,122 @@ func (dm *DirModel) fillTopEntries(entries heap.Interface, tm *table.Model) {
 	tm.SetCursor(0)
 }
 
+// toggleSizeMode switches between the apparent and the on-disk sizes. All size
+// dependent elements, including the top entries, are re-rendered accordingly.
+func (dm *DirModel) toggleSizeMode() {
+	if dm.sizeMode == structure.DiskUsage {
+		dm.sizeMode = structure.ApparentSize
+	} else {
+		dm.sizeMode = structure.DiskUsage
+	}
+
+	dm.updateTableData()
+	dm.updateTopEntries()
+}
+
+// updateTopEntries rescans the current entry for the biggest files and
+// directories using the current size mode and refills the top tables.
+func (dm *DirModel) updateTopEntries() {
+	dm.topFilesTable.SetRows(nil)
+	dm.topDirsTable.SetRows(nil)
+
+	structure.TopEntriesInstance.SetSizeMode(dm.sizeMode)
+	structure.TopEntriesInstance.ScanFiles(dm.nav.Entry())
+	structure.TopEntriesInstance.ScanDirs(dm.nav.Entry())
+
+	dm.fillTopEntries(structure.TopEntriesInstance.Files(), dm.topFilesTable)
+	dm.fillTopEntries(structure.TopEntriesInstance.Dirs(), dm.topDirsTable)
+	dm.fillHardLinks()
+}
+
+// fillHardLinks fills the table with files that have multiple hard links. The
+// table header contains the total space shared by all listed files.
+func (dm *DirModel) fillHardLinks() {
+	iconWidth := 5
+	colSize := int(float64(dm.width-iconWidth) * colWidthRatio)
+	nameWidth := dm.width - (colSize * 2) - iconWidth
+
+	hardLinks := dm.nav.tree.HardLinks()
+	rows := make([]table.Row, 0, len(hardLinks))
+
+	var shared int64
+
+	for _, hl := range hardLinks {
+		shared += hl.SizeBy(dm.sizeMode)
+
+		rows = append(rows, table.Row{
+			"⛓",
+			hl.Paths[0],
+			FmtName(strings.Join(hl.Paths, ", "), nameWidth),
+			fmt.Sprintf("%d/%d", len(hl.Paths), hl.Links),
+			FmtSize(hl.SizeBy(dm.sizeMode), entrySizeWidth),
+		})
+	}
+
+	dm.linksTable.SetColumns([]table.Column{
+		{Title: "", Width: iconWidth},
+		{Title: "", Width: 0},
+		{Title: "Hard links (shared " + FmtSize(shared, 0) + ")", Width: nameWidth},
+		{Title: "Found/Total", Width: colSize},
+		{Title: "Size", Width: colSize},
+	})
+
+	dm.linksTable.SetRows(rows)
+	dm.linksTable.SetCursor(0)
+}
+
+// fillGrowth fills the table with the watched directories that have grown the
+// most within the last minute, along with their growth rate.
+func (dm *DirModel) fillGrowth() {
+	iconWidth := 5
+	colSize := int(float64(dm.width-iconWidth) * colWidthRatio)
+	nameWidth := dm.width - colSize - iconWidth
+
+	growth := dm.nav.TopGrowth(topFilesTableHeight - 1)
+	rows := make([]table.Row, 0, len(growth))
+
+	for _, dg := range growth {
+		rows = append(rows, table.Row{
+			"📈",
+			dg.Path,
+			FmtName(dg.Path, nameWidth),
+			FmtSize(dg.BytesPerMinute, 0) + "/min",
+		})
+	}
+
+	dm.growthTable.SetColumns([]table.Column{
+		{Title: "", Width: iconWidth},
+		{Title: "", Width: 0},
+		{Title: "Fastest growing", Width: nameWidth},
+		{Title: "Rate", Width: colSize},
+	})
+
+	dm.growthTable.SetRows(rows)
+	dm.growthTable.SetCursor(0)
+}
+
 func (dm *DirModel) updateSize(width, height int) {
 	dm.width, dm.height = width, height
 
 	dm.dirsTable.SetWidth(width)
 	dm.topFilesTable.SetWidth(width)
 	dm.topDirsTable.SetWidth(width)
+	dm.linksTable.SetWidth(width)
+	dm.growthTable.SetWidth(width)
 
 	dm.updateTableData()
 
 	dm.fillTopEntries(structure.TopEntriesInstance.Files(), dm.topFilesTable)
 	dm.fillTopEntries(structure.TopEntriesInstance.Dirs(), dm.topDirsTable)
+
+	if !dm.nav.OnDrives() {
+		dm.fillHardLinks()
+		dm.fillGrowth()
+	}
 }
 
 func (dm *DirModel) viewProgress() string {
-	completed := (float64(dm.nav.Entry().Size) / float64(dm.nav.currentDrive.UsedBytes)) - 0.01
+	completed := (float64(dm.nav.tree.Root().SizeBy(structure.ApparentSize)) / float64(dm.nav.currentDrive.UsedBytes)) - 0.01
 
 	return style.StatusBar().Margin(1, 0, 1, 0).Render(
 		dm.scanPG.New(dm.width).ViewAs(completed),
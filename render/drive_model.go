@@ -2,6 +2,7 @@ package render
 
 import (
 	"strings"
+	"time"
 
 	"github.com/crumbyte/noxdir/drive"
 	"github.com/crumbyte/noxdir/render/table"
@@ -33,6 +34,7 @@ func NewDriveModel(n *Navigation) *DriveModel {
 		{Title: "Used Space", SortKey: drive.TotalUsed},
 		{Title: "Free Space", SortKey: drive.TotalFree},
 		{Title: "Usage", SortKey: drive.TotalUsedP},
+		{Title: "Cache"},
 		{},
 	}
 
@@ -121,7 +123,7 @@ func (dm *DriveModel) updateTableData(key drive.SortKey, sortDesc bool) {
 	tableWidth := dm.width
 
 	colWidth := int(float64(tableWidth) * 0.07)
-	progressWidth := tableWidth - (colWidth * 6) - iconWidth - pathWidth
+	progressWidth := tableWidth - (colWidth * 7) - iconWidth - pathWidth
 
 	columns := make([]table.Column, len(dm.driveColumns))
 
@@ -155,6 +157,7 @@ func (dm *DriveModel) updateTableData(key drive.SortKey, sortDesc bool) {
 			FmtSize(d.UsedBytes, driveSizeWidth),
 			FmtSize(d.FreeBytes, driveSizeWidth),
 			FmtUsage(d.UsedPercent / 100),
+			dm.cacheAge(d.Path),
 			lipgloss.JoinHorizontal(
 				lipgloss.Top,
 				strings.Repeat(" ", progressWidth-lipgloss.Width(pgBar)),
@@ -167,6 +170,17 @@ func (dm *DriveModel) updateTableData(key drive.SortKey, sortDesc bool) {
 	dm.drivesTable.SetCursor(0)
 }
 
+// cacheAge returns the age of the drive's cache, or "-" if there is no valid
+// cache for the drive.
+func (dm *DriveModel) cacheAge(path string) string {
+	cachedAt, ok := dm.nav.CachedAt(path)
+	if !ok {
+		return "-"
+	}
+
+	return FmtAge(time.Since(cachedAt))
+}
+
 func (dm *DriveModel) drivesSummary() string {
 	dl := dm.nav.DrivesList()
 
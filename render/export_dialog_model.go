@@ -0,0 +1,227 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/crumbyte/noxdir/export"
+	"github.com/crumbyte/noxdir/structure"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const exportDialogWidth = 60
+
+// ExportFinished is sent once the export dialog is closed. The Path value is
+// empty if the export was cancelled.
+type ExportFinished struct {
+	Err  error
+	Path string
+}
+
+// exportFormat describes the format the current directory can be exported in
+// from the export dialog.
+type exportFormat struct {
+	format export.Format
+	title  string
+	ext    string
+}
+
+var exportFormats = []exportFormat{
+	{format: export.FormatNCDU, title: "ncdu JSON dump", ext: ".json"},
+	{format: export.FormatCSV, title: "CSV", ext: ".csv"},
+	{format: export.FormatTSV, title: "TSV", ext: ".tsv"},
+}
+
+// ExportDialogModel allows exporting the current directory's subtree to a file
+// in one of the supported formats. The file's path is prefilled with a name in
+// the current working directory and can be edited. An existing file is not
+// overwritten unless it's confirmed.
+type ExportDialogModel struct {
+	nav   *Navigation
+	entry *structure.Entry
+
+	// overwrite contains the path of the existing file the user agreed to
+	// overwrite.
+	overwrite string
+	input     textinput.Model
+	choice    int
+	writing   bool
+}
+
+func NewExportDialogModel(nav *Navigation) *ExportDialogModel {
+	ti := textinput.New()
+
+	ti.Prompt = ""
+	ti.Width = exportDialogWidth - 1
+	ti.SetValue(defaultExportPath(nav.Entry(), exportFormats[0]))
+	ti.Focus()
+
+	return &ExportDialogModel{nav: nav, entry: nav.Entry(), input: ti}
+}
+
+func (edm *ExportDialogModel) Init() tea.Cmd {
+	return nil
+}
+
+func (edm *ExportDialogModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok || edm.writing {
+		return edm, nil
+	}
+
+	switch strings.ToLower(keyMsg.String()) {
+	case enter.String():
+		return edm, edm.export()
+	case escape.String():
+		return edm, func() tea.Msg { return ExportFinished{} }
+	case "tab":
+		prev := exportFormats[edm.choice]
+		edm.choice = (edm.choice + 1) % len(exportFormats)
+
+		// the extension is replaced only if it was not changed manually
+		if path, ok := strings.CutSuffix(edm.input.Value(), prev.ext); ok {
+			edm.input.SetValue(path + exportFormats[edm.choice].ext)
+		}
+	default:
+		edm.input, _ = edm.input.Update(msg)
+	}
+
+	return edm, nil
+}
+
+func (edm *ExportDialogModel) View() string {
+	textStyle := lipgloss.NewStyle().Width(exportDialogWidth)
+
+	title := textStyle.
+		Align(lipgloss.Center).
+		Bold(true).
+		Render("Export\n")
+
+	formats := make([]string, 0, len(exportFormats))
+
+	for i, f := range exportFormats {
+		btn := style.ConfirmButton()
+		if i == edm.choice {
+			btn = style.ActiveButton()
+		}
+
+		formats = append(formats, btn.Render(f.title))
+	}
+
+	help := "tab - change format • enter - export • esc - cancel"
+
+	switch {
+	case edm.writing:
+		help = "exporting..."
+	case edm.overwriteConfirmed():
+		help = "the file exists • enter - overwrite • esc - cancel"
+	}
+
+	return style.DialogBox().Render(
+		lipgloss.JoinVertical(
+			lipgloss.Left,
+			title,
+			textStyle.Render(FmtName(edm.entry.Path(), exportDialogWidth)),
+			"",
+			lipgloss.JoinHorizontal(lipgloss.Top, formats...),
+			"",
+			style.SelectedRow().Width(exportDialogWidth).Render(edm.input.View()),
+			"",
+			style.Help().Render(help),
+		),
+	)
+}
+
+// export returns the command writing the export file in the background. The
+// navigation stays locked until the file is written, so the tree is neither
+// refreshed nor changed by the watched events meanwhile. If the file exists,
+// nil is returned, and the export must be confirmed once again.
+func (edm *ExportDialogModel) export() tea.Cmd {
+	path := strings.TrimSpace(edm.input.Value())
+	if len(path) == 0 {
+		return nil
+	}
+
+	if !edm.overwriteConfirmed() {
+		if _, err := os.Stat(path); err == nil {
+			edm.overwrite = edm.input.Value()
+
+			return nil
+		}
+	}
+
+	if !edm.nav.lock() {
+		return func() tea.Msg {
+			return ExportFinished{Err: errors.New("export: the tree is being scanned")}
+		}
+	}
+
+	edm.writing = true
+	overwrite := edm.overwriteConfirmed()
+
+	return func() tea.Msg {
+		defer edm.nav.unlock()
+
+		return ExportFinished{Path: path, Err: edm.write(path, overwrite)}
+	}
+}
+
+// overwriteConfirmed reports whether the user agreed to overwrite the file at
+// the current path.
+func (edm *ExportDialogModel) overwriteConfirmed() bool {
+	return len(edm.overwrite) != 0 && edm.overwrite == edm.input.Value()
+}
+
+// write writes the export file. The file is created exclusively unless the
+// overwrite is allowed, so a file created after the confirmation was asked is
+// not truncated.
+func (edm *ExportDialogModel) write(path string, overwrite bool) error {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if overwrite {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+
+	f, err := os.OpenFile(path, flags, 0o666)
+	if err != nil {
+		return fmt.Errorf("create export file: %w", err)
+	}
+
+	err = export.Write(
+		f,
+		edm.entry,
+		exportFormats[edm.choice].format,
+		export.WithAppVersion(Version),
+		export.WithHardLinks(edm.nav.tree.HardLinks()),
+	)
+	if err != nil {
+		_ = f.Close()
+
+		return fmt.Errorf("export %s: %w", edm.entry.Path(), err)
+	}
+
+	return f.Close()
+}
+
+// defaultExportPath returns the export file's path in the current working
+// directory named after the exported entry.
+func defaultExportPath(entry *structure.Entry, f exportFormat) string {
+	name := filepath.Base(entry.Path())
+	if strings.ContainsAny(name, `/\:`) || name == "." {
+		name = "root"
+	}
+
+	name = "noxdir-" + name + f.ext
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return name
+	}
+
+	return filepath.Join(wd, name)
+}
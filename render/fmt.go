@@ -5,6 +5,7 @@ import (
 	"math"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/lipgloss"
 )
@@ -68,3 +69,42 @@ func FmtUsage(usage float64) string {
 		usageFmt,
 	)
 }
+
+// FmtAge formats the provided age using the largest whole unit, e.g., "5m ago",
+// "3h ago", or "12d ago". An age of less than a minute is shown as "just now".
+func FmtAge(age time.Duration) string {
+	switch {
+	case age < time.Minute:
+		return "just now"
+	case age < time.Hour:
+		return strconv.Itoa(int(age/time.Minute)) + "m ago"
+	case age < 24*time.Hour:
+		return strconv.Itoa(int(age/time.Hour)) + "h ago"
+	}
+
+	return strconv.Itoa(int(age/(24*time.Hour))) + "d ago"
+}
+
+// FmtDelta formats the size difference with an explicit sign, e.g., "+1.50 GB"
+// or "-200.00 MB". A zero difference is shown as an empty string.
+func FmtDelta(delta int64, width int) string {
+	switch {
+	case delta > 0:
+		return "+" + FmtSize(delta, max(0, width-1))
+	case delta < 0:
+		return "-" + FmtSize(-delta, max(0, width-1))
+	}
+
+	return ""
+}
+
+// FmtModTime formats the modification time provided as the Unix time in
+// seconds. A zero value means the time is unknown, e.g., for the entries read
+// from a du listing, and is shown as "-".
+func FmtModTime(modTime int64) string {
+	if modTime == 0 {
+		return "-"
+	}
+
+	return time.Unix(modTime, 0).Format("2006-01-02 15:04")
+}
@@ -2,6 +2,7 @@ package render_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/crumbyte/noxdir/render"
 
@@ -34,3 +35,28 @@ func TestFmtSize(t *testing.T) {
 		require.Equal(t, data.expected, render.FmtSize(data.bytes, data.width))
 	}
 }
+
+func TestFmtAge(t *testing.T) {
+	tableData := []struct {
+		expected string
+		age      time.Duration
+	}{
+		{"just now", 0},
+		{"just now", 59 * time.Second},
+		{"1m ago", time.Minute},
+		{"59m ago", time.Hour - time.Second},
+		{"3h ago", 3*time.Hour + 59*time.Minute},
+		{"2d ago", 50 * time.Hour},
+	}
+
+	for _, data := range tableData {
+		require.Equal(t, data.expected, render.FmtAge(data.age))
+	}
+}
+
+func TestFmtModTime(t *testing.T) {
+	modTime := time.Date(2024, 5, 1, 10, 30, 0, 0, time.Local)
+
+	require.Equal(t, "2024-05-01 10:30", render.FmtModTime(modTime.Unix()))
+	require.Equal(t, "-", render.FmtModTime(0))
+}
@@ -9,6 +9,22 @@ import "github.com/crumbyte/noxdir/structure"
 func EntryIcon(e *structure.Entry) string {
 	icon := "📁"
 
+	if e.IsExcluded {
+		return "🚫"
+	}
+
+	if e.IsCollapsed {
+		return "🗃"
+	}
+
+	if e.IsIgnored {
+		return "🙈"
+	}
+
+	if e.IsLink {
+		return "🔗"
+	}
+
 	if e.IsDir {
 		if e.HasChild() {
 			icon = "📂"
@@ -1,12 +1,17 @@
 package render
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
 	"sync/atomic"
+	"time"
 
 	"github.com/crumbyte/noxdir/drive"
+	"github.com/crumbyte/noxdir/pkg/cache"
 	"github.com/crumbyte/noxdir/structure"
 )
 
@@ -23,6 +28,15 @@ const (
 	Dirs
 )
 
+// ErrSyntheticEntry is returned when an action requires an existing file or
+// directory, but the entry was created by the application, e.g., the group of
+// the ignored entries.
+var ErrSyntheticEntry = errors.New("entry does not exist on the file system")
+
+// ErrReadOnly is returned when an action requires the file system, but the
+// navigation browses a tree that was not scanned locally, e.g., a dump.
+var ErrReadOnly = errors.New("tree is read-only")
+
 // OnChangeLevel defines a custom type for a function being called on changing
 // the current tree level. It accepts the current active entry instance and the
 // navigation's state: Drives or Dirs.
@@ -56,6 +70,21 @@ func (e *entryStack) pop() *stackItem {
 	return item
 }
 
+// changesCache contains the result of Navigation.Changes for the entry.
+type changesCache struct {
+	entry *structure.Entry
+	old   *structure.Entry
+	diff  structure.Diff
+}
+
+// Origin describes where and when the read-only tree was scanned, e.g., the
+// cache snapshot copied from another machine.
+type Origin struct {
+	ScannedAt time.Time
+	Host      string
+	Root      string
+}
+
 // Navigation defines the behavior for traversing the file system tree structure
 // and handles the changes of state. It contains the current active drive/volume,
 // traversing history, and handles the race condition cases.
@@ -66,8 +95,22 @@ type Navigation struct {
 	currentDrive *drive.Info
 	entryStack   *entryStack
 	state        State
+	cancelScan   context.CancelFunc
+	rescanTree   *structure.Tree
+	watcher      *drive.Watcher
+	growth       *structure.GrowthTracker
+	watchQueue   []drive.WatchEvent
+	baseline     *structure.Tree
+	baselineIno  structure.InoIndex
+	currentIno   structure.InoIndex
+	baselineAt   time.Time
+	changes      *changesCache
+	origin       *Origin
 	cursor       int
 	locked       atomic.Bool
+	scanning     atomic.Bool
+	watchEnabled bool
+	readOnly     bool
 }
 
 func NewNavigation(t *structure.Tree) *Navigation {
@@ -84,13 +127,14 @@ func NewNavigation(t *structure.Tree) *Navigation {
 
 // NewRootNavigation creates navigation for a predefined root directory entry.
 // It starts the blocking traversal immediately rather than in interactive mode.
-// Therefore, a root with a wide subdirectory structure might cause a delay.
+// Therefore, a root with a wide subdirectory structure might cause a delay. If
+// the tree uses the cache, the root is restored from it when possible.
 func NewRootNavigation(t *structure.Tree) (*Navigation, error) {
 	if t.Root() == nil {
 		return nil, errors.New("root is nil")
 	}
 
-	done, errChan := t.TraverseAsync(true)
+	done, errChan := t.TraverseAsync(context.Background(), false)
 	if done == nil {
 		return nil, errors.New("root is nil")
 	}
@@ -106,7 +150,6 @@ wait:
 	}
 
 	<-done
-	t.CalculateSize()
 
 	n := NewNavigation(t)
 
@@ -116,6 +159,43 @@ wait:
 	return n, nil
 }
 
+// NewStaticNavigation creates read-only navigation for a tree that was built
+// without scanning the local file system, e.g., read from a dump. Such a tree
+// cannot be refreshed, and its entries cannot be explored or deleted. The
+// navigation never leaves the root, since the drives list is not relevant.
+func NewStaticNavigation(root *structure.Entry) *Navigation {
+	return &Navigation{
+		tree:       structure.NewTree(root),
+		entry:      root,
+		drives:     &drive.List{},
+		state:      Dirs,
+		entryStack: &entryStack{},
+		readOnly:   true,
+	}
+}
+
+// SetOrigin sets the origin of the read-only tree, so it can be shown along
+// with the tree. See NewStaticNavigation.
+func (n *Navigation) SetOrigin(o Origin) {
+	n.origin = &o
+}
+
+// Origin returns the origin of the read-only tree, and reports whether it was
+// set by SetOrigin.
+func (n *Navigation) Origin() (Origin, bool) {
+	if n.origin == nil {
+		return Origin{}, false
+	}
+
+	return *n.origin, true
+}
+
+// ReadOnly reports whether the navigation browses a tree that was not scanned
+// locally. See NewStaticNavigation.
+func (n *Navigation) ReadOnly() bool {
+	return n.readOnly
+}
+
 // OnDrives checks whether the current navigation state is Drives or not.
 func (n *Navigation) OnDrives() bool {
 	return n.state == Drives
@@ -128,13 +208,22 @@ func (n *Navigation) DrivesList() *drive.List {
 	return n.drives
 }
 
+// CachedAt returns the time the tree of the drive with the provided path was
+// cached at. It reports "false" if there is no valid cache for the drive.
+func (n *Navigation) CachedAt(path string) (time.Time, bool) {
+	return n.tree.CachedAt(path)
+}
+
 // Entry returns the current active *structure.Entry instance. It never returns
 // an instance of a file, but only a directory.
 func (n *Navigation) Entry() *structure.Entry {
 	return n.entry
 }
 
-func (n *Navigation) ParentSize() int64 {
+// ParentSize returns the size of the current active entry according to the
+// provided size mode. If there is no active entry, the drive's used space will
+// be returned instead.
+func (n *Navigation) ParentSize(sm structure.SizeMode) int64 {
 	minSize := int64(1)
 
 	if n.entry == nil {
@@ -142,7 +231,7 @@ func (n *Navigation) ParentSize() int64 {
 		return max(minSize, int64(n.currentDrive.UsedBytes))
 	}
 
-	return max(minSize, n.entry.Size)
+	return max(minSize, n.entry.SizeBy(sm))
 }
 
 // Up changes the current tree level up to the previous one. It doesn't accept
@@ -159,12 +248,22 @@ func (n *Navigation) Up(ocl OnChangeLevel) {
 
 	defer n.unlock()
 
+	if n.entryStack.len() == 0 && n.readOnly {
+		return
+	}
+
 	if n.entryStack.len() == 0 {
-		if err := n.tree.PersistCache(); err != nil {
+		// the progressive scan is still running, so the partial tree must not
+		// be cached.
+		if n.scanning.Load() {
+			n.CancelScan()
+		} else if err := n.tree.PersistCache(); err != nil {
 			// ignore caching error
 			_ = err
 		}
 
+		n.StopWatch()
+		n.StopCompare()
 		n.state, n.cursor = Drives, 0
 
 		return
@@ -176,6 +275,7 @@ func (n *Navigation) Up(ocl OnChangeLevel) {
 
 	lastItem := n.entryStack.pop()
 	n.entry, n.cursor = lastItem.entry, lastItem.cursor
+	n.tree.SetFocus(n.entry)
 }
 
 // SetCursor preserves the current position of the table's cursor. The cursor
@@ -193,7 +293,14 @@ func (n *Navigation) SetCursor(cursor int) {
 // "errors" that occurred during the drive scan. The client is responsible for
 // listening to the channels and handling the state of scanning. The navigation
 // will be locked until the "done" channel is closed. In the second case, both
-// channels will be returned as nil values, since the scanning is already done.
+// channels will be returned as nil values, since the scanning is already done,
+// unless the target directory is collapsed. The collapsed directory's content is
+// scanned on demand the same way as the drive is.
+//
+// If the tree uses the focus-first mode, the navigation is not locked during the
+// drive scan. Instead, the directory the navigation currently points to is
+// scanned first. The collapsed directories cannot be opened until the drive scan
+// is complete.
 func (n *Navigation) Down(path string, cursor int, ocl OnChangeLevel) (chan struct{}, chan error) {
 	if len(path) == 0 || !n.lock() {
 		return nil, nil
@@ -201,13 +308,35 @@ func (n *Navigation) Down(path string, cursor int, ocl OnChangeLevel) (chan stru
 
 	// handle scenario when the drive was selected
 	if n.OnDrives() {
+		// the previous progressive scan is still being stopped
+		if n.scanning.Load() {
+			n.unlock()
+
+			return nil, nil
+		}
+
+		n.StopWatch()
+		n.StopCompare()
 		n.state = Dirs
 
-		n.entry = structure.NewDirEntry(path, 0)
+		n.entry, n.rescanTree = structure.NewDirEntry(path, 0), nil
 		n.currentDrive = n.drives.DriveInfo(path)
 		n.tree.SetRoot(n.entry)
+		n.tree.SetFocus(n.entry)
 
-		doneChan, errChan := n.tree.TraverseAsync(false)
+		doneChan, errChan := n.tree.TraverseAsync(n.scanContext(), false)
+
+		if n.tree.FocusFirst() {
+			n.scanning.Store(true)
+			n.unlock()
+
+			go func() {
+				<-doneChan
+				n.scanning.Store(false)
+			}()
+
+			return doneChan, errChan
+		}
 
 		go func() {
 			<-doneChan
@@ -217,19 +346,42 @@ func (n *Navigation) Down(path string, cursor int, ocl OnChangeLevel) (chan stru
 		return doneChan, errChan
 	}
 
-	// in other cases, it's just a lookup for a child directory
-	defer func() {
+	entry := n.entry.GetChild(path)
+	if entry == nil || !entry.IsDir || entry.IsExcluded ||
+		(entry.IsCollapsed && (n.scanning.Load() || n.readOnly)) {
 		ocl(n.entry, n.state)
 		n.unlock()
-	}()
 
-	entry := n.entry.GetChild(path)
-	if entry == nil || !entry.IsDir {
 		return nil, nil
 	}
 
 	n.entryStack.push(&stackItem{entry: n.entry, cursor: cursor})
 	n.entry, n.cursor = entry, 0
+	n.tree.SetFocus(n.entry)
+
+	// the collapsed directory's content was not kept, so it must be scanned
+	// on demand.
+	if entry.IsCollapsed {
+		entry.ClearChild()
+		entry.IsCollapsed = false
+
+		doneChan, errChan := n.tree.Subtree(entry).TraverseAsync(
+			n.scanContext(), true,
+		)
+
+		ocl(n.entry, n.state)
+
+		go func() {
+			<-doneChan
+			n.unlock()
+		}()
+
+		return doneChan, errChan
+	}
+
+	// in other cases, it's just a lookup for a child directory
+	ocl(n.entry, n.state)
+	n.unlock()
 
 	return nil, nil
 }
@@ -254,12 +406,34 @@ func (n *Navigation) RefreshDrives() {
 // The navigation will be locked until the scanning is complete and the "done"
 // channel is closed.
 func (n *Navigation) RefreshEntry() (chan struct{}, chan error, error) {
-	if n.OnDrives() || !n.lock() || n.entry == nil {
+	return n.refreshEntry(false)
+}
+
+// RefreshChangedEntry works the same way as RefreshEntry does, but only the
+// directories whose modification time has changed since the last scan are read
+// again. The content of other directories is reused. The number of reused and
+// read directories is available via RescanStats once the scanning is complete.
+func (n *Navigation) RefreshChangedEntry() (chan struct{}, chan error, error) {
+	return n.refreshEntry(true)
+}
+
+// RescanStats returns the statistics of the last refresh, and reports whether
+// the refresh was done by RefreshChangedEntry.
+func (n *Navigation) RescanStats() (structure.RescanStats, bool) {
+	if n.rescanTree == nil {
+		return structure.RescanStats{}, false
+	}
+
+	return n.rescanTree.RescanStats(), true
+}
+
+func (n *Navigation) refreshEntry(changedOnly bool) (chan struct{}, chan error, error) {
+	if n.OnDrives() || n.readOnly || n.scanning.Load() || !n.lock() || n.entry == nil {
 		return nil, nil, nil
 	}
 
 	for n.entryStack.len() > 0 || n.entry != nil {
-		_, err := os.Lstat(n.entry.Path)
+		_, err := os.Lstat(n.entry.Path())
 		if err == nil {
 			break
 		}
@@ -288,24 +462,56 @@ func (n *Navigation) RefreshEntry() (chan struct{}, chan error, error) {
 		return nil, nil, nil
 	}
 
-	n.entry.Child = nil
+	var (
+		entry    = n.entry
+		subtree  = n.tree.Subtree(entry)
+		doneChan chan struct{}
+		errChan  chan error
+	)
 
-	t := structure.NewTree(n.entry, structure.WithPartialRoot())
-	doneChan, errChan := t.TraverseAsync(true)
+	n.rescanTree, n.changes = nil, nil
+
+	if changedOnly {
+		n.rescanTree = subtree
+		doneChan, errChan = subtree.TraverseIncremental(n.scanContext())
+	} else {
+		n.entry.ClearChild()
+		doneChan, errChan = subtree.TraverseAsync(n.scanContext(), true)
+	}
 
 	go func() {
 		<-doneChan
+
+		// the refreshed content is merged into the cached trees containing
+		// it, e.g., the drive's tree when the root is a directory.
+		if !subtree.Interrupted() {
+			n.tree.TrackRefresh(entry)
+		}
+
 		n.unlock()
 	}()
 
 	return doneChan, errChan, nil
 }
 
+// CancelScan cancels the running drive or directory scan. The entries that were
+// already scanned are kept, and the navigation will be unlocked as soon as the
+// scan workers stop. It does nothing if there is no running scan.
+func (n *Navigation) CancelScan() {
+	if n.cancelScan != nil {
+		n.cancelScan()
+	}
+}
+
 func (n *Navigation) Explore(path string) error {
 	if len(path) == 0 {
 		return nil
 	}
 
+	if n.readOnly {
+		return ErrReadOnly
+	}
+
 	var fullPath string
 
 	if n.OnDrives() {
@@ -321,7 +527,7 @@ func (n *Navigation) Explore(path string) error {
 			return nil
 		}
 
-		fullPath = entry.Path
+		fullPath = entry.Path()
 	}
 
 	return drive.Explore(fullPath)
@@ -341,13 +547,290 @@ func (n *Navigation) Delete(path string) error {
 		return nil
 	}
 
-	if err := os.RemoveAll(entry.Path); err != nil {
+	if n.readOnly {
+		return fmt.Errorf("delete: path: %s: %w", path, ErrReadOnly)
+	}
+
+	if entry.IsIgnored {
+		return fmt.Errorf("delete: path: %s: %w", path, ErrSyntheticEntry)
+	}
+
+	if err := os.RemoveAll(entry.Path()); err != nil {
 		return fmt.Errorf("delete: path: %s: %w", path, err)
 	}
 
 	return nil
 }
 
+// EnableWatch enables watching the scanned tree for the file system changes. The
+// watching itself is started by StartWatch once the scanning is complete.
+func (n *Navigation) EnableWatch() {
+	n.watchEnabled = true
+	n.growth = structure.NewGrowthTracker(time.Minute)
+}
+
+// Scanning reports whether the directory entry's subtree is still being scanned
+// by the progressive drive scan.
+func (n *Navigation) Scanning(e *structure.Entry) bool {
+	return n.scanning.Load() && n.tree.Scanning(e)
+}
+
+// Watching reports whether the scanned tree is being watched for the changes.
+func (n *Navigation) Watching() bool {
+	return n.watcher != nil
+}
+
+// StartWatch starts watching the scanned tree for the file system changes if the
+// watching was enabled by EnableWatch. The batches of changes are passed to the
+// onEvents function, and the errors that occurred while watching are passed to
+// the onErr function and do not stop the watching. Both functions are called
+// from the watching goroutine, so the changes must be passed to the goroutine
+// that reads the tree and applied there by ApplyWatchEvents.
+//
+// It does nothing if the tree is already being watched.
+func (n *Navigation) StartWatch(onEvents func([]drive.WatchEvent), onErr func(error)) error {
+	if !n.watchEnabled || n.watcher != nil || n.OnDrives() || n.tree.Root() == nil {
+		return nil
+	}
+
+	w, err := drive.NewWatcher(n.tree.Root().Path())
+	if err != nil {
+		return fmt.Errorf("watch: %w", err)
+	}
+
+	n.watcher = w
+
+	go n.watch(w, onEvents, onErr)
+
+	return nil
+}
+
+// StopWatch stops watching the scanned tree. It does nothing if the tree is not
+// being watched.
+func (n *Navigation) StopWatch() {
+	if n.watcher == nil {
+		return
+	}
+
+	_ = n.watcher.Close()
+	n.watcher, n.watchQueue = nil, nil
+}
+
+// ApplyWatchEvents applies the batch of the watched changes to the tree. The
+// tree must not be changed while it's being scanned, so if the navigation is
+// locked, the changes are queued and applied along with the next batch, or by
+// calling ApplyWatchEvents with an empty batch once the scanning is complete.
+// It reports whether any changes were applied.
+//
+// The content of the new directories is scanned in the background. In that
+// case, the navigation will be locked until the scanning is complete and the
+// returned channel is closed, so the changes watched meanwhile are queued.
+//
+// The function must be called from the same goroutine that reads the tree,
+// e.g., renders it.
+func (n *Navigation) ApplyWatchEvents(batch []drive.WatchEvent) (bool, chan struct{}) {
+	if n.watcher == nil {
+		return false, nil
+	}
+
+	n.watchQueue = append(n.watchQueue, batch...)
+
+	if len(n.watchQueue) == 0 || n.scanning.Load() || !n.lock() {
+		return false, nil
+	}
+
+	now := time.Now()
+
+	for _, ev := range n.watchQueue {
+		if dir, delta := n.tree.ApplyEvent(ev); len(dir) != 0 {
+			n.growth.Record(dir, delta, now)
+		}
+	}
+
+	n.watchQueue, n.changes = nil, nil
+
+	newDirs := n.tree.DrainNewDirs()
+	if len(newDirs) == 0 {
+		n.unlock()
+
+		return true, nil
+	}
+
+	return true, n.scanNewDirs(newDirs)
+}
+
+// TopGrowth returns up to the provided number of directories with the highest
+// growth rate within the last minute of watching.
+func (n *Navigation) TopGrowth(limit int) []structure.DirGrowth {
+	if n.growth == nil {
+		return nil
+	}
+
+	return n.growth.Top(limit, time.Now())
+}
+
+// Snapshots returns the snapshots of the current tree's root kept in the cache
+// history, newest first.
+func (n *Navigation) Snapshots() ([]cache.File, error) {
+	return n.tree.Snapshots()
+}
+
+// CompareWith restores the provided snapshot and uses it as a baseline the
+// current state is compared with. See Changes.
+func (n *Navigation) CompareWith(s cache.File) error {
+	root, err := n.tree.LoadSnapshot(s)
+	if err != nil {
+		return err
+	}
+
+	n.baseline, n.baselineAt = structure.NewTree(root), s.Header.CreatedAt
+	n.baselineIno = structure.NewInoIndex(root)
+	n.currentIno = structure.NewInoIndex(n.tree.Root())
+	n.changes = nil
+
+	return nil
+}
+
+// StopCompare drops the baseline set by CompareWith.
+func (n *Navigation) StopCompare() {
+	n.baseline, n.baselineAt = nil, time.Time{}
+	n.baselineIno, n.currentIno = nil, nil
+	n.changes = nil
+}
+
+// Comparing returns the time the baseline snapshot was created at, and reports
+// whether the current state is compared with a snapshot.
+func (n *Navigation) Comparing() (time.Time, bool) {
+	return n.baselineAt, n.baseline != nil
+}
+
+// Changes compares the current entry's content with the content of the same
+// directory within the baseline snapshot. It returns the diff of the child
+// entries and the directory's previous state. If the directory did not exist
+// in the snapshot, or it was a file, all child entries are reported as added,
+// and a nil previous state is returned.
+//
+// The entries moved from or to other directories since the snapshot are
+// reported as moved along with their other location. The current locations
+// are indexed once the comparison starts; hence, the entries moved later are
+// reported as removed.
+//
+// The result is cached until the current entry or the baseline changes, or the
+// entry's content is refreshed or updated by the watched changes. It's not
+// cached while the tree is being scanned.
+func (n *Navigation) Changes() (structure.Diff, *structure.Entry) {
+	if n.changes != nil && n.changes.entry == n.entry {
+		return n.changes.diff, n.changes.old
+	}
+
+	// the scan might complete while the diff is computed, so the state is
+	// checked beforehand
+	cacheable := !n.locked.Load() && !n.scanning.Load()
+
+	diff, old := n.changesOf(n.entry)
+
+	if cacheable {
+		n.changes = &changesCache{entry: n.entry, old: old, diff: diff}
+	}
+
+	return diff, old
+}
+
+func (n *Navigation) changesOf(entry *structure.Entry) (structure.Diff, *structure.Entry) {
+	var old *structure.Entry
+
+	if n.baseline != nil && entry != nil {
+		old = n.baseline.Lookup(entry.Path())
+	}
+
+	// the file replaced by the directory is compared as a missing directory
+	if old != nil && !old.IsDir {
+		old = nil
+	}
+
+	prev := old
+	if prev == nil {
+		prev = structure.NewDirEntry("", 0)
+	}
+
+	diff := structure.EntryList(slices.Collect(prev.Entries())).Diff(
+		slices.Collect(entry.Entries()),
+	)
+
+	if n.baseline != nil {
+		diff.MatchMoved(
+			n.baselineIno.Lookup,
+			func(key drive.InoKey) *structure.Entry {
+				// the indexed entry might have been removed since then
+				e := n.currentIno.Lookup(key)
+				if e == nil || n.tree.Lookup(e.Path()) != e {
+					return nil
+				}
+
+				return e
+			},
+		)
+	}
+
+	return diff, old
+}
+
+func (n *Navigation) watch(w *drive.Watcher, onEvents func([]drive.WatchEvent), onErr func(error)) {
+	for {
+		select {
+		case batch, ok := <-w.Events():
+			if !ok {
+				return
+			}
+
+			onEvents(batch)
+		case err, ok := <-w.Errors():
+			if !ok {
+				return
+			}
+
+			onErr(err)
+		}
+	}
+}
+
+// scanNewDirs scans the content of the new directories found by watching in the
+// background. The scan can be canceled by CancelScan, and the navigation is
+// unlocked once it's complete.
+func (n *Navigation) scanNewDirs(newDirs []*structure.Entry) chan struct{} {
+	var (
+		ctx  = n.scanContext()
+		done = make(chan struct{})
+	)
+
+	go func() {
+		defer close(done)
+		defer n.unlock()
+
+		for _, dir := range newDirs {
+			_ = n.tree.Subtree(dir).Traverse(ctx, true)
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			n.growth.Record(filepath.Dir(dir.Path()), dir.Size, time.Now())
+		}
+	}()
+
+	return done
+}
+
+// scanContext creates a new cancelable context for the scan and preserves its
+// cancel function, so the scan can be canceled by CancelScan.
+func (n *Navigation) scanContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	n.cancelScan = cancel
+
+	return ctx
+}
+
 func (n *Navigation) lock() bool {
 	return !n.locked.Swap(true)
 }
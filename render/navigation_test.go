@@ -0,0 +1,257 @@
+package render_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/crumbyte/noxdir/drive"
+	"github.com/crumbyte/noxdir/pkg/cache"
+	"github.com/crumbyte/noxdir/render"
+	"github.com/crumbyte/noxdir/structure"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestNavigation_ApplyWatchEvents applies the watched changes while the tree is
+// sorted and iterated the same way the UI does. It's meant to be run with the
+// race detector.
+func TestNavigation_ApplyWatchEvents(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("watching is supported on linux only")
+	}
+
+	const (
+		filesNumber = 100
+		totalSize   = filesNumber * (filesNumber - 1) / 2
+	)
+
+	root := t.TempDir()
+
+	nav, err := render.NewRootNavigation(
+		structure.NewTree(structure.NewDirEntry(root, 0)),
+	)
+	require.NoError(t, err)
+
+	nav.EnableWatch()
+
+	batches := make(chan []drive.WatchEvent)
+	done := make(chan struct{})
+
+	defer close(done)
+
+	require.NoError(t, nav.StartWatch(
+		func(batch []drive.WatchEvent) {
+			select {
+			case batches <- batch:
+			case <-done:
+			}
+		},
+		func(err error) {
+			t.Errorf("watch: %v", err)
+		},
+	))
+
+	defer nav.StopWatch()
+
+	go func() {
+		for i := range filesNumber {
+			name := filepath.Join(root, "file"+strconv.Itoa(i))
+
+			if err := os.WriteFile(name, make([]byte, i), 0o600); err != nil {
+				t.Errorf("create file: %v", err)
+
+				return
+			}
+		}
+	}()
+
+	timeout := time.After(10 * time.Second)
+
+	// the file's content might be reported separately from its creation
+	for nav.Entry().LocalFiles < filesNumber || nav.Entry().Size < totalSize {
+		select {
+		case batch := <-batches:
+			nav.ApplyWatchEvents(batch)
+		case <-timeout:
+			t.Fatalf("applied %d of %d files", nav.Entry().LocalFiles, filesNumber)
+		}
+
+		nav.Entry().SortChildBy(structure.ApparentSize)
+
+		for child := range nav.Entry().Entries() {
+			_ = child.Size
+		}
+	}
+
+	require.EqualValues(t, filesNumber, nav.Entry().LocalFiles)
+	require.EqualValues(t, totalSize, nav.Entry().Size)
+}
+
+// TestNavigation_ApplyWatchEventsNewDir moves a directory with its content into
+// the watched tree. The directory's content is scanned in the background while
+// the tree is read the same way the UI does.
+func TestNavigation_ApplyWatchEventsNewDir(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("watching is supported on linux only")
+	}
+
+	root, outside := t.TempDir(), t.TempDir()
+
+	require.NoError(t, os.MkdirAll(filepath.Join(outside, "new", "sub"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "new", "file"), make([]byte, 10), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "new", "sub", "file"), make([]byte, 100), 0o600))
+
+	nav, err := render.NewRootNavigation(
+		structure.NewTree(structure.NewDirEntry(root, 0)),
+	)
+	require.NoError(t, err)
+
+	nav.EnableWatch()
+
+	batches := make(chan []drive.WatchEvent, 16)
+
+	require.NoError(t, nav.StartWatch(
+		func(batch []drive.WatchEvent) { batches <- batch },
+		func(err error) { t.Errorf("watch: %v", err) },
+	))
+
+	defer nav.StopWatch()
+
+	require.NoError(t, os.Rename(filepath.Join(outside, "new"), filepath.Join(root, "new")))
+
+	var scanDone chan struct{}
+
+	select {
+	case batch := <-batches:
+		_, scanDone = nav.ApplyWatchEvents(batch)
+	case <-time.After(10 * time.Second):
+		t.Fatal("no watch events")
+	}
+
+	require.NotNil(t, scanDone)
+
+	// the changes watched during the scan are queued
+	applied, _ := nav.ApplyWatchEvents(nil)
+	require.False(t, applied)
+
+	for child := range nav.Entry().Entries() {
+		_ = child.SizeBy(structure.ApparentSize)
+	}
+
+	select {
+	case <-scanDone:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the new directory is not scanned")
+	}
+
+	require.EqualValues(t, 110, nav.Entry().Size)
+	require.EqualValues(t, 2, nav.Entry().TotalFiles)
+	require.NotNil(t, nav.Entry().GetChild("new").GetChild("sub").GetChild("file"))
+}
+
+func TestNavigation_ChangesCached(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("watching is supported on linux only")
+	}
+
+	root := t.TempDir()
+
+	require.NoError(t, os.WriteFile(filepath.Join(root, "file0"), nil, 0o600))
+
+	nav, err := render.NewRootNavigation(
+		structure.NewTree(structure.NewDirEntry(root, 0)),
+	)
+	require.NoError(t, err)
+
+	nav.EnableWatch()
+
+	batches := make(chan []drive.WatchEvent, 16)
+
+	require.NoError(t, nav.StartWatch(
+		func(batch []drive.WatchEvent) { batches <- batch },
+		func(err error) { t.Errorf("watch: %v", err) },
+	))
+
+	defer nav.StopWatch()
+
+	// without the baseline, the whole content is reported as added
+	diff, old := nav.Changes()
+	require.Nil(t, old)
+	require.Len(t, diff.Added, 1)
+
+	cached, _ := nav.Changes()
+	require.Same(t, &diff.Added[0], &cached.Added[0])
+
+	require.NoError(t, os.WriteFile(filepath.Join(root, "file1"), nil, 0o600))
+
+	select {
+	case batch := <-batches:
+		applied, _ := nav.ApplyWatchEvents(batch)
+		require.True(t, applied)
+	case <-time.After(10 * time.Second):
+		t.Fatal("no watch events")
+	}
+
+	diff, _ = nav.Changes()
+	require.Len(t, diff.Added, 2)
+}
+
+func TestNavigation_ChangesFileReplacedByDir(t *testing.T) {
+	root, cacheDir := t.TempDir(), t.TempDir()
+
+	c, err := cache.NewCache(
+		func(w io.Writer) cache.Encoder { return structure.NewEncoder(w) },
+		func(r io.Reader) cache.Decoder { return structure.NewDecoder(r) },
+		false,
+		cache.WithCacheDir(cacheDir),
+		cache.WithHistory(5, 0),
+	)
+	require.NoError(t, err)
+
+	item := filepath.Join(root, "item")
+
+	require.NoError(t, os.WriteFile(item, make([]byte, 10), 0o600))
+
+	tree := structure.NewTree(structure.NewDirEntry(root, 0), structure.WithCache(c))
+
+	require.NoError(t, tree.Traverse(context.Background(), true))
+	require.NoError(t, tree.PersistCache())
+
+	// the file is moved out of the tree, so its inode is not reused by the new
+	// entries and they're not reported as moved
+	require.NoError(t, os.Rename(item, filepath.Join(t.TempDir(), "item")))
+	require.NoError(t, os.Mkdir(item, 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(item, "file"), make([]byte, 20), 0o600))
+
+	tree.SetRoot(structure.NewDirEntry(root, 0))
+
+	require.NoError(t, tree.Traverse(context.Background(), true))
+	require.NoError(t, tree.PersistCache())
+
+	nav, err := render.NewRootNavigation(tree)
+	require.NoError(t, err)
+
+	snapshots, err := nav.Snapshots()
+	require.NoError(t, err)
+	require.Len(t, snapshots, 2)
+	require.NoError(t, nav.CompareWith(snapshots[1]))
+
+	diff, _ := nav.Changes()
+	require.Len(t, diff.Added, 1)
+	require.Len(t, diff.Removed, 1)
+
+	nav.Down("item", 0, func(*structure.Entry, render.State) {})
+	require.Equal(t, item, nav.Entry().Path())
+
+	// the file at the same path is not the directory's previous state
+	diff, old := nav.Changes()
+	require.Nil(t, old)
+	require.Len(t, diff.Added, 1)
+	require.Empty(t, diff.Removed)
+}
@@ -21,8 +21,26 @@ type (
 	UpdateDirState struct{}
 	ScanFinished   struct{}
 	EnqueueRefresh struct{}
+	WatchUpdate    struct{}
 )
 
+// WatchEvents contains a batch of the watched file system changes. The batches
+// are applied to the tree within the Update call, so the tree is never changed
+// while it's being rendered.
+type WatchEvents struct {
+	Batch []drive.WatchEvent
+}
+
+// WatchScanFinished is sent once the content of the new directories found by
+// watching is scanned.
+type WatchScanFinished struct{}
+
+// WatchError contains an error occurred while watching the tree, e.g., the
+// dropped change notifications.
+type WatchError struct {
+	Err error
+}
+
 var teaProg *tea.Program
 
 type ViewModel struct {
@@ -30,6 +48,8 @@ type ViewModel struct {
 	dirModel   *DirModel
 	nav        *Navigation
 	lastErr    []error
+
+	watchPending bool
 }
 
 func NewViewModel(n *Navigation, driveModel *DriveModel, dirMode *DirModel) *ViewModel {
@@ -50,34 +70,59 @@ func (vm *ViewModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	switch msg := msg.(type) {
 	case EnqueueRefresh:
-		vm.refresh()
+		vm.refresh(false)
+	case ScanFinished:
+		vm.startWatch()
+
+		// the changes watched during the refresh were queued
+		cmd = vm.applyWatchEvents(nil)
+	case ExportFinished:
+		// the changes watched during the export were queued
+		cmd = vm.applyWatchEvents(nil)
+	case WatchEvents:
+		cmd = vm.applyWatchEvents(msg.Batch)
+	case WatchScanFinished:
+		// the scanned directories have changed the tree, and the changes
+		// watched during the scan were queued
+		cmd = tea.Batch(vm.scheduleWatchUpdate(), vm.applyWatchEvents(nil))
+	case WatchUpdate:
+		vm.watchPending = false
 	case tea.KeyMsg:
 		bk := bindingKey(strings.ToLower(msg.String()))
 
-		if vm.dirModel.mode == INPUT {
+		if vm.dirModel.typing() {
 			break
 		}
 
 		switch bk {
 		case refresh:
-			vm.refresh()
+			vm.refresh(false)
+		case refreshChanged:
+			if !vm.nav.OnDrives() {
+				vm.refresh(true)
+			}
 		case quit, cancel:
+			// ctrl+c cancels the running scan instead of quitting
+			if bk == cancel && vm.scanning() {
+				break
+			}
+
 			return vm, tea.Quit
 		case enter, right:
-			if vm.dirModel.mode != DELETE || vm.nav.OnDrives() {
+			if !vm.dirModel.dialogOpen() || vm.nav.OnDrives() {
 				vm.levelDown()
 			}
 		case backspace, left:
-			if vm.dirModel.mode != DELETE || vm.nav.OnDrives() {
+			if !vm.dirModel.dialogOpen() || vm.nav.OnDrives() {
 				vm.levelUp()
 			}
 		}
 	}
 
 	vm.driveModel.Update(msg)
-	vm.dirModel.Update(msg)
+	_, dirCmd := vm.dirModel.Update(msg)
 
-	return vm, tea.Batch(cmd)
+	return vm, tea.Batch(cmd, dirCmd)
 }
 
 func (vm *ViewModel) View() string {
@@ -88,6 +133,10 @@ func (vm *ViewModel) View() string {
 	return vm.dirModel.View()
 }
 
+func (vm *ViewModel) scanning() bool {
+	return !vm.nav.OnDrives() && vm.dirModel.mode == PENDING
+}
+
 func (vm *ViewModel) levelDown() {
 	sr := vm.dirModel.dirsTable.SelectedRow()
 	cursor := vm.dirModel.dirsTable.Cursor()
@@ -154,13 +203,18 @@ func (vm *ViewModel) levelUp() {
 	})
 }
 
-func (vm *ViewModel) refresh() {
+func (vm *ViewModel) refresh(changedOnly bool) {
 	if vm.nav.OnDrives() {
 		vm.nav.RefreshDrives()
 		vm.driveModel.Update(nil)
 	}
 
-	done, errChan, err := vm.nav.RefreshEntry()
+	refreshEntry := vm.nav.RefreshEntry
+	if changedOnly {
+		refreshEntry = vm.nav.RefreshChangedEntry
+	}
+
+	done, errChan, err := refreshEntry()
 	if err != nil {
 		// TODO: the error might occur only if there were no directories in stack
 		return
@@ -197,6 +251,61 @@ func (vm *ViewModel) refresh() {
 	}()
 }
 
+// startWatch starts watching the scanned tree if it was enabled. The watched
+// changes and errors are sent to the program as messages, so they're handled
+// by the same goroutine that renders the tree.
+func (vm *ViewModel) startWatch() {
+	err := vm.nav.StartWatch(
+		func(batch []drive.WatchEvent) {
+			teaProg.Send(WatchEvents{Batch: batch})
+		},
+		func(err error) {
+			teaProg.Send(WatchError{Err: err})
+		},
+	)
+	if err != nil {
+		vm.dirModel.watchFailed(err)
+	}
+}
+
+// applyWatchEvents applies the batch of the watched changes and schedules their
+// rendering. If the changes contain new directories, the returned command waits
+// until their content is scanned.
+func (vm *ViewModel) applyWatchEvents(batch []drive.WatchEvent) tea.Cmd {
+	applied, scanDone := vm.nav.ApplyWatchEvents(batch)
+	if !applied {
+		return nil
+	}
+
+	if scanDone == nil {
+		return vm.scheduleWatchUpdate()
+	}
+
+	return tea.Batch(
+		vm.scheduleWatchUpdate(),
+		func() tea.Msg {
+			<-scanDone
+
+			return WatchScanFinished{}
+		},
+	)
+}
+
+// scheduleWatchUpdate schedules rendering of the applied watched changes. The
+// changes are rendered with the same interval as the scanning progress, so the
+// frequent changes are rendered at most once per interval.
+func (vm *ViewModel) scheduleWatchUpdate() tea.Cmd {
+	if vm.watchPending {
+		return nil
+	}
+
+	vm.watchPending = true
+
+	return tea.Tick(updateTickerInterval, func(time.Time) tea.Msg {
+		return WatchUpdate{}
+	})
+}
+
 func SetTeaProgram(tp *tea.Program) {
 	teaProg = tp
 }
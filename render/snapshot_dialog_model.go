@@ -0,0 +1,120 @@
+package render
+
+import (
+	"strings"
+	"time"
+
+	"github.com/crumbyte/noxdir/pkg/cache"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const snapshotDialogWidth = 60
+
+// SnapshotSelected is sent once the snapshot dialog is closed. The Snapshot
+// value is nil if the selection was cancelled.
+type SnapshotSelected struct {
+	Snapshot *cache.File
+}
+
+// SnapshotDialogModel lists the snapshots of the current root kept in the cache
+// history and allows choosing the one the current state will be compared with.
+type SnapshotDialogModel struct {
+	err       error
+	snapshots []cache.File
+	size      int64
+	choice    int
+}
+
+func NewSnapshotDialogModel(nav *Navigation) *SnapshotDialogModel {
+	snapshots, err := nav.Snapshots()
+
+	return &SnapshotDialogModel{
+		err:       err,
+		snapshots: snapshots,
+		size:      nav.tree.Root().Size,
+	}
+}
+
+func (sdm *SnapshotDialogModel) Init() tea.Cmd {
+	return nil
+}
+
+func (sdm *SnapshotDialogModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return sdm, nil
+	}
+
+	switch strings.ToLower(keyMsg.String()) {
+	case enter.String():
+		var selected *cache.File
+
+		if sdm.choice < len(sdm.snapshots) {
+			selected = &sdm.snapshots[sdm.choice]
+		}
+
+		go func() {
+			teaProg.Send(SnapshotSelected{Snapshot: selected})
+		}()
+	case escape.String():
+		go func() {
+			teaProg.Send(SnapshotSelected{})
+		}()
+	case "up", "k":
+		sdm.choice = max(0, sdm.choice-1)
+	case "down", "j":
+		sdm.choice = min(max(0, len(sdm.snapshots)-1), sdm.choice+1)
+	}
+
+	return sdm, nil
+}
+
+func (sdm *SnapshotDialogModel) View() string {
+	textStyle := lipgloss.NewStyle().Width(snapshotDialogWidth)
+
+	title := textStyle.
+		Align(lipgloss.Center).
+		Bold(true).
+		Render("Compare with snapshot\n")
+
+	rows := []string{title}
+
+	switch {
+	case sdm.err != nil:
+		rows = append(rows, textStyle.Render(sdm.err.Error()))
+	case len(sdm.snapshots) == 0:
+		rows = append(
+			rows,
+			textStyle.Align(lipgloss.Center).Render("No snapshots found"),
+		)
+	}
+
+	for i, s := range sdm.snapshots {
+		delta := FmtDelta(sdm.size-s.Header.Summary.Size, 0)
+		if len(delta) == 0 {
+			delta = "no change"
+		}
+
+		row := lipgloss.JoinHorizontal(
+			lipgloss.Top,
+			lipgloss.NewStyle().Width(18).Render(s.Header.CreatedAt.Format("2006-01-02 15:04")),
+			lipgloss.NewStyle().Width(12).Render(FmtAge(time.Since(s.Header.CreatedAt))),
+			lipgloss.NewStyle().Width(14).Render(FmtSize(s.Header.Summary.Size, 0)),
+			delta,
+		)
+
+		if i == sdm.choice {
+			rows = append(rows, style.SelectedRow().Width(snapshotDialogWidth).Render(row))
+
+			continue
+		}
+
+		rows = append(rows, textStyle.Render(row))
+	}
+
+	return style.DialogBox().Render(
+		lipgloss.JoinVertical(lipgloss.Left, rows...),
+	)
+}
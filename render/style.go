@@ -110,6 +110,88 @@ func (s *Style) TopFiles() *lipgloss.Style {
 	return cv
 }
 
+func (s *Style) ExcludedRow() *lipgloss.Style {
+	cv, ok := s.cache["excludedRow"]
+	if !ok {
+		cs := lipgloss.NewStyle().
+			Foreground(lipgloss.Color(s.cs.ExcludedText)).
+			Faint(true)
+
+		s.cache["excludedRow"] = &cs
+
+		return &cs
+	}
+
+	return cv
+}
+
+// AddedRow renders the entries added since the snapshot the current state is
+// compared with.
+func (s *Style) AddedRow() *lipgloss.Style {
+	cv, ok := s.cache["addedRow"]
+	if !ok {
+		cs := lipgloss.NewStyle().
+			Foreground(lipgloss.Color(s.cs.AddedText)).
+			Bold(true)
+
+		s.cache["addedRow"] = &cs
+
+		return &cs
+	}
+
+	return cv
+}
+
+// RemovedRow renders the entries removed since the snapshot the current state
+// is compared with.
+func (s *Style) RemovedRow() *lipgloss.Style {
+	cv, ok := s.cache["removedRow"]
+	if !ok {
+		cs := lipgloss.NewStyle().
+			Foreground(lipgloss.Color(s.cs.RemovedText)).
+			Strikethrough(true)
+
+		s.cache["removedRow"] = &cs
+
+		return &cs
+	}
+
+	return cv
+}
+
+// MovedRow renders the entries renamed or moved since the snapshot the current
+// state is compared with.
+func (s *Style) MovedRow() *lipgloss.Style {
+	cv, ok := s.cache["movedRow"]
+	if !ok {
+		cs := lipgloss.NewStyle().
+			Foreground(lipgloss.Color(s.cs.MovedText)).
+			Italic(true)
+
+		s.cache["movedRow"] = &cs
+
+		return &cs
+	}
+
+	return cv
+}
+
+// ModifiedRow renders the files modified since the snapshot the current state
+// is compared with.
+func (s *Style) ModifiedRow() *lipgloss.Style {
+	cv, ok := s.cache["modifiedRow"]
+	if !ok {
+		cs := lipgloss.NewStyle().
+			Foreground(lipgloss.Color(s.cs.ModifiedText))
+
+		s.cache["modifiedRow"] = &cs
+
+		return &cs
+	}
+
+	return cv
+}
+
 func (s *Style) Help() *lipgloss.Style {
 	cv, ok := s.cache["help"]
 	if !ok {
@@ -0,0 +1,206 @@
+// Package report renders the scanned tree as a self-contained HTML report that
+// can be shared and browsed without noxdir, e.g., attached to a ticket.
+package report
+
+import (
+	"container/heap"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"path/filepath"
+	"time"
+
+	"github.com/crumbyte/noxdir/structure"
+)
+
+//go:embed report.html
+var reportHTML string
+
+var reportTemplate = template.Must(template.New("report").Parse(reportHTML))
+
+// Option defines a custom option for the report.
+type Option func(*reporter)
+
+// WithMaxDepth limits the depth of the entries included in the report, where
+// the root's child entries are on depth 1. The directories' sizes still include
+// their entire content. Zero value means no limit.
+func WithMaxDepth(depth int) Option {
+	return func(r *reporter) {
+		r.maxDepth = depth
+	}
+}
+
+// WithMinSize skips the entries whose size is less than the provided value,
+// along with their content. The root entry is always included.
+func WithMinSize(size int64) Option {
+	return func(r *reporter) {
+		r.minSize = size
+	}
+}
+
+// WithSizeMode defines the size used for the treemap's areas, the top entries'
+// ranking, and the WithMinSize comparison.
+func WithSizeMode(sm structure.SizeMode) Option {
+	return func(r *reporter) {
+		r.sizeMode = sm
+	}
+}
+
+// WithAppVersion sets the application version shown in the report's footer.
+func WithAppVersion(version string) Option {
+	return func(r *reporter) {
+		r.appVersion = version
+	}
+}
+
+// WithTopEntries defines the maximum number of entries in the top files and
+// directories lists. The structure.DefaultMaxTopEntries value is used by
+// default.
+func WithTopEntries(n int) Option {
+	return func(r *reporter) {
+		r.topEntries = n
+	}
+}
+
+// node contains the reported entry's details. The keys are shortened since the
+// whole tree is embedded into the report.
+type node struct {
+	Name     string  `json:"n"`
+	Type     string  `json:"t"`
+	Children []*node `json:"c,omitempty"`
+	Size     int64   `json:"s"`
+	Usage    int64   `json:"u"`
+	ModTime  int64   `json:"m,omitempty"`
+	Files    uint64  `json:"f,omitempty"`
+	Dirs     uint64  `json:"d,omitempty"`
+}
+
+// topEntry contains the details of an entry from the top files or directories
+// lists.
+type topEntry struct {
+	Path    string `json:"path"`
+	Size    int64  `json:"size"`
+	Usage   int64  `json:"usage"`
+	ModTime int64  `json:"mtime,omitempty"`
+}
+
+// reportData contains the report's content embedded into the HTML document.
+type reportData struct {
+	Root      *node      `json:"root"`
+	Separator string     `json:"sep"`
+	SizeMode  string     `json:"sizeMode"`
+	TopFiles  []topEntry `json:"topFiles"`
+	TopDirs   []topEntry `json:"topDirs"`
+}
+
+type reporter struct {
+	appVersion string
+	maxDepth   int
+	minSize    int64
+	topEntries int
+	sizeMode   structure.SizeMode
+}
+
+// WriteHTML writes the report for the tree starting from the provided root as a
+// single HTML document. The document contains the styles and scripts inline and
+// doesn't load any external resources, so it can be viewed offline.
+func WriteHTML(w io.Writer, root *structure.Entry, opts ...Option) error {
+	r := &reporter{topEntries: structure.DefaultMaxTopEntries}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	te := structure.NewTopEntries(r.topEntries)
+	te.SetSizeMode(r.sizeMode)
+	te.ScanFiles(root)
+	te.ScanDirs(root)
+
+	data := reportData{
+		Root:      r.node(root, root.Path(), 0),
+		Separator: string(filepath.Separator),
+		SizeMode:  "size",
+		TopFiles:  topList(te.Files()),
+		TopDirs:   topList(te.Dirs()),
+	}
+
+	if r.sizeMode == structure.DiskUsage {
+		data.SizeMode = "usage"
+	}
+
+	err := reportTemplate.Execute(w, struct {
+		Data       reportData
+		Root       string
+		AppVersion string
+		Generated  string
+	}{
+		Data:       data,
+		Root:       root.Path(),
+		AppVersion: r.appVersion,
+		Generated:  time.Now().Format(time.RFC1123),
+	})
+	if err != nil {
+		return fmt.Errorf("render report: %w", err)
+	}
+
+	return nil
+}
+
+func (r *reporter) node(e *structure.Entry, name string, depth int) *node {
+	n := &node{
+		Name:    name,
+		Type:    "file",
+		Size:    e.Size,
+		Usage:   e.Usage,
+		ModTime: e.ModTime,
+	}
+
+	switch {
+	case e.IsLink:
+		n.Type = "link"
+	case e.IsDir:
+		n.Type = "dir"
+		n.Dirs, n.Files = e.Totals()
+	}
+
+	for child := range e.Entries() {
+		if r.include(child, depth+1) {
+			n.Children = append(n.Children, r.node(child, child.Name(), depth+1))
+		}
+	}
+
+	return n
+}
+
+// include reports whether the entry on the provided depth must be included in
+// the report.
+func (r *reporter) include(entry *structure.Entry, depth int) bool {
+	if r.maxDepth > 0 && depth > r.maxDepth {
+		return false
+	}
+
+	return entry.SizeBy(r.sizeMode) >= r.minSize
+}
+
+// topList drains the heap and returns its entries ordered from the biggest to
+// the smallest one.
+func topList(h heap.Interface) []topEntry {
+	list := make([]topEntry, h.Len())
+
+	for i := len(list) - 1; i >= 0; i-- {
+		e, ok := heap.Pop(h).(*structure.Entry)
+		if !ok {
+			continue
+		}
+
+		list[i] = topEntry{
+			Path:    e.Path(),
+			Size:    e.Size,
+			Usage:   e.Usage,
+			ModTime: e.ModTime,
+		}
+	}
+
+	return list
+}
@@ -0,0 +1,121 @@
+package report_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/crumbyte/noxdir/report"
+	"github.com/crumbyte/noxdir/structure"
+
+	"github.com/stretchr/testify/require"
+)
+
+var testRootPath = filepath.Join(string(filepath.Separator), "data")
+
+type reportNode struct {
+	Name     string       `json:"n"`
+	Type     string       `json:"t"`
+	Children []reportNode `json:"c"`
+	Size     int64        `json:"s"`
+	Files    uint64       `json:"f"`
+}
+
+type reportData struct {
+	Root     reportNode `json:"root"`
+	TopFiles []struct {
+		Path string `json:"path"`
+		Size int64  `json:"size"`
+	} `json:"topFiles"`
+}
+
+func TestWriteHTML(t *testing.T) {
+	var buf bytes.Buffer
+
+	require.NoError(t, report.WriteHTML(&buf, testTree(), report.WithAppVersion("v1.2.3")))
+
+	html := buf.String()
+	require.True(t, strings.HasPrefix(html, "<!DOCTYPE html>"))
+	require.Contains(t, html, "noxdir v1.2.3")
+
+	// the report must not depend on any external resources
+	require.NotRegexp(t, `(?i)(src|href)\s*=\s*["']?(https?:)?//`, html)
+
+	data := readData(t, html)
+	require.Equal(t, testRootPath, data.Root.Name)
+	require.Equal(t, "dir", data.Root.Type)
+	require.EqualValues(t, 1111, data.Root.Size)
+	require.EqualValues(t, 3, data.Root.Files)
+	require.Len(t, data.Root.Children, 2)
+
+	require.Len(t, data.TopFiles, 3)
+	require.Equal(t, filepath.Join(testRootPath, "a", "big.bin"), data.TopFiles[0].Path)
+	require.EqualValues(t, 1000, data.TopFiles[0].Size)
+}
+
+func TestWriteHTML_Pruned(t *testing.T) {
+	var buf bytes.Buffer
+
+	require.NoError(
+		t,
+		report.WriteHTML(&buf, testTree(), report.WithMaxDepth(1), report.WithMinSize(50)),
+	)
+
+	data := readData(t, buf.String())
+	require.Len(t, data.Root.Children, 1)
+	require.Equal(t, "a", data.Root.Children[0].Name)
+	require.Empty(t, data.Root.Children[0].Children)
+
+	// the top lists are built from the entire tree
+	require.Len(t, data.TopFiles, 3)
+}
+
+func TestWriteHTML_Escaping(t *testing.T) {
+	name := `<script>alert(1)<!--`
+	root := structure.NewDirEntry(testRootPath, 0)
+
+	root.AddChild(structure.NewFileEntry(name, 10, 0))
+
+	var buf bytes.Buffer
+
+	require.NoError(t, report.WriteHTML(&buf, root))
+	require.NotContains(t, buf.String(), name)
+
+	data := readData(t, buf.String())
+	require.Len(t, data.Root.Children, 1)
+	require.Equal(t, name, data.Root.Children[0].Name)
+}
+
+// readData extracts and decodes the tree embedded into the report.
+func readData(t *testing.T, html string) reportData {
+	t.Helper()
+
+	_, rest, ok := strings.Cut(html, `<script id="report-data" type="application/json">`)
+	require.True(t, ok)
+
+	raw, _, ok := strings.Cut(rest, "</script>")
+	require.True(t, ok)
+
+	var data reportData
+
+	require.NoError(t, json.Unmarshal([]byte(raw), &data))
+
+	return data
+}
+
+func testTree() *structure.Entry {
+	root := structure.NewDirEntry(testRootPath, 0)
+	a := structure.NewDirEntry("a", 0)
+	c := structure.NewDirEntry("c", 0)
+
+	root.AddChild(a)
+	root.AddChild(c)
+
+	a.AddChild(structure.NewFileEntry("big.bin", 1000, 0))
+	a.AddChild(structure.NewFileEntry("small.txt", 100, 0))
+	c.AddChild(structure.NewFileEntry("tiny", 11, 0))
+
+	return root
+}
@@ -0,0 +1,79 @@
+package structure
+
+import "sync/atomic"
+
+// WithMaxDepth limits the depth of the tree kept in memory. The directories on
+// the provided depth, where the root's children are on depth 1, are collapsed:
+// their content is still scanned to calculate the size and the number of the
+// child entries, but the child entries themselves are not kept in the tree.
+//
+// A zero or negative value disables the limit.
+func WithMaxDepth(depth int) TreeOpt {
+	return func(t *Tree) {
+		t.maxDepth = depth
+	}
+}
+
+// depth returns the depth of the provided entry relative to the tree's root. The
+// root itself has zero depth. The synthetic groups, e.g., the ignored entries,
+// do not add to the depth.
+func (t *Tree) depth(e *Entry) int {
+	var depth int
+
+	for p := e; p != nil && p != t.root; p = p.parent {
+		if !p.IsIgnored {
+			depth++
+		}
+	}
+
+	return depth
+}
+
+// collapsedTarget returns the collapsed entry the content of the provided
+// directory must be summarized into. The directory on the maximum depth becomes
+// the collapsed entry itself, while all directories below it are transient and
+// refer to their collapsed ancestor. A nil value will be returned if the
+// directory's content must be kept in the tree.
+func (t *Tree) collapsedTarget(e *Entry) *Entry {
+	if t.maxDepth <= 0 {
+		return nil
+	}
+
+	if v, ok := t.collapsedDirs.LoadAndDelete(e); ok {
+		target, _ := v.(*Entry)
+
+		return target
+	}
+
+	if t.depth(e) < t.maxDepth {
+		return nil
+	}
+
+	e.IsCollapsed = true
+
+	return e
+}
+
+// addChild adds the child entry to the parent one. If the parent's content is
+// collapsed, only the child's size and the number of entries are added to the
+// collapsed entry and its ancestors, and the child entry itself is discarded.
+func addChild(parent, collapsed, child *Entry) {
+	if collapsed == nil {
+		parent.AddChild(child)
+
+		return
+	}
+
+	dirs, files := entryCount(child)
+	collapsed.grow(child.Size, child.Usage, dirs, files)
+
+	if parent != collapsed {
+		return
+	}
+
+	if child.IsDir {
+		atomic.AddUint64(&collapsed.LocalDirs, 1)
+	} else {
+		atomic.AddUint64(&collapsed.LocalFiles, 1)
+	}
+}
@@ -6,6 +6,16 @@ import (
 	"io"
 	"sync"
 	"unsafe"
+
+	"github.com/crumbyte/noxdir/pkg/cache"
+)
+
+const (
+	dirFlag       = 1 << 0
+	linkFlag      = 1 << 1
+	excludedFlag  = 1 << 2
+	ignoredFlag   = 1 << 3
+	collapsedFlag = 1 << 4
 )
 
 type Encoder struct {
@@ -18,9 +28,9 @@ func NewEncoder(w io.Writer) *Encoder {
 
 var bufferPool = sync.Pool{
 	New: func() any {
-		// 48 bytes for 2 int64 and 4 uint64, 1 byte for dir flag, and 4 bytes
-		// for the number of child entries.
-		buf := make([]byte, 8*6+1+4)
+		// 72 bytes for 3 int64 and 6 uint64, 1 byte for the entry flags, and 4
+		// bytes for the number of child entries.
+		buf := make([]byte, 8*9+1+4)
 
 		return &buf
 	},
@@ -43,30 +53,61 @@ func (e *Encoder) Encode(v any) error {
 	{
 		binary.LittleEndian.PutUint64((*buf)[0:], uint64(entry.ModTime))
 		binary.LittleEndian.PutUint64((*buf)[8:], uint64(entry.Size))
+		binary.LittleEndian.PutUint64((*buf)[16:], uint64(entry.Usage))
 	}
 
-	binary.LittleEndian.PutUint64((*buf)[16:], entry.LocalDirs)
-	binary.LittleEndian.PutUint64((*buf)[24:], entry.LocalFiles)
-	binary.LittleEndian.PutUint64((*buf)[32:], entry.TotalDirs)
-	binary.LittleEndian.PutUint64((*buf)[40:], entry.TotalFiles)
-	(*buf)[48] = 0
+	clear((*buf)[24:])
+
+	if entry.dirData != nil {
+		binary.LittleEndian.PutUint64((*buf)[24:], entry.LocalDirs)
+		binary.LittleEndian.PutUint64((*buf)[32:], entry.LocalFiles)
+		binary.LittleEndian.PutUint64((*buf)[40:], entry.TotalDirs)
+		binary.LittleEndian.PutUint64((*buf)[48:], entry.TotalFiles)
+	}
+
+	binary.LittleEndian.PutUint64((*buf)[56:], entry.Ino.Dev)
+	binary.LittleEndian.PutUint64((*buf)[64:], entry.Ino.Ino)
 
 	if entry.IsDir {
-		(*buf)[48] = 1
+		(*buf)[72] |= dirFlag
 	}
 
-	//nolint:gosec // ...
-	binary.LittleEndian.PutUint32((*buf)[49:], uint32(len(entry.Child)))
+	if entry.IsLink {
+		(*buf)[72] |= linkFlag
+	}
+
+	if entry.IsExcluded {
+		(*buf)[72] |= excludedFlag
+	}
+
+	if entry.IsIgnored {
+		(*buf)[72] |= ignoredFlag
+	}
+
+	if entry.IsCollapsed {
+		(*buf)[72] |= collapsedFlag
+	}
+
+	if entry.HasChild() {
+		//nolint:gosec // ...
+		binary.LittleEndian.PutUint32((*buf)[73:], uint32(len(entry.Child)))
+	}
 
 	if _, err := e.w.Write(*buf); err != nil {
 		return fmt.Errorf("structure: write buffer: %w", err)
 	}
 
-	if err := e.writeString(entry.Path); err != nil {
+	if err := e.writeString(entry.Path()); err != nil {
 		return fmt.Errorf("structure: write path: %w", err)
 	}
 
-	for _, child := range entry.Child {
+	if entry.IsLink {
+		if err := e.writeString(entry.Target); err != nil {
+			return fmt.Errorf("structure: write link target: %w", err)
+		}
+	}
+
+	for child := range entry.Entries() {
 		if err := e.Encode(child); err != nil {
 			return err
 		}
@@ -75,6 +116,18 @@ func (e *Encoder) Encode(v any) error {
 	return nil
 }
 
+// CacheSummary returns the entry's totals, which are stored in the cache file's
+// header.
+func (e *Entry) CacheSummary() cache.Summary {
+	s := cache.Summary{Size: e.Size, Usage: e.Usage}
+
+	if e.dirData != nil {
+		s.Dirs, s.Files = e.TotalDirs, e.TotalFiles
+	}
+
+	return s
+}
+
 func (e *Encoder) writeString(s string) error {
 	//nolint:gosec // ...
 	err := binary.Write(e.w, binary.LittleEndian, int32(len(s)))
@@ -116,23 +169,47 @@ func (d *Decoder) Decode(v any) error {
 	{
 		entry.ModTime = int64(binary.LittleEndian.Uint64((*buf)[0:]))
 		entry.Size = int64(binary.LittleEndian.Uint64((*buf)[8:]))
+		entry.Usage = int64(binary.LittleEndian.Uint64((*buf)[16:]))
 	}
 
-	entry.LocalDirs = uint64(binary.LittleEndian.Uint32((*buf)[16:]))
-	entry.LocalFiles = uint64(binary.LittleEndian.Uint32((*buf)[24:]))
-	entry.TotalDirs = uint64(binary.LittleEndian.Uint32((*buf)[32:]))
-	entry.TotalFiles = uint64(binary.LittleEndian.Uint32((*buf)[40:]))
-	entry.IsDir = (*buf)[48] == 1
+	entry.Ino.Dev = binary.LittleEndian.Uint64((*buf)[56:])
+	entry.Ino.Ino = binary.LittleEndian.Uint64((*buf)[64:])
+
+	entry.IsDir = (*buf)[72]&dirFlag != 0
+	entry.IsLink = (*buf)[72]&linkFlag != 0
+	entry.IsExcluded = (*buf)[72]&excludedFlag != 0
+	entry.IsIgnored = (*buf)[72]&ignoredFlag != 0
+	entry.IsCollapsed = (*buf)[72]&collapsedFlag != 0
+
+	// only directories and links have the details allocated
+	if entry.IsDir || entry.IsLink {
+		entry.dirData = &dirData{
+			LocalDirs:  binary.LittleEndian.Uint64((*buf)[24:]),
+			LocalFiles: binary.LittleEndian.Uint64((*buf)[32:]),
+			TotalDirs:  binary.LittleEndian.Uint64((*buf)[40:]),
+			TotalFiles: binary.LittleEndian.Uint64((*buf)[48:]),
+		}
+	}
 
-	childCount := binary.LittleEndian.Uint32((*buf)[49:])
+	childCount := binary.LittleEndian.Uint32((*buf)[73:])
 
 	bufferPool.Put(buf)
 
-	entry.Path, err = d.readString()
+	entry.name, err = d.readString()
 	if err != nil {
 		return fmt.Errorf("decoding path: %w", err)
 	}
 
+	if entry.IsLink {
+		if entry.Target, err = d.readString(); err != nil {
+			return fmt.Errorf("decoding link target: %w", err)
+		}
+	}
+
+	if !entry.IsDir {
+		return nil
+	}
+
 	entry.Child = make([]*Entry, 0, childCount)
 
 	for range childCount {
@@ -142,6 +219,7 @@ func (d *Decoder) Decode(v any) error {
 			return err
 		}
 
+		entry.adopt(child)
 		entry.Child = append(entry.Child, child)
 	}
 
@@ -155,6 +233,10 @@ func (d *Decoder) readString() (string, error) {
 		return "", err
 	}
 
+	if l < 0 {
+		return "", fmt.Errorf("invalid string length: %d", l)
+	}
+
 	b := make([]byte, l)
 	_, err := io.ReadFull(d.r, b)
 
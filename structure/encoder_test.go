@@ -0,0 +1,44 @@
+package structure_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/crumbyte/noxdir/drive"
+	"github.com/crumbyte/noxdir/structure"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncoder_RoundTrip(t *testing.T) {
+	root := testDir(
+		"/root",
+		testDir("dir", testFile("file.txt", 10)),
+		testFile("huge.img", 1<<42),
+	)
+
+	// the counters exceeding 32 bits must survive the round trip
+	root.TotalFiles += 1 << 33
+	root.GetChild("dir").GetChild("file.txt").Ino = drive.InoKey{Dev: 1 << 40, Ino: 42}
+
+	var buf bytes.Buffer
+
+	require.NoError(t, structure.NewEncoder(&buf).Encode(root))
+
+	restored := &structure.Entry{}
+
+	require.NoError(t, structure.NewDecoder(&buf).Decode(restored))
+
+	require.Equal(t, "/root", restored.Path())
+	require.Equal(t, root.Size, restored.Size)
+	require.Equal(t, root.TotalFiles, restored.TotalFiles)
+	require.Equal(t, root.TotalDirs, restored.TotalDirs)
+	require.Equal(t, root.LocalFiles, restored.LocalFiles)
+
+	file := restored.GetChild("dir").GetChild("file.txt")
+	require.NotNil(t, file)
+	require.Equal(t, filepath.Join("/root", "dir", "file.txt"), file.Path())
+	require.EqualValues(t, 10, file.Size)
+	require.Equal(t, drive.InoKey{Dev: 1 << 40, Ino: 42}, file.Ino)
+}
@@ -1,7 +1,6 @@
 package structure
 
 import (
-	"bytes"
 	"cmp"
 	"iter"
 	"maps"
@@ -10,22 +9,38 @@ import (
 	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
+
+	"github.com/crumbyte/noxdir/drive"
+)
+
+// SizeMode defines which of the entry sizes must be used for sorting, ranking,
+// and displaying the entries.
+type SizeMode uint8
+
+const (
+	// ApparentSize uses the file length reported by the file system.
+	ApparentSize SizeMode = iota
+
+	// DiskUsage uses the space actually allocated for the entry on the disk.
+	DiskUsage
 )
 
 // Entry contains the information about a single directory or a file instance
 // within the file system. If the entry represents a directory instance, it has
 // access to its child elements.
+//
+// The entry keeps only its own name and a link to the parent entry, and the full
+// path is built on demand. The data only directories and symbolic links have is
+// allocated for such entries only, so file entries stay as compact as possible.
 type Entry struct {
-	// Path contains the full path to the file or directory represented as an
-	// instance of the entry.
-	Path string
+	*dirData
 
-	// Child contains a list of all child instances including both files and
-	// directories. If the current Entry instance represents a file, this
-	// property will always be nil.
-	Child []*Entry
+	parent *Entry
 
-	mx sync.RWMutex
+	// name contains the entry's name, or the full path if the entry has no
+	// parent, e.g., the tree's root.
+	name string
 
 	// ModTime contains the last modification time of the entry.
 	ModTime int64
@@ -33,61 +48,230 @@ type Entry struct {
 	// Size contains a total tail in bytes including sizes of all child entries.
 	Size int64
 
-	// LocalDirs contain the number of directories within the current entry. This
-	// property will always be zero if the current instance represents a file.
+	// Usage contains the total number of bytes allocated on the disk, including
+	// the allocated space of all child entries. It may differ from the Size
+	// value for sparse or preallocated files, and for small files stored in
+	// large file system blocks.
+	Usage int64
+
+	// Ino contains the device and inode numbers of the file system object the
+	// entry represents. They identify the entry across renames and moves. Both
+	// values are zero if the operating system does not provide them.
+	Ino drive.InoKey
+
+	// IsDir defines whether the current instance represents a dir or a file.
+	IsDir bool
+
+	// IsLink defines whether the current instance represents a symbolic link.
+	// A followed link to a directory is a directory as well and contains the
+	// target's child entries.
+	IsLink bool
+
+	// IsExcluded defines whether the current directory was excluded from the
+	// traversal by the exclusion rules. Such a directory is a placeholder and
+	// never contains child entries.
+	IsExcluded bool
+
+	// IsIgnored defines whether the current instance is a synthetic directory
+	// that groups the entries matched by the ignore files. Such a directory
+	// does not exist on the file system and is not a part of its child entries'
+	// paths.
+	IsIgnored bool
+
+	// IsCollapsed defines whether the current directory is located on the
+	// maximum tree depth. Its content was scanned to calculate the size and the
+	// number of entries, but the child entries were not kept.
+	IsCollapsed bool
+}
+
+// childIndexThreshold defines the number of child entries starting from which
+// the lookup by name uses the index instead of the linear search.
+const childIndexThreshold = 64
+
+// dirData contains the fields only directories and symbolic links have. The
+// fields are promoted to the Entry, and must not be accessed for the file
+// entries, which have no dirData allocated. The entries whose type is not known
+// must be read through the nil-safe accessors instead, e.g., Entries, Totals,
+// LocalCounts, ChildCount, or LinkTarget.
+type dirData struct {
+	// Child contains a list of all child instances including both files and
+	// directories.
+	Child []*Entry
+
+	// Target contains the path the symbolic link points to. It's empty if the
+	// current instance is not a symbolic link or its target cannot be read.
+	Target string
+
+	// index maps the child entries' names to the entries. It's built on the
+	// first lookup once the directory has at least childIndexThreshold child
+	// entries, and is kept up to date by the entry's methods afterward.
+	index map[string]*Entry
+
+	mx sync.RWMutex
+
+	// LocalDirs contain the number of directories within the current entry.
 	LocalDirs uint64
 
-	// LocalFiles contain the number of files within the current entry. This property
-	// will always be zero if the current instance represents a file.
+	// LocalFiles contain the number of files within the current entry.
 	LocalFiles uint64
 
 	// TotalDirs contains the total number of directories within the current
-	// entry, including directories within the child entries. This property will
-	// always be zero if the current instance represents a file.
+	// entry, including directories within the child entries.
 	TotalDirs uint64
 
 	// TotalFiles contains the total number of files within the current entry,
-	// including files within the child entries. This property will always be
-	// zero if the current instance represents a file.
+	// including files within the child entries.
 	TotalFiles uint64
-
-	// IsDir defines whether the current instance represents a dir or a file.
-	IsDir bool
 }
 
+// NewDirEntry creates a new directory *Entry instance with the provided full
+// path. Once the entry is added to the parent one, only its name is kept.
 func NewDirEntry(path string, modTime int64) *Entry {
 	return &Entry{
-		Path:    path,
-		Child:   make([]*Entry, 0),
+		dirData: &dirData{Child: make([]*Entry, 0)},
+		name:    path,
 		IsDir:   true,
 		ModTime: modTime,
 	}
 }
 
+// NewFileEntry creates a new file *Entry instance. The allocated size is set
+// equal to the apparent size and can be overridden if the actual value is known.
 func NewFileEntry(path string, size int64, modTime int64) *Entry {
 	return &Entry{
-		Path:    path,
+		name:    path,
 		Size:    size,
+		Usage:   size,
 		ModTime: modTime,
 	}
 }
 
+// NewLinkEntry creates a new *Entry instance representing a symbolic link that
+// has not been followed. Such an entry has no size and no child entries.
+func NewLinkEntry(path, target string, modTime int64) *Entry {
+	return &Entry{
+		dirData: &dirData{Target: target},
+		name:    path,
+		ModTime: modTime,
+		IsLink:  true,
+	}
+}
+
+// Name returns the name of the file or directory represented by the entry.
 func (e *Entry) Name() string {
-	li := bytes.LastIndex([]byte(e.Path), []byte{os.PathSeparator})
+	if e.parent != nil {
+		return e.name
+	}
+
+	li := strings.LastIndexByte(e.name, os.PathSeparator)
 	if li == -1 {
-		return e.Path
+		return e.name
+	}
+
+	return e.name[li+1:]
+}
+
+// Path builds the full path to the file or directory represented by the entry.
+// The synthetic groups, e.g., the ignored entries, are not a part of the path.
+func (e *Entry) Path() string {
+	if e.parent == nil {
+		return e.name
+	}
+
+	var (
+		buf   [32]*Entry
+		chain = buf[:0]
+		size  int
+	)
+
+	for p := e; p != nil; p = p.parent {
+		if p.IsIgnored && p != e {
+			continue
+		}
+
+		chain = append(chain, p)
+		size += len(p.name) + 1
+	}
+
+	var sb strings.Builder
+
+	sb.Grow(size)
+
+	for i := len(chain) - 1; i >= 0; i-- {
+		if i != len(chain)-1 && !strings.HasSuffix(chain[i+1].name, string(os.PathSeparator)) {
+			sb.WriteByte(os.PathSeparator)
+		}
+
+		sb.WriteString(chain[i].name)
+	}
+
+	return sb.String()
+}
+
+// Parent returns the parent entry, or nil if the entry has no parent.
+func (e *Entry) Parent() *Entry {
+	return e.parent
+}
+
+// SizeBy returns either the apparent size or the allocated size of the entry
+// depending on the provided SizeMode value.
+func (e *Entry) SizeBy(sm SizeMode) int64 {
+	if sm == DiskUsage {
+		return atomic.LoadInt64(&e.Usage)
+	}
+
+	return atomic.LoadInt64(&e.Size)
+}
+
+// Totals returns the total number of directories and files within the entry.
+// The values are loaded atomically, so they can be read while the entry is
+// still being scanned. Zero values are returned for the file entries.
+func (e *Entry) Totals() (uint64, uint64) {
+	if e.dirData == nil {
+		return 0, 0
+	}
+
+	return atomic.LoadUint64(&e.TotalDirs), atomic.LoadUint64(&e.TotalFiles)
+}
+
+// LocalCounts returns the number of directories and files directly within the
+// entry. Zero values are returned for the file entries.
+func (e *Entry) LocalCounts() (uint64, uint64) {
+	if e.dirData == nil {
+		return 0, 0
+	}
+
+	e.mx.RLock()
+	defer e.mx.RUnlock()
+
+	return e.LocalDirs, e.LocalFiles
+}
+
+// ChildCount returns the number of the entry's child entries. Zero is returned
+// for the file entries.
+func (e *Entry) ChildCount() int {
+	return len(e.children())
+}
+
+// LinkTarget returns the path the symbolic link points to. An empty string is
+// returned if the entry is not a symbolic link or its target cannot be read.
+func (e *Entry) LinkTarget() string {
+	if e.dirData == nil {
+		return ""
 	}
 
-	return e.Path[li+1:]
+	return e.Target
 }
 
 func (e *Entry) Ext() string {
-	li := bytes.LastIndex([]byte(e.Path), []byte{'.'})
+	name := e.Name()
+
+	li := strings.LastIndexByte(name, '.')
 	if li == -1 {
-		return e.Path
+		return name
 	}
 
-	return strings.ToLower(e.Path[li+1:])
+	return strings.ToLower(name[li+1:])
 }
 
 // EntriesByType returns an iterator for the current node's child elements.
@@ -95,8 +279,8 @@ func (e *Entry) Ext() string {
 // or files.
 func (e *Entry) EntriesByType(dirs bool) iter.Seq[*Entry] {
 	return func(yield func(*Entry) bool) {
-		for i := range e.Child {
-			if e.Child[i].IsDir == dirs && !yield(e.Child[i]) {
+		for _, child := range e.children() {
+			if child.IsDir == dirs && !yield(child) {
 				break
 			}
 		}
@@ -106,25 +290,74 @@ func (e *Entry) EntriesByType(dirs bool) iter.Seq[*Entry] {
 // Entries returns an iterator for all the current node's child elements.
 func (e *Entry) Entries() iter.Seq[*Entry] {
 	return func(yield func(*Entry) bool) {
-		for i := range e.Child {
-			if !yield(e.Child[i]) {
+		for _, child := range e.children() {
+			if !yield(child) {
 				break
 			}
 		}
 	}
 }
 
+// children returns the current list of the child entries. The list is taken
+// under the lock, so the entries added concurrently, e.g., by the running scan,
+// don't affect the iteration.
+func (e *Entry) children() []*Entry {
+	if e.dirData == nil {
+		return nil
+	}
+
+	e.mx.RLock()
+	defer e.mx.RUnlock()
+
+	return e.Child
+}
+
 // GetChild tries to find a child element by its name. The search will be done
 // only on the first level of the child entries. If such an entry was not found,
 // a nil value will be returned.
+//
+// The child entries are matched by their names rather than full paths, since
+// the entries within a synthetic group, e.g., the ignored entries, keep their
+// actual paths on the file system.
 func (e *Entry) GetChild(name string) *Entry {
+	if e.dirData == nil {
+		return nil
+	}
+
 	e.mx.RLock()
-	defer e.mx.RUnlock()
 
-	path := filepath.Join(e.Path, name)
+	if e.index != nil || len(e.Child) < childIndexThreshold {
+		defer e.mx.RUnlock()
+
+		return e.lookupChild(name)
+	}
+
+	e.mx.RUnlock()
+
+	e.mx.Lock()
+	defer e.mx.Unlock()
+
+	// the index might have been built while the lock was released
+	if e.index == nil {
+		e.index = make(map[string]*Entry, len(e.Child))
+
+		for _, child := range e.Child {
+			e.index[child.Name()] = child
+		}
+	}
+
+	return e.index[name]
+}
+
+// lookupChild finds the child entry by its name using the index if it's built.
+// The caller must hold the lock.
+func (e *Entry) lookupChild(name string) *Entry {
+	if e.index != nil {
+		return e.index[name]
+	}
 
 	for _, child := range e.Child {
-		if child.Path == path {
+		if child.Name() == name {
 			return child
 		}
 	}
@@ -133,74 +366,272 @@ func (e *Entry) GetChild(name string) *Entry {
 }
 
 // AddChild adds the provided [*Entry] instance to a list of child entries. The
-// counters will be updated respectively depending on the type of child entry.
+// local counters will be updated respectively depending on the type of child
+// entry. The child's size and the total number of entries it represents are
+// added to the current entry and all its ancestors right away, so the totals
+// are always up to date.
 func (e *Entry) AddChild(child *Entry) {
 	e.mx.Lock()
-	defer e.mx.Unlock()
 
 	if e.Child == nil {
 		e.Child = make([]*Entry, 0, 10)
 	}
 
+	e.adopt(child)
 	e.Child = append(e.Child, child)
 
+	if e.index != nil {
+		e.index[child.name] = child
+	}
+
 	if child.IsDir {
-		e.TotalDirs, e.LocalDirs = e.TotalDirs+1, e.LocalDirs+1
+		e.LocalDirs++
+	} else {
+		e.LocalFiles++
+	}
+
+	e.mx.Unlock()
 
+	dirs, files := entryCount(child)
+	e.grow(child.Size, child.Usage, dirs, files)
+}
+
+// ClearChild removes all child entries of the directory. The sizes and the
+// total number of entries of the directory become zero, and the removed values
+// are subtracted from all its ancestors.
+func (e *Entry) ClearChild() {
+	if e.dirData == nil {
 		return
 	}
 
-	e.TotalFiles, e.LocalFiles = e.TotalFiles+1, e.LocalFiles+1
+	e.mx.Lock()
+	e.Child, e.index, e.LocalDirs, e.LocalFiles = nil, nil, 0, 0
+	e.mx.Unlock()
+
+	//nolint:gosec // the totals always fit
+	e.grow(
+		-atomic.LoadInt64(&e.Size),
+		-atomic.LoadInt64(&e.Usage),
+		-int64(atomic.LoadUint64(&e.TotalDirs)),
+		-int64(atomic.LoadUint64(&e.TotalFiles)),
+	)
+}
+
+// RemoveChild removes the provided child entry from the list of child entries.
+// The child's size and the total number of entries it represents are subtracted
+// from the current entry and all its ancestors. It reports whether the child
+// entry was found.
+func (e *Entry) RemoveChild(child *Entry) bool {
+	if e.dirData == nil {
+		return false
+	}
+
+	e.mx.Lock()
+
+	idx := slices.Index(e.Child, child)
+	if idx == -1 {
+		e.mx.Unlock()
+
+		return false
+	}
+
+	e.Child = slices.Delete(e.Child, idx, idx+1)
+
+	if e.index[child.name] == child {
+		delete(e.index, child.name)
+	}
+
+	if child.IsDir && e.LocalDirs > 0 {
+		e.LocalDirs--
+	} else if !child.IsDir && e.LocalFiles > 0 {
+		e.LocalFiles--
+	}
+
+	e.mx.Unlock()
+
+	dirs, files := entryCount(child)
+	e.grow(-child.Size, -child.Usage, -dirs, -files)
+
+	return true
+}
+
+// grow atomically adds the provided deltas to the sizes and the total number of
+// entries of the current entry and all its ancestors.
+func (e *Entry) grow(size, usage, dirs, files int64) {
+	for p := e; p != nil; p = p.parent {
+		atomic.AddInt64(&p.Size, size)
+		atomic.AddInt64(&p.Usage, usage)
+
+		// the file entries have no totals
+		if p.dirData == nil {
+			continue
+		}
+
+		// the negative deltas wrap around, which decrements the totals
+		atomic.AddUint64(&p.TotalDirs, uint64(dirs))   //nolint:gosec // see above
+		atomic.AddUint64(&p.TotalFiles, uint64(files)) //nolint:gosec // see above
+	}
+}
+
+// entryCount returns the number of directories and files the entry represents,
+// including the entry itself. The ignored entries group is not an actual
+// directory; hence, it's counted as a file.
+func entryCount(e *Entry) (int64, int64) {
+	if !e.IsDir {
+		return 0, 1
+	}
+
+	//nolint:gosec // the totals always fit
+	dirs, files := int64(atomic.LoadUint64(&e.TotalDirs)), int64(atomic.LoadUint64(&e.TotalFiles))
+
+	if e.IsIgnored {
+		return dirs, files + 1
+	}
+
+	return dirs + 1, files
+}
+
+// adopt makes the current entry the child's parent. If the child still contains
+// the full path, only its last segment is kept.
+func (e *Entry) adopt(child *Entry) {
+	child.parent = e
+
+	if li := strings.LastIndexByte(child.name, os.PathSeparator); li != -1 {
+		child.name = strings.Clone(child.name[li+1:])
+	}
 }
 
 func (e *Entry) HasChild() bool {
-	return len(e.Child) != 0
+	return e.dirData != nil && len(e.Child) != 0
 }
 
 func (e *Entry) SortChild() *Entry {
+	return e.SortChildBy(ApparentSize)
+}
+
+// SortChildBy sorts the child entries in descending order by the size defined
+// by the provided SizeMode value. The entries are sorted under the lock, so the
+// directory can be sorted while it's still being scanned.
+func (e *Entry) SortChildBy(sm SizeMode) *Entry {
+	if e.dirData == nil {
+		return e
+	}
+
+	e.mx.Lock()
+	defer e.mx.Unlock()
+
 	slices.SortFunc(e.Child, func(a, b *Entry) int {
-		return cmp.Compare(b.Size, a.Size)
+		return cmp.Compare(b.SizeBy(sm), a.SizeBy(sm))
 	})
 
 	return e
 }
 
+// Copy creates a copy of the entry without its child entries. The copy has no
+// parent and keeps the full path as its name.
 func (e *Entry) Copy() *Entry {
-	return &Entry{
-		Path:       e.Path,
-		Child:      make([]*Entry, 0, len(e.Child)),
-		IsDir:      e.IsDir,
-		ModTime:    e.ModTime,
-		Size:       e.Size,
-		LocalDirs:  e.LocalDirs,
-		LocalFiles: e.LocalFiles,
-		TotalDirs:  e.TotalDirs,
-		TotalFiles: e.TotalFiles,
+	return e.copyAs(e.Path())
+}
+
+// clone creates a deep copy of the entry, including all its child entries. Like
+// Copy, the copy has no parent and keeps the full path as its name. The totals
+// are copied as they are rather than accumulated again.
+func (e *Entry) clone() *Entry {
+	c := e.copyAs(e.Path())
+	c.cloneChild(e)
+
+	return c
+}
+
+// cloneChild adds the deep copies of the source entry's child entries to the
+// current entry.
+func (e *Entry) cloneChild(src *Entry) {
+	if src.dirData == nil {
+		return
+	}
+
+	src.mx.RLock()
+	defer src.mx.RUnlock()
+
+	for _, child := range src.Child {
+		c := child.copyAs(child.name)
+		c.parent = e
+		c.cloneChild(child)
+
+		e.Child = append(e.Child, c)
 	}
 }
 
+// copyAs creates a copy of the entry with the provided name and without its
+// child entries.
+func (e *Entry) copyAs(name string) *Entry {
+	c := &Entry{
+		name:        name,
+		IsDir:       e.IsDir,
+		IsLink:      e.IsLink,
+		IsExcluded:  e.IsExcluded,
+		IsIgnored:   e.IsIgnored,
+		IsCollapsed: e.IsCollapsed,
+		ModTime:     e.ModTime,
+		Size:        e.Size,
+		Usage:       e.Usage,
+		Ino:         e.Ino,
+	}
+
+	if e.dirData != nil {
+		c.dirData = &dirData{
+			Child:      make([]*Entry, 0, len(e.Child)),
+			Target:     e.Target,
+			LocalDirs:  e.LocalDirs,
+			LocalFiles: e.LocalFiles,
+			TotalDirs:  e.TotalDirs,
+			TotalFiles: e.TotalFiles,
+		}
+	}
+
+	return c
+}
+
+// Diff compares the entry with its new state and returns all added, removed,
+// moved, and modified entries, including the ones within the child directories
+// present in both states. The entries are matched by their paths first, and the
+// remaining ones by their device and inode numbers; hence, a renamed or moved
+// entry is reported as moved rather than removed and added. The content of the
+// moved directories is compared as well.
 func (e *Entry) Diff(ne *Entry) Diff {
 	var ep EntryPair
 
-	d := Diff{Added: make([]*Entry, 0), Removed: make([]*Entry, 0)}
+	d := Diff{
+		Added:    make([]*Entry, 0),
+		Removed:  make([]*Entry, 0),
+		Moved:    make([]EntryPair, 0),
+		Modified: make([]EntryPair, 0),
+	}
 
 	queue := []EntryPair{{e, ne}}
 
 	for len(queue) > 0 {
-		ep, queue = queue[0], queue[1:]
+		for len(queue) > 0 {
+			ep, queue = queue[0], queue[1:]
 
-		if len(ep[0].Child) == 0 {
-			break
-		}
+			diff := diffChild(ep[0], ep[1])
 
-		diff := EntryList(ep[0].Child).Diff(ep[1].Child)
+			d.Added = append(d.Added, diff.Added...)
+			d.Removed = append(d.Removed, diff.Removed...)
+			d.Modified = append(d.Modified, diff.Modified...)
 
-		d.Added = append(d.Added, diff.Added...)
-		d.Removed = append(d.Removed, diff.Removed...)
+			for _, sameEntries := range diff.Same {
+				if sameEntries[0].IsDir {
+					queue = append(queue, sameEntries)
+				}
+			}
+		}
 
-		for _, sameEntries := range diff.Same {
-			if sameEntries[0].IsDir {
-				queue = append(queue, sameEntries)
+		// the moved directories might contain further changes, including the
+		// entries moved out of them.
+		for _, moved := range d.matchMoved() {
+			if moved[0].IsDir && moved[1].IsDir {
+				queue = append(queue, moved)
 			}
 		}
 	}
@@ -208,12 +639,64 @@ func (e *Entry) Diff(ne *Entry) Diff {
 	return d
 }
 
+// diffChild compares the child entries of the provided entries by their names.
+// Unlike EntryList.Diff, the lookups use the entries' name indexes, which are
+// built once and reused by the subsequent lookups. An entry replaced by an entry
+// of another type, e.g., a file replaced by a directory with the same name, is
+// reported as removed and added.
+func diffChild(oe, ne *Entry) Diff {
+	d := Diff{
+		Same:     make([]EntryPair, 0),
+		Added:    make([]*Entry, 0),
+		Removed:  make([]*Entry, 0),
+		Modified: make([]EntryPair, 0),
+	}
+
+	for newChild := range ne.Entries() {
+		oldChild := oe.GetChild(newChild.Name())
+
+		if oldChild != nil && oldChild.IsDir == newChild.IsDir {
+			d.addPair(oldChild, newChild)
+
+			continue
+		}
+
+		d.Added = append(d.Added, newChild)
+	}
+
+	for oldChild := range oe.Entries() {
+		newChild := ne.GetChild(oldChild.Name())
+
+		if newChild == nil || newChild.IsDir != oldChild.IsDir {
+			d.Removed = append(d.Removed, oldChild)
+		}
+	}
+
+	return d
+}
+
 type EntryPair [2]*Entry
 
+// Diff contains the differences between two states of the entries.
 type Diff struct {
-	Same    []EntryPair
-	Added   []*Entry
+	// Same contains the entries present in both states with the same path. The
+	// directories are always reported as the same ones, even if their content
+	// has changed, while the changed files are reported as modified.
+	Same []EntryPair
+
+	// Added contains the entries present only in the new state.
+	Added []*Entry
+
+	// Removed contains the entries present only in the old state.
 	Removed []*Entry
+
+	// Moved contains the entries that were renamed or moved to another
+	// directory. They are matched by their device and inode numbers.
+	Moved []EntryPair
+
+	// Modified contains the files present in both states with the same path,
+	// whose size or modification time has changed.
+	Modified []EntryPair
 }
 
 func (d *Diff) TotalAdded() int64 {
@@ -236,32 +719,246 @@ func (d *Diff) TotalRemoved() int64 {
 	return total
 }
 
+// MatchMoved pairs the added and removed entries with the entries found by
+// their device and inode numbers using the provided lookup functions. The
+// before function looks up the entry's previous state for an added entry, and
+// the after function looks up the current state for a removed entry. It allows
+// detecting the moves between the directories when only a part of the tree is
+// compared. Either function can be nil. The matched entries are moved to the
+// Moved list.
+func (d *Diff) MatchMoved(before, after func(drive.InoKey) *Entry) {
+	d.matchMoved()
+
+	if before != nil {
+		d.Added = slices.DeleteFunc(d.Added, func(added *Entry) bool {
+			old := lookupMoved(before, added)
+			if old != nil {
+				d.Moved = append(d.Moved, EntryPair{old, added})
+			}
+
+			return old != nil
+		})
+	}
+
+	if after != nil {
+		d.Removed = slices.DeleteFunc(d.Removed, func(removed *Entry) bool {
+			current := lookupMoved(after, removed)
+			if current != nil {
+				d.Moved = append(d.Moved, EntryPair{removed, current})
+			}
+
+			return current != nil
+		})
+	}
+}
+
+// matchMoved pairs the added and removed entries with the same device and inode
+// numbers, and moves them to the Moved list. The newly matched pairs are
+// returned.
+func (d *Diff) matchMoved() []EntryPair {
+	if len(d.Added) == 0 || len(d.Removed) == 0 {
+		return nil
+	}
+
+	removed := make(map[drive.InoKey]*Entry, len(d.Removed))
+
+	for _, r := range d.Removed {
+		if r.Ino != (drive.InoKey{}) {
+			removed[r.Ino] = r
+		}
+	}
+
+	if len(removed) == 0 {
+		return nil
+	}
+
+	var (
+		matched    = make([]EntryPair, 0)
+		matchedOld = make(map[*Entry]struct{})
+	)
+
+	d.Added = slices.DeleteFunc(d.Added, func(added *Entry) bool {
+		old, ok := removed[added.Ino]
+		if !ok || old.IsDir != added.IsDir {
+			return false
+		}
+
+		delete(removed, added.Ino)
+
+		matched = append(matched, EntryPair{old, added})
+		matchedOld[old] = struct{}{}
+
+		return true
+	})
+
+	if len(matched) == 0 {
+		return nil
+	}
+
+	d.Removed = slices.DeleteFunc(d.Removed, func(r *Entry) bool {
+		_, ok := matchedOld[r]
+
+		return ok
+	})
+
+	d.Moved = append(d.Moved, matched...)
+
+	return matched
+}
+
+// lookupMoved looks up the entry's counterpart by its device and inode numbers.
+// The counterpart must be of the same type and located at another path.
+func lookupMoved(lookup func(drive.InoKey) *Entry, e *Entry) *Entry {
+	if e.Ino == (drive.InoKey{}) {
+		return nil
+	}
+
+	c := lookup(e.Ino)
+	if c == nil || c.IsDir != e.IsDir || c.Path() == e.Path() {
+		return nil
+	}
+
+	return c
+}
+
+// addPair adds the entries present in both states to the Same or the Modified
+// list, respectively.
+func (d *Diff) addPair(oe, ne *Entry) {
+	if !oe.IsDir && !ne.IsDir && (oe.Size != ne.Size || oe.ModTime != ne.ModTime) {
+		d.Modified = append(d.Modified, EntryPair{oe, ne})
+
+		return
+	}
+
+	d.Same = append(d.Same, EntryPair{oe, ne})
+}
+
+// Changes returns the changes of all entries within the diff, including the
+// entries present in both states.
+func (d *Diff) Changes() []Change {
+	changes := make(
+		[]Change,
+		0,
+		len(d.Same)+len(d.Added)+len(d.Removed)+len(d.Moved)+len(d.Modified),
+	)
+
+	for _, pair := range d.Same {
+		changes = append(changes, Change{Old: pair[0], New: pair[1], Kind: ChangeNone})
+	}
+
+	for _, added := range d.Added {
+		changes = append(changes, Change{New: added, Kind: ChangeAdded})
+	}
+
+	for _, removed := range d.Removed {
+		changes = append(changes, Change{Old: removed, Kind: ChangeRemoved})
+	}
+
+	for _, pair := range d.Moved {
+		changes = append(changes, Change{Old: pair[0], New: pair[1], Kind: ChangeMoved})
+	}
+
+	for _, pair := range d.Modified {
+		changes = append(changes, Change{Old: pair[0], New: pair[1], Kind: ChangeModified})
+	}
+
+	return changes
+}
+
+// ChangeKind defines how a single entry has changed between two states of the
+// tree.
+type ChangeKind uint8
+
+const (
+	// ChangeNone defines an entry present in both states with the same path. A
+	// directory's content might have changed though.
+	ChangeNone ChangeKind = iota
+
+	// ChangeAdded defines an entry present only in the new state.
+	ChangeAdded
+
+	// ChangeRemoved defines an entry present only in the old state.
+	ChangeRemoved
+
+	// ChangeMoved defines an entry that was renamed or moved to another
+	// directory.
+	ChangeMoved
+
+	// ChangeModified defines a file whose size or modification time has changed.
+	ChangeModified
+)
+
+// Change describes how a single entry has changed between two states of the
+// tree. The Old entry is nil for the added entries, and the New entry is nil
+// for the removed ones.
+type Change struct {
+	Old  *Entry
+	New  *Entry
+	Kind ChangeKind
+}
+
+// Entry returns the entry's current state, or the previous one if the entry
+// was removed.
+func (c Change) Entry() *Entry {
+	if c.New != nil {
+		return c.New
+	}
+
+	return c.Old
+}
+
+// Delta returns the difference between the entry's current and previous sizes
+// defined by the provided SizeMode value.
+func (c Change) Delta(sm SizeMode) int64 {
+	var delta int64
+
+	if c.New != nil {
+		delta += c.New.SizeBy(sm)
+	}
+
+	if c.Old != nil {
+		delta -= c.Old.SizeBy(sm)
+	}
+
+	return delta
+}
+
+// Renamed reports whether the moved entry was renamed within the same
+// directory.
+func (c Change) Renamed() bool {
+	return c.Kind == ChangeMoved &&
+		filepath.Dir(c.Old.Path()) == filepath.Dir(c.New.Path())
+}
+
 type EntryList []*Entry
 
+// Diff compares the sibling entries with their new state. The entries are
+// matched by their names first, and the remaining ones by their device and
+// inode numbers, so the renamed entries are reported as moved. The child
+// directories' content is not compared.
 func (el EntryList) Diff(newList EntryList) Diff {
 	d := Diff{
-		Same:    make([]EntryPair, 0),
-		Added:   make([]*Entry, 0),
-		Removed: make([]*Entry, 0),
-	}
-
-	if len(newList) == 0 {
-		return d
+		Same:     make([]EntryPair, 0),
+		Added:    make([]*Entry, 0),
+		Removed:  make([]*Entry, 0),
+		Moved:    make([]EntryPair, 0),
+		Modified: make([]EntryPair, 0),
 	}
 
+	// the lists contain the sibling entries; hence, their names are unique
 	elMap := make(map[string]*Entry, len(el))
 
 	for _, entry := range el {
-		elMap[entry.Path] = entry
+		elMap[entry.Name()] = entry
 	}
 
 	for _, newChild := range newList {
-		oldChild, ok := elMap[newChild.Path]
+		oldChild, ok := elMap[newChild.Name()]
 
 		if ok && oldChild.IsDir == newChild.IsDir {
-			d.Same = append(d.Same, EntryPair{oldChild, newChild})
+			d.addPair(oldChild, newChild)
 
-			delete(elMap, newChild.Path)
+			delete(elMap, newChild.Name())
 
 			continue
 		}
@@ -273,5 +970,7 @@ func (el EntryList) Diff(newList EntryList) Diff {
 		d.Removed = append(d.Removed, removed)
 	}
 
+	d.matchMoved()
+
 	return d
 }
@@ -0,0 +1,196 @@
+//go:build linux || darwin
+
+package structure_test
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+
+	"github.com/crumbyte/noxdir/structure"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	benchDirs    = 20
+	benchSubDirs = 20
+	benchFiles   = 250
+
+	// memoryVariantEnv defines the tree built by TestEntryMemoryVariant. It's
+	// set only for the child processes started by BenchmarkEntryMemory.
+	memoryVariantEnv = "NOXDIR_ENTRY_MEMORY_VARIANT"
+	maxRSSPrefix     = "maxrss="
+)
+
+// legacyEntry replicates the Entry layout of the baseline, i.e., before the
+// tree was redesigned: each entry kept its full path, the mutex, and all
+// counters, including the files.
+type legacyEntry struct {
+	Path       string
+	Child      []*legacyEntry
+	mx         sync.RWMutex
+	ModTime    int64
+	Size       int64
+	LocalDirs  uint64
+	LocalFiles uint64
+	TotalDirs  uint64
+	TotalFiles uint64
+	IsDir      bool
+}
+
+func newLegacyDirEntry(path string) *legacyEntry {
+	return &legacyEntry{Path: path, Child: make([]*legacyEntry, 0), IsDir: true}
+}
+
+func (e *legacyEntry) addChild(child *legacyEntry) {
+	e.mx.Lock()
+	defer e.mx.Unlock()
+
+	if e.Child == nil {
+		e.Child = make([]*legacyEntry, 0, 10)
+	}
+
+	e.Child = append(e.Child, child)
+
+	if child.IsDir {
+		e.TotalDirs, e.LocalDirs = e.TotalDirs+1, e.LocalDirs+1
+
+		return
+	}
+
+	e.TotalFiles, e.LocalFiles = e.TotalFiles+1, e.LocalFiles+1
+}
+
+// BenchmarkEntryMemory compares the peak RSS of the processes that build a
+// synthetic tree of 100k files with the baseline and the current Entry layouts.
+// Each tree is built by a separate process running TestEntryMemoryVariant, so
+// the measurements don't affect each other. The "empty" variant shows the RSS
+// of the test process itself.
+func BenchmarkEntryMemory(b *testing.B) {
+	for _, variant := range []string{"empty", "legacy", "compact"} {
+		b.Run(variant, func(b *testing.B) {
+			var peak int64
+
+			for range b.N {
+				peak = max(peak, variantMaxRSS(b, variant))
+			}
+
+			entries := benchDirs * benchSubDirs * (benchFiles + 1)
+
+			b.ReportMetric(float64(peak)/(1<<20), "peak-RSS-MB")
+			b.ReportMetric(float64(peak)/float64(entries), "RSS-B/entry")
+		})
+	}
+}
+
+// TestEntryMemoryVariant builds the tree defined by the memoryVariantEnv value
+// and prints the peak RSS of the process. It's skipped unless it's started by
+// BenchmarkEntryMemory.
+func TestEntryMemoryVariant(t *testing.T) {
+	variant := os.Getenv(memoryVariantEnv)
+	if len(variant) == 0 {
+		t.Skip("started by BenchmarkEntryMemory only")
+	}
+
+	var tree any
+
+	switch variant {
+	case "legacy":
+		tree = buildLegacyTree()
+	case "compact":
+		tree = buildCompactTree()
+	case "empty":
+	default:
+		t.Fatalf("unknown variant: %s", variant)
+	}
+
+	var ru syscall.Rusage
+
+	require.NoError(t, syscall.Getrusage(syscall.RUSAGE_SELF, &ru))
+	runtime.KeepAlive(tree)
+
+	// the peak RSS is reported in kilobytes on linux and in bytes on darwin
+	maxRSS := int64(ru.Maxrss)
+	if runtime.GOOS == "linux" {
+		maxRSS *= 1024
+	}
+
+	fmt.Printf("%s%d\n", maxRSSPrefix, maxRSS)
+}
+
+func variantMaxRSS(b *testing.B, variant string) int64 {
+	b.Helper()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestEntryMemoryVariant$", "-test.v")
+	cmd.Env = append(os.Environ(), memoryVariantEnv+"="+variant)
+
+	out, err := cmd.CombinedOutput()
+	require.NoError(b, err, string(out))
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(scanner.Text(), maxRSSPrefix); ok {
+			maxRSS, err := strconv.ParseInt(value, 10, 64)
+			require.NoError(b, err)
+
+			return maxRSS
+		}
+	}
+
+	b.Fatalf("no peak RSS reported by the %s variant:\n%s", variant, out)
+
+	return 0
+}
+
+func buildLegacyTree() *legacyEntry {
+	root := newLegacyDirEntry(benchRootPath)
+
+	for i := range benchDirs {
+		dir := newLegacyDirEntry(root.Path + "/dir_" + strconv.Itoa(i))
+		root.addChild(dir)
+
+		for j := range benchSubDirs {
+			sub := newLegacyDirEntry(dir.Path + "/sub_" + strconv.Itoa(j))
+			dir.addChild(sub)
+
+			for k := range benchFiles {
+				sub.addChild(&legacyEntry{
+					Path: sub.Path + "/file_" + strconv.Itoa(k) + ".dat",
+					Size: int64(k),
+				})
+			}
+		}
+	}
+
+	return root
+}
+
+func buildCompactTree() *structure.Entry {
+	root := structure.NewDirEntry(benchRootPath, 0)
+
+	for i := range benchDirs {
+		dir := structure.NewDirEntry("dir_"+strconv.Itoa(i), 0)
+		root.AddChild(dir)
+
+		for j := range benchSubDirs {
+			sub := structure.NewDirEntry("sub_"+strconv.Itoa(j), 0)
+			dir.AddChild(sub)
+
+			for k := range benchFiles {
+				sub.AddChild(structure.NewFileEntry("file_"+strconv.Itoa(k)+".dat", int64(k), 0))
+			}
+		}
+	}
+
+	return root
+}
@@ -0,0 +1,183 @@
+package structure_test
+
+import (
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/crumbyte/noxdir/structure"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	benchRootPath = "/mnt/filer/synthetic"
+	benchHugeDir  = 1_000_000
+)
+
+func TestEntry_Path(t *testing.T) {
+	sep := string(os.PathSeparator)
+
+	root := structure.NewDirEntry(sep+"root", 0)
+	dir := structure.NewDirEntry(sep+"root"+sep+"dir", 0)
+	file := structure.NewFileEntry("file.txt", 10, 0)
+
+	root.AddChild(dir)
+	dir.AddChild(file)
+
+	require.Equal(t, "dir", dir.Name())
+	require.Equal(t, sep+"root"+sep+"dir"+sep+"file.txt", file.Path())
+	require.Equal(t, "file.txt", file.Name())
+	require.Equal(t, "txt", file.Ext())
+	require.Equal(t, dir, file.Parent())
+	require.Nil(t, file.GetChild("any"))
+	require.False(t, file.HasChild())
+
+	group := structure.NewDirEntry(structure.IgnoredGroupName, 0)
+	group.IsIgnored = true
+
+	ignored := structure.NewFileEntry("ignored.log", 10, 0)
+
+	dir.AddChild(group)
+	group.AddChild(ignored)
+
+	// the synthetic group is not a part of the path
+	require.Equal(t, sep+"root"+sep+"dir"+sep+"ignored.log", ignored.Path())
+}
+
+func TestEntry_FileAccessors(t *testing.T) {
+	dir := structure.NewDirEntry("dir", 0)
+	file := structure.NewFileEntry("file.txt", 10, 0)
+
+	dir.AddChild(file)
+
+	// the file entries have no directory data, but the accessors are safe
+	dirs, files := file.Totals()
+	require.Zero(t, dirs)
+	require.Zero(t, files)
+
+	dirs, files = file.LocalCounts()
+	require.Zero(t, dirs)
+	require.Zero(t, files)
+
+	require.Zero(t, file.ChildCount())
+	require.Empty(t, file.LinkTarget())
+
+	dirs, files = dir.LocalCounts()
+	require.Zero(t, dirs)
+	require.EqualValues(t, 1, files)
+	require.Equal(t, 1, dir.ChildCount())
+
+	link := structure.NewLinkEntry("link", "target", 0)
+	require.Equal(t, "target", link.LinkTarget())
+}
+
+func TestEntry_GetChildIndex(t *testing.T) {
+	dir := structure.NewDirEntry("dir", 0)
+
+	for i := range 100 {
+		dir.AddChild(structure.NewFileEntry("file_"+strconv.Itoa(i), 1, 0))
+	}
+
+	// the first lookup builds the index
+	require.NotNil(t, dir.GetChild("file_10"))
+	require.Nil(t, dir.GetChild("file_100"))
+
+	dir.AddChild(structure.NewFileEntry("file_100", 1, 0))
+	require.NotNil(t, dir.GetChild("file_100"))
+
+	require.True(t, dir.RemoveChild(dir.GetChild("file_10")))
+	require.False(t, dir.RemoveChild(structure.NewFileEntry("file_10", 1, 0)))
+	require.Nil(t, dir.GetChild("file_10"))
+	require.EqualValues(t, 100, dir.Size)
+	require.EqualValues(t, 100, dir.LocalFiles)
+	require.EqualValues(t, 100, dir.TotalFiles)
+
+	dir.ClearChild()
+	require.Nil(t, dir.GetChild("file_20"))
+	require.EqualValues(t, 0, dir.TotalFiles)
+}
+
+// TestEntry_SortChildByConcurrent sorts and iterates the directory while its
+// content is still being added, like the UI does during the progressive scan.
+// It's meant to be run with the race detector.
+func TestEntry_SortChildByConcurrent(t *testing.T) {
+	const filesNumber = 1000
+
+	root := structure.NewDirEntry("root", 0)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		for i := range filesNumber {
+			root.AddChild(structure.NewFileEntry(strconv.Itoa(i), int64(i), 0))
+		}
+	}()
+
+	for {
+		root.SortChildBy(structure.ApparentSize)
+
+		for child := range root.Entries() {
+			_ = child.SizeBy(structure.ApparentSize)
+		}
+
+		select {
+		case <-done:
+			root.SortChildBy(structure.ApparentSize)
+
+			require.Len(t, root.Child, filesNumber)
+			require.EqualValues(t, filesNumber-1, root.Child[0].Size)
+
+			return
+		default:
+		}
+	}
+}
+
+// BenchmarkEntry_GetChild looks up the child entries of a directory with one
+// million files, e.g., a mail spool.
+func BenchmarkEntry_GetChild(b *testing.B) {
+	dir := buildHugeDir()
+	names := []string{"file_0", "file_499999", "file_999999", "missing"}
+
+	b.ResetTimer()
+
+	for i := range b.N {
+		dir.GetChild(names[i%len(names)])
+	}
+}
+
+// BenchmarkEntry_Diff compares two snapshots of a directory with one million
+// files, where a thousand files were replaced.
+func BenchmarkEntry_Diff(b *testing.B) {
+	oldDir, newDir := buildHugeDir(), structure.NewDirEntry(benchRootPath, 0)
+
+	for i := range benchHugeDir {
+		name := "file_" + strconv.Itoa(i)
+		if i%1000 == 0 {
+			name = "new_" + strconv.Itoa(i)
+		}
+
+		newDir.AddChild(structure.NewFileEntry(name, 1, 0))
+	}
+
+	b.ResetTimer()
+
+	for range b.N {
+		d := oldDir.Diff(newDir)
+
+		require.Len(b, d.Added, benchHugeDir/1000)
+		require.Len(b, d.Removed, benchHugeDir/1000)
+	}
+}
+
+func buildHugeDir() *structure.Entry {
+	dir := structure.NewDirEntry(benchRootPath, 0)
+
+	for i := range benchHugeDir {
+		dir.AddChild(structure.NewFileEntry("file_"+strconv.Itoa(i), 1, 0))
+	}
+
+	return dir
+}
@@ -0,0 +1,108 @@
+package structure
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DirGrowth contains the growth rate of a single directory.
+type DirGrowth struct {
+	// Path contains the full path of the directory.
+	Path string
+
+	// BytesPerMinute contains the average change of the directory's size per
+	// minute within the tracking window.
+	BytesPerMinute int64
+}
+
+type growthSample struct {
+	at    int64
+	delta int64
+}
+
+// GrowthTracker records the size changes of the directories and ranks them by
+// their growth rate within the sliding time window. The changes are aggregated
+// by seconds, so frequent changes of the same directory do not increase the
+// memory usage. It's safe for concurrent use.
+type GrowthTracker struct {
+	dirs   map[string][]growthSample
+	window time.Duration
+	mx     sync.Mutex
+}
+
+// NewGrowthTracker creates a new GrowthTracker instance that calculates the
+// growth rate within the provided time window.
+func NewGrowthTracker(window time.Duration) *GrowthTracker {
+	return &GrowthTracker{
+		dirs:   make(map[string][]growthSample),
+		window: max(window, time.Second),
+	}
+}
+
+// Record registers the directory's size change that happened at the provided
+// time.
+func (gt *GrowthTracker) Record(path string, delta int64, at time.Time) {
+	if delta == 0 {
+		return
+	}
+
+	gt.mx.Lock()
+	defer gt.mx.Unlock()
+
+	samples, sec := gt.dirs[path], at.Unix()
+
+	if len(samples) > 0 && samples[len(samples)-1].at == sec {
+		samples[len(samples)-1].delta += delta
+
+		return
+	}
+
+	gt.dirs[path] = append(samples, growthSample{at: sec, delta: delta})
+}
+
+// Top returns up to the provided number of directories with the highest growth
+// rate at the provided time. Only the growing directories are included. The
+// samples outside the tracking window are discarded.
+func (gt *GrowthTracker) Top(n int, at time.Time) []DirGrowth {
+	gt.mx.Lock()
+	defer gt.mx.Unlock()
+
+	since := at.Add(-gt.window).Unix()
+	top := make([]DirGrowth, 0, n)
+
+	for path, samples := range gt.dirs {
+		idx := slices.IndexFunc(samples, func(s growthSample) bool {
+			return s.at > since
+		})
+
+		if idx == -1 {
+			delete(gt.dirs, path)
+
+			continue
+		}
+
+		samples = samples[idx:]
+		gt.dirs[path] = samples
+
+		var total int64
+
+		for _, s := range samples {
+			total += s.delta
+		}
+
+		if total > 0 {
+			top = append(top, DirGrowth{
+				Path:           path,
+				BytesPerMinute: total * int64(time.Minute) / int64(gt.window),
+			})
+		}
+	}
+
+	slices.SortFunc(top, func(a, b DirGrowth) int {
+		return cmp.Compare(b.BytesPerMinute, a.BytesPerMinute)
+	})
+
+	return top[:min(n, len(top))]
+}
@@ -0,0 +1,33 @@
+package structure_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/crumbyte/noxdir/structure"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGrowthTracker_Top(t *testing.T) {
+	gt := structure.NewGrowthTracker(time.Minute)
+	now := time.Now()
+
+	gt.Record("/a", 100, now.Add(-time.Second*30))
+	gt.Record("/a", 100, now)
+	gt.Record("/b", 1000, now)
+	gt.Record("/c", -500, now)
+	gt.Record("/d", 5000, now.Add(-time.Minute*2))
+
+	require.Equal(
+		t,
+		[]structure.DirGrowth{
+			{Path: "/b", BytesPerMinute: 1000},
+			{Path: "/a", BytesPerMinute: 200},
+		},
+		gt.Top(5, now),
+	)
+
+	require.Len(t, gt.Top(1, now), 1)
+	require.Empty(t, gt.Top(5, now.Add(time.Minute*2)))
+}
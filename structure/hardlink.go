@@ -0,0 +1,115 @@
+package structure
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+
+	"github.com/crumbyte/noxdir/drive"
+)
+
+// HardlinkMode defines how the size of a file with multiple hard links is
+// accounted during the tree traversal.
+type HardlinkMode uint8
+
+const (
+	// HardlinkOnce counts the file only once, for the first found link. All
+	// other links are skipped and do not appear in the tree.
+	HardlinkOnce HardlinkMode = iota
+
+	// HardlinkAll counts the full file size for every found link. The total
+	// size of the parent directories may exceed the actual disk usage.
+	HardlinkAll
+
+	// HardlinkSplit splits the file size evenly between all its links, so each
+	// link holds its share of the file size.
+	HardlinkSplit
+)
+
+// HardLink contains the information about a single file that has more than one
+// hard link. All links share the same data on the disk.
+type HardLink struct {
+	// Paths contains the paths of all links found during the traversal. It may
+	// contain fewer items than Links if some links are outside the scanned tree.
+	Paths []string
+
+	// Size contains the apparent size of the file data shared by all links.
+	Size int64
+
+	// Usage contains the allocated size of the file data shared by all links.
+	Usage int64
+
+	// Links contains the total number of hard links reported by the file system.
+	Links uint64
+
+	// Ino contains the device and inode numbers shared by all links.
+	Ino drive.InoKey
+}
+
+// SizeBy returns either the apparent size or the allocated size of the shared
+// data depending on the provided SizeMode value.
+func (hl *HardLink) SizeBy(sm SizeMode) int64 {
+	if sm == DiskUsage {
+		return hl.Usage
+	}
+
+	return hl.Size
+}
+
+// hardLinks registers files with multiple hard links found during a single tree
+// traversal. The files are identified by the device and inode numbers, so the
+// same inode numbers on different devices do not collide.
+type hardLinks struct {
+	links map[drive.InoKey]*HardLink
+	mx    sync.Mutex
+}
+
+func newHardLinks() *hardLinks {
+	return &hardLinks{links: make(map[drive.InoKey]*HardLink)}
+}
+
+// add registers a new link to the file and returns "true" if it's the first
+// found link to that file. The link that is already registered is not added
+// again, e.g., when the watched file is modified.
+func (hl *hardLinks) add(fi drive.FileInfo, path string) bool {
+	hl.mx.Lock()
+	defer hl.mx.Unlock()
+
+	link, ok := hl.links[fi.InoKey()]
+	if ok {
+		if idx := slices.Index(link.Paths, path); idx != -1 {
+			return idx == 0
+		}
+	} else {
+		link = &HardLink{
+			Size:  fi.Size(),
+			Usage: fi.Usage(),
+			Links: fi.Links(),
+			Ino:   fi.InoKey(),
+		}
+
+		hl.links[fi.InoKey()] = link
+	}
+
+	link.Paths = append(link.Paths, path)
+
+	return !ok
+}
+
+// list returns all registered files sorted by their size in descending order.
+func (hl *hardLinks) list() []*HardLink {
+	hl.mx.Lock()
+	defer hl.mx.Unlock()
+
+	list := make([]*HardLink, 0, len(hl.links))
+
+	for _, link := range hl.links {
+		list = append(list, link)
+	}
+
+	slices.SortFunc(list, func(a, b *HardLink) int {
+		return cmp.Compare(b.Size, a.Size)
+	})
+
+	return list
+}
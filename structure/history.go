@@ -0,0 +1,90 @@
+package structure
+
+import (
+	"errors"
+
+	"github.com/crumbyte/noxdir/drive"
+	"github.com/crumbyte/noxdir/pkg/cache"
+)
+
+// Snapshots returns the snapshots of the tree's root kept in the cache history,
+// newest first. The snapshots that cannot be read are skipped.
+func (t *Tree) Snapshots() ([]cache.File, error) {
+	if t.cache == nil || t.root == nil {
+		return nil, nil
+	}
+
+	history, err := t.cache.History(t.cache.FileName(cacheKey(t.root.Path())))
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := make([]cache.File, 0, len(history))
+
+	for _, s := range history {
+		if s.Err == nil {
+			snapshots = append(snapshots, s)
+		}
+	}
+
+	return snapshots, nil
+}
+
+// LoadSnapshot restores the provided snapshot of the tree's root. The returned
+// entry is the root of the snapshot's tree, which is not connected to the
+// current tree in any way.
+func (t *Tree) LoadSnapshot(s cache.File) (*Entry, error) {
+	if t.cache == nil || t.root == nil {
+		return nil, errors.New("cache is disabled")
+	}
+
+	path := t.root.Path()
+	root := NewDirEntry(path, 0)
+
+	if err := t.cache.GetSnapshot(cacheKey(path), s.Name, root); err != nil {
+		return nil, err
+	}
+
+	return root, nil
+}
+
+// InoIndex maps the device and inode numbers to the entries of a single tree.
+// It allows finding the entry's previous or current location once it was moved.
+type InoIndex map[drive.InoKey]*Entry
+
+// NewInoIndex indexes all entries of the provided tree. The hard-linked files
+// share the same numbers for different paths; hence, they're not indexed. The
+// same applies to the entries without the device and inode numbers.
+func NewInoIndex(root *Entry) InoIndex {
+	ii, linked := make(InoIndex), make(map[drive.InoKey]struct{})
+	stack := []*Entry{root}
+
+	for len(stack) > 0 {
+		e := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if e.Ino != (drive.InoKey{}) {
+			if _, ok := ii[e.Ino]; ok {
+				linked[e.Ino] = struct{}{}
+			}
+
+			ii[e.Ino] = e
+		}
+
+		for child := range e.Entries() {
+			stack = append(stack, child)
+		}
+	}
+
+	for key := range linked {
+		delete(ii, key)
+	}
+
+	return ii
+}
+
+// Lookup returns the entry with the provided device and inode numbers, or nil if
+// there is no such entry.
+func (ii InoIndex) Lookup(key drive.InoKey) *Entry {
+	return ii[key]
+}
@@ -0,0 +1,70 @@
+package structure_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/crumbyte/noxdir/pkg/cache"
+	"github.com/crumbyte/noxdir/structure"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTree_Snapshots(t *testing.T) {
+	root, cacheDir := t.TempDir(), t.TempDir()
+
+	c, err := cache.NewCache(
+		func(w io.Writer) cache.Encoder { return structure.NewEncoder(w) },
+		func(r io.Reader) cache.Decoder { return structure.NewDecoder(r) },
+		false,
+		cache.WithCacheDir(cacheDir),
+		cache.WithHistory(5, 0),
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "a"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a", "grown"), make([]byte, 100), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "removed"), make([]byte, 50), 0600))
+
+	tree := structure.NewTree(structure.NewDirEntry(root, 0), structure.WithCache(c))
+
+	require.NoError(t, tree.Traverse(context.Background(), true))
+	require.NoError(t, tree.PersistCache())
+
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a", "grown"), make([]byte, 300), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "added"), make([]byte, 10), 0600))
+	require.NoError(t, os.Remove(filepath.Join(root, "removed")))
+
+	tree.SetRoot(structure.NewDirEntry(root, 0))
+
+	require.NoError(t, tree.Traverse(context.Background(), true))
+	require.NoError(t, tree.PersistCache())
+
+	snapshots, err := tree.Snapshots()
+	require.NoError(t, err)
+	require.Len(t, snapshots, 2)
+
+	baseline, err := tree.LoadSnapshot(snapshots[1])
+	require.NoError(t, err)
+	require.EqualValues(t, 150, baseline.Size)
+
+	diff := structure.EntryList(baseline.Child).Diff(tree.Root().Child)
+
+	deltas := make(map[string]int64)
+
+	for _, change := range diff.Changes() {
+		deltas[change.Entry().Name()] = change.Delta(structure.ApparentSize)
+	}
+
+	require.Equal(t, map[string]int64{"a": 200, "added": 10, "removed": -50}, deltas)
+	require.Len(t, diff.Added, 1)
+	require.Len(t, diff.Removed, 1)
+
+	// the baseline keeps the nested directories, so they can be compared too
+	nested := structure.NewTree(baseline).Lookup(filepath.Join(root, "a"))
+	require.NotNil(t, nested)
+	require.EqualValues(t, 100, nested.Size)
+}
@@ -0,0 +1,210 @@
+package structure
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/crumbyte/noxdir/drive"
+	"github.com/crumbyte/noxdir/pkg/exclude"
+)
+
+// IgnoreMode defines how the entries matched by the ignore files, e.g.,
+// ".gitignore", are handled during the tree traversal.
+type IgnoreMode uint8
+
+const (
+	// IgnoreNone disables the ignore files. All entries are scanned as usual.
+	IgnoreNone IgnoreMode = iota
+
+	// IgnoreSkip skips the ignored entries completely. They do not appear in
+	// the tree and their size is not counted.
+	IgnoreSkip
+
+	// IgnoreGroup groups the ignored entries of each directory under a single
+	// synthetic child entry. The ignored entries are still scanned, so their
+	// size stays visible, but they do not clutter the directory content.
+	IgnoreGroup
+)
+
+// IgnoredGroupName defines the name of the synthetic entry that groups all
+// ignored entries of a directory when the IgnoreGroup mode is used.
+const IgnoredGroupName = "[ignored]"
+
+// ignoreFiles contains the names of the ignore files read in each directory. The
+// files are applied in the provided order, so the rules from the latter ones
+// take precedence.
+var ignoreFiles = []string{".gitignore", ".ignore", ".noxdirignore"}
+
+// ignoreScope contains the rules of a single ignore file along with the rules
+// defined by the ignore files in the parent directories. The rules are matched
+// against the path relative to the directory where the ignore file is located.
+type ignoreScope struct {
+	parent  *ignoreScope
+	matcher *exclude.Matcher
+	base    string
+}
+
+// disabledScope marks the directories within the ignored entries group. The
+// ignore files are not read there, since all entries are ignored anyway.
+var disabledScope = &ignoreScope{}
+
+// match checks the path against the rules starting from the deepest ignore
+// file. The first ignore file that has a matching rule defines the result, so
+// the nested files can re-include the paths ignored by the parent ones.
+func (is *ignoreScope) match(path string, isDir bool) bool {
+	for s := is; s != nil; s = s.parent {
+		if s.matcher == nil {
+			continue
+		}
+
+		relPath := strings.TrimLeft(path[len(s.base):], `/\`)
+
+		if excluded, ok := s.matcher.MatchResult(relPath, isDir); ok {
+			return excluded
+		}
+	}
+
+	return false
+}
+
+// resolveIgnoreScope resolves the ignore rules for the provided directory. The rules
+// inherited from the parent directories are extended with the ignore files
+// the directory contains according to the provided hasFile function. A nil
+// value will be returned if the ignore files are disabled or there are no rules
+// to apply.
+func (t *Tree) resolveIgnoreScope(e *Entry, hasFile func(string) bool, onErr func(error)) *ignoreScope {
+	if t.ignoreMode == IgnoreNone {
+		return nil
+	}
+
+	var scope *ignoreScope
+
+	if v, ok := t.ignoreScopes.LoadAndDelete(e); ok {
+		scope, _ = v.(*ignoreScope)
+	}
+
+	// the root of a subtree inherits the rules of the directories above it,
+	// since they are not traversed again.
+	if e == t.root && scope == nil {
+		scope = ancestorsIgnoreScope(e, onErr)
+	}
+
+	if scope == disabledScope {
+		return scope
+	}
+
+	return extendIgnoreScope(scope, e.Path(), hasFile, onErr)
+}
+
+// ancestorsIgnoreScope resolves the ignore rules defined by the ignore files in
+// the parent directories of the provided entry. The files are read from the
+// disk, since the tree may not contain them, e.g., if they were filtered out.
+func ancestorsIgnoreScope(e *Entry, onErr func(error)) *ignoreScope {
+	var ancestors []*Entry
+
+	for p := e.parent; p != nil; p = p.parent {
+		if p.IsIgnored {
+			return disabledScope
+		}
+
+		ancestors = append(ancestors, p)
+	}
+
+	var scope *ignoreScope
+
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		dir := ancestors[i].Path()
+
+		hasFile := func(name string) bool {
+			fi, err := os.Stat(filepath.Join(dir, name))
+
+			return err == nil && !fi.IsDir()
+		}
+
+		scope = extendIgnoreScope(scope, dir, hasFile, onErr)
+	}
+
+	return scope
+}
+
+// extendIgnoreScope extends the provided scope with the rules of the ignore
+// files located in the dir directory.
+func extendIgnoreScope(
+	scope *ignoreScope,
+	dir string,
+	hasFile func(string) bool,
+	onErr func(error),
+) *ignoreScope {
+	for _, name := range ignoreFiles {
+		if !hasFile(name) {
+			continue
+		}
+
+		m, err := readIgnoreFile(
+			filepath.Join(dir, name),
+			exclude.WithRegexp(name == ".noxdirignore"),
+		)
+		if err != nil {
+			onErr(err)
+
+			continue
+		}
+
+		if !m.Empty() {
+			scope = &ignoreScope{parent: scope, matcher: m, base: dir}
+		}
+	}
+
+	return scope
+}
+
+// ignoredGroup returns the synthetic entry that groups the ignored entries of
+// the provided directory. The entry will be created and added to the directory
+// if it does not exist yet.
+func ignoredGroup(e *Entry, group *Entry) *Entry {
+	if group != nil {
+		return group
+	}
+
+	group = NewDirEntry(IgnoredGroupName, e.ModTime)
+	group.IsIgnored = true
+
+	e.AddChild(group)
+
+	return group
+}
+
+func readIgnoreFile(path string, opts ...exclude.Option) (*exclude.Matcher, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open ignore file: %w", err)
+	}
+
+	defer func() {
+		_ = f.Close()
+	}()
+
+	patterns, err := exclude.ReadPatterns(f)
+	if err != nil {
+		return nil, fmt.Errorf("read ignore file %s: %w", path, err)
+	}
+
+	m, err := exclude.New(patterns, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("parse ignore file %s: %w", path, err)
+	}
+
+	return m, nil
+}
+
+func containsFile(entries []drive.FileInfo, name string) bool {
+	for _, fi := range entries {
+		if !fi.IsDir() && fi.Name() == name {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,182 @@
+package structure
+
+import (
+	"context"
+
+	"github.com/crumbyte/noxdir/drive"
+)
+
+// RescanStats contains the statistics of the last incremental traversal.
+type RescanStats struct {
+	// Reused contains the number of directories whose content was reused from
+	// the existing tree, since their modification time did not change.
+	Reused uint64
+
+	// Reread contains the number of directories whose content was read from the
+	// file system, including the new and the changed ones.
+	Reread uint64
+}
+
+// TraverseIncremental refreshes the existing tree, e.g., restored from the
+// cache, instead of building it from scratch. The directories are still walked
+// concurrently, but only the directories whose modification time has changed
+// since the previous traversal are read from the file system. The content of
+// the unchanged directories is reused as is.
+//
+// The directory's modification time changes only when its entries are added,
+// removed, or renamed. Hence, the changed size of the existing files within
+// the reused directories is not detected. Also, the modification time has a
+// one-second precision, and the hard links within the reused directories are
+// not registered.
+//
+// The function does not block and returns the same channels as TraverseAsync.
+// The number of reused and read directories is available via RescanStats.
+func (t *Tree) TraverseIncremental(ctx context.Context) (chan struct{}, chan error) {
+	t.resetTraversal()
+
+	if t.root == nil || !t.root.IsDir {
+		return nil, nil
+	}
+
+	t.incremental = true
+	t.cachedDirs.Store(t.root, struct{}{})
+
+	done, errChan := make(chan struct{}), make(chan error, 1)
+
+	go t.traverseAsync(ctx, done, errChan)
+
+	return done, errChan
+}
+
+// RescanStats returns the number of reused and read directories during the last
+// incremental traversal.
+func (t *Tree) RescanStats() RescanStats {
+	return RescanStats{
+		Reused: t.reusedDirs.Load(),
+		Reread: t.rereadDirs.Load(),
+	}
+}
+
+// visit handles the directory taken from the traversal queue. During the
+// incremental traversal, the directories that already have content are
+// validated by their modification time first.
+func (t *Tree) visit(e *Entry, onNewDir func(*Entry), onErr func(error)) {
+	if !t.incremental {
+		t.handleEntry(e, onNewDir, onErr)
+
+		return
+	}
+
+	if _, ok := t.cachedDirs.LoadAndDelete(e); !ok {
+		t.rereadDirs.Add(1)
+		t.handleEntry(e, onNewDir, onErr)
+
+		return
+	}
+
+	fi, err := drive.Stat(e.Path())
+	if err == nil && fi.ModTime() == e.ModTime && !e.IsCollapsed {
+		t.reusedDirs.Add(1)
+		t.reuseEntry(e, fi, onNewDir, onErr)
+
+		return
+	}
+
+	t.rereadDirs.Add(1)
+
+	if err == nil {
+		e.ModTime = fi.ModTime()
+	}
+
+	t.rereadEntry(e, onNewDir, onErr)
+}
+
+// reuseEntry keeps the directory's content and queues its child directories for
+// the validation, since they might have changed independently.
+func (t *Tree) reuseEntry(e *Entry, fi drive.FileInfo, onNewDir func(*Entry), onErr func(error)) {
+	if t.followSymlinks {
+		t.visitedDirs.add(fi.InoKey())
+	}
+
+	scope := t.resolveIgnoreScope(
+		e,
+		func(name string) bool {
+			child := e.GetChild(name)
+
+			return child != nil && !child.IsDir
+		},
+		onErr,
+	)
+
+	for _, child := range e.Child {
+		switch {
+		case !child.IsDir || child.IsExcluded:
+			continue
+		case child.IsIgnored:
+			for _, ignored := range child.Child {
+				if ignored.IsDir && !ignored.IsExcluded {
+					t.reuseDir(ignored, disabledScope, onNewDir)
+				}
+			}
+		default:
+			t.reuseDir(child, scope, onNewDir)
+		}
+	}
+}
+
+func (t *Tree) reuseDir(e *Entry, scope *ignoreScope, onNewDir func(*Entry)) {
+	t.cachedDirs.Store(e, struct{}{})
+
+	if scope != nil {
+		t.ignoreScopes.Store(e, scope)
+	}
+
+	onNewDir(e)
+}
+
+// rereadEntry reads the changed directory's content from scratch. The child
+// directories that existed before keep their content, which will be validated
+// separately.
+func (t *Tree) rereadEntry(e *Entry, onNewDir func(*Entry), onErr func(error)) {
+	cached := make(map[string]*Entry)
+
+	// the ignored entries are located in the same directory; hence, all
+	// names are unique.
+	for _, child := range e.Child {
+		if child.IsIgnored {
+			for _, ignored := range child.Child {
+				cached[ignored.Name()] = ignored
+			}
+
+			continue
+		}
+
+		cached[child.Name()] = child
+	}
+
+	e.ClearChild()
+	e.IsCollapsed = false
+
+	t.handleEntry(
+		e,
+		func(newDir *Entry) {
+			old, ok := cached[newDir.Name()]
+			if ok && old.IsDir && !old.IsExcluded && !old.IsCollapsed {
+				newDir.Child, newDir.ModTime = old.Child, old.ModTime
+				newDir.LocalDirs, newDir.LocalFiles = old.LocalDirs, old.LocalFiles
+
+				for _, child := range newDir.Child {
+					child.parent = newDir
+				}
+
+				//nolint:gosec // the totals always fit
+				newDir.grow(old.Size, old.Usage, int64(old.TotalDirs), int64(old.TotalFiles))
+
+				t.cachedDirs.Store(newDir, struct{}{})
+			}
+
+			onNewDir(newDir)
+		},
+		onErr,
+	)
+}
@@ -0,0 +1,142 @@
+package structure
+
+import (
+	"errors"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/crumbyte/noxdir/pkg/cache"
+)
+
+// TrackRefresh records that the content of the provided directory was scanned
+// again, e.g., by a subtree refresh. Once the tree is persisted, the refreshed
+// content is merged into the other cached trees that contain the directory,
+// e.g., the cached drive tree when the tree's root is a directory on that drive.
+// Hence, the cached trees stay accurate without scanning them again.
+func (t *Tree) TrackRefresh(e *Entry) {
+	if t.cache == nil || e == nil || !e.IsDir {
+		return
+	}
+
+	t.refreshMx.Lock()
+	defer t.refreshMx.Unlock()
+
+	if t.refreshed == nil {
+		t.refreshed = make(map[string]struct{})
+	}
+
+	t.refreshed[e.Path()] = struct{}{}
+}
+
+// mergeRefreshed merges the refreshed directories tracked by TrackRefresh into
+// the cached trees of their ancestors. The tree's own cache entry is written as
+// a whole; hence, it's not updated here. The refreshed directories are merged
+// only once.
+func (t *Tree) mergeRefreshed() error {
+	t.refreshMx.Lock()
+	paths := slices.Sorted(maps.Keys(t.refreshed))
+	t.refreshed = nil
+	t.refreshMx.Unlock()
+
+	var (
+		rootPath = t.root.Path()
+		merged   = make([]*Entry, 0, len(paths))
+		targets  = make(map[string][]*Entry)
+	)
+
+	for _, path := range paths {
+		// the nested directories are merged as a part of their ancestor
+		if slices.ContainsFunc(merged, func(e *Entry) bool {
+			return pathWithin(path, e.Path())
+		}) {
+			continue
+		}
+
+		e := t.root
+		if path != rootPath {
+			_, e = t.locate(path)
+		}
+
+		// the directory might have been removed or replaced since then
+		if e == nil || !e.IsDir || e.IsCollapsed {
+			continue
+		}
+
+		merged = append(merged, e)
+
+		for p := path; ; p = filepath.Dir(p) {
+			if p != rootPath {
+				targets[p] = append(targets[p], e)
+			}
+
+			if filepath.Dir(p) == p {
+				break
+			}
+		}
+	}
+
+	var errList []error
+
+	for cachedPath, entries := range targets {
+		key := cacheKey(cachedPath)
+		if !t.cache.Has(key) {
+			continue
+		}
+
+		if err := t.mergeCached(key, entries); err != nil {
+			errList = append(errList, err)
+		}
+	}
+
+	return errors.Join(errList...)
+}
+
+// mergeCached restores the cached tree by the provided key, replaces the content
+// of the provided directories within it, and writes the tree back. The
+// directories not kept in the cached tree, e.g., located below a collapsed
+// directory, are skipped.
+func (t *Tree) mergeCached(key cache.Key, entries []*Entry) error {
+	// the cached tree's root itself was refreshed, so the tree is replaced
+	// entirely. The nested directories are never tracked along with it.
+	if len(entries) == 1 && entries[0].Path() == key.Path {
+		return t.cache.Set(key, entries[0].clone())
+	}
+
+	cached := NewDirEntry(key.Path, 0)
+
+	if err := t.cache.Get(key, cached); err != nil {
+		if errors.Is(err, cache.ErrNoCache) {
+			return nil
+		}
+
+		return err
+	}
+
+	cachedTree := NewTree(cached)
+
+	for _, e := range entries {
+		parent, old := cachedTree.locate(e.Path())
+		if parent == nil {
+			continue
+		}
+
+		if old != nil {
+			parent.RemoveChild(old)
+		}
+
+		parent.AddChild(e.clone())
+	}
+
+	return t.cache.Set(key, cached)
+}
+
+// pathWithin reports whether the path is the provided directory or is located
+// within it.
+func pathWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+
+	return err == nil && rel != ".." &&
+		!strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
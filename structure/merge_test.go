@@ -0,0 +1,89 @@
+package structure_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/crumbyte/noxdir/pkg/cache"
+	"github.com/crumbyte/noxdir/structure"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTree_PersistCacheMerge(t *testing.T) {
+	root, cacheDir := t.TempDir(), t.TempDir()
+	a := filepath.Join(root, "a")
+
+	c, err := cache.NewCache(
+		func(w io.Writer) cache.Encoder { return structure.NewEncoder(w) },
+		func(r io.Reader) cache.Decoder { return structure.NewDecoder(r) },
+		false,
+		cache.WithCacheDir(cacheDir),
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(a, "b"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "file"), make([]byte, 10), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(a, "b", "file"), make([]byte, 100), 0600))
+
+	driveTree := structure.NewTree(structure.NewDirEntry(root, 0), structure.WithCache(c))
+
+	require.NoError(t, driveTree.Traverse(context.Background(), true))
+	require.NoError(t, driveTree.PersistCache())
+
+	// the partial root's scan is merged into the cached tree containing it
+	require.NoError(t, os.WriteFile(filepath.Join(a, "new"), make([]byte, 1000), 0600))
+
+	partialTree := structure.NewTree(
+		structure.NewDirEntry(a, time.Now().Unix()),
+		structure.WithCache(c),
+		structure.WithPartialRoot(),
+	)
+
+	require.NoError(t, partialTree.Traverse(context.Background(), true))
+
+	// as well as the refreshed subtree
+	require.NoError(t, os.WriteFile(filepath.Join(a, "b", "refreshed"), make([]byte, 500), 0600))
+
+	b := partialTree.Root().GetChild("b")
+	b.ClearChild()
+
+	done, errChan := partialTree.Subtree(b).TraverseAsync(context.Background(), true)
+
+	for err = range errChan {
+		require.NoError(t, err)
+	}
+
+	<-done
+
+	partialTree.TrackRefresh(b)
+
+	require.NoError(t, partialTree.PersistCache())
+
+	// the new files are removed, so they can be found only in the cache
+	require.NoError(t, os.Remove(filepath.Join(a, "new")))
+	require.NoError(t, os.Remove(filepath.Join(a, "b", "refreshed")))
+
+	restored := structure.NewDirEntry(root, 0)
+
+	require.NoError(t, structure.NewTree(restored, structure.WithCache(c)).Traverse(context.Background(), false))
+	require.EqualValues(t, 1610, restored.Size)
+	require.EqualValues(t, 2, restored.TotalDirs)
+	require.EqualValues(t, 4, restored.TotalFiles)
+	require.NotNil(t, restored.GetChild("a").GetChild("new"))
+	require.NotNil(t, restored.GetChild("a").GetChild("b").GetChild("refreshed"))
+
+	restoredPartial := structure.NewDirEntry(a, 0)
+
+	require.NoError(
+		t,
+		structure.NewTree(restoredPartial, structure.WithCache(c), structure.WithPartialRoot()).
+			Traverse(context.Background(), false),
+	)
+	require.EqualValues(t, 1600, restoredPartial.Size)
+	require.Equal(t, a, restoredPartial.Path())
+}
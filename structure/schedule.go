@@ -0,0 +1,188 @@
+package structure
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// WithFocusFirst enables the focus-first scheduling mode for TraverseAsync. In
+// this mode, the directories within the focused entry's subtree, set by
+// Tree.SetFocus, are read before any other queued directories. It also enables
+// tracking of the scanned subtrees, so the still scanned directories can be
+// checked by Tree.Scanning while the traversal is running.
+func WithFocusFirst() TreeOpt {
+	return func(t *Tree) {
+		t.focusFirst = true
+	}
+}
+
+// FocusFirst reports whether the focus-first mode is enabled.
+func (t *Tree) FocusFirst() bool {
+	return t.focusFirst
+}
+
+// SetFocus sets the directory entry whose subtree must be scanned first. The
+// focus can be changed at any time, including during the running traversal. It
+// does nothing unless the focus-first mode is enabled by WithFocusFirst.
+func (t *Tree) SetFocus(e *Entry) {
+	if !t.focusFirst {
+		return
+	}
+
+	t.focus.Store(e)
+
+	if q := t.queue.Load(); q != nil {
+		q.setFocus(e)
+	}
+}
+
+// Scanning reports whether the directory entry's subtree is still being scanned
+// by the running traversal. It always returns "false" unless the focus-first
+// mode is enabled by WithFocusFirst.
+func (t *Tree) Scanning(e *Entry) bool {
+	_, ok := t.progress.Load(e)
+
+	return ok
+}
+
+// scanProgress contains the number of directories within the entry's subtree,
+// including the entry itself, that are not yet read.
+type scanProgress struct {
+	entry   *Entry
+	parent  *scanProgress
+	pending atomic.Int64
+}
+
+// trackDir registers the new queued directory within the subtrees of all its
+// ancestors.
+func (t *Tree) trackDir(parent, dir *Entry) {
+	if !t.focusFirst {
+		return
+	}
+
+	p := &scanProgress{entry: dir}
+
+	if parent != nil {
+		if pp, ok := t.progress.Load(parent); ok {
+			p.parent, _ = pp.(*scanProgress)
+		}
+	}
+
+	t.progress.Store(dir, p)
+
+	for s := p; s != nil; s = s.parent {
+		s.pending.Add(1)
+	}
+}
+
+// untrackDir marks the directory as read. The directory's ancestors whose
+// subtrees are completely read are not tracked anymore.
+func (t *Tree) untrackDir(dir *Entry) {
+	p, ok := t.progress.Load(dir)
+	if !ok {
+		return
+	}
+
+	for s, _ := p.(*scanProgress); s != nil; s = s.parent {
+		if s.pending.Add(-1) == 0 {
+			t.progress.Delete(s.entry)
+		}
+	}
+}
+
+// scanQueue is an unbounded queue of the directories to be read. The directories
+// within the focused entry's subtree are taken before any other directories.
+// The workers are notified about the new directories via the notify channel.
+type scanQueue struct {
+	focus   *Entry
+	notify  chan struct{}
+	focused []*Entry
+	other   []*Entry
+	mx      sync.Mutex
+}
+
+func newScanQueue(focus *Entry) *scanQueue {
+	return &scanQueue{focus: focus, notify: make(chan struct{}, 1)}
+}
+
+func (q *scanQueue) push(e *Entry) {
+	q.mx.Lock()
+
+	if within(e, q.focus) {
+		q.focused = append(q.focused, e)
+	} else {
+		q.other = append(q.other, e)
+	}
+
+	q.mx.Unlock()
+	q.signal()
+}
+
+// pop takes the next directory from the queue, or returns nil if the queue is
+// empty. If there are more directories left, another worker is notified.
+func (q *scanQueue) pop() *Entry {
+	var e *Entry
+
+	q.mx.Lock()
+
+	switch {
+	case len(q.focused) > 0:
+		e, q.focused = q.focused[0], q.focused[1:]
+	case len(q.other) > 0:
+		e, q.other = q.other[0], q.other[1:]
+	}
+
+	left := len(q.focused) + len(q.other)
+
+	q.mx.Unlock()
+
+	if left > 0 {
+		q.signal()
+	}
+
+	return e
+}
+
+// setFocus changes the focused entry and reorders the queued directories
+// accordingly, preserving their relative order.
+func (q *scanQueue) setFocus(focus *Entry) {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+
+	queued := make([]*Entry, 0, len(q.focused)+len(q.other))
+	queued = append(append(queued, q.focused...), q.other...)
+
+	q.focus, q.focused, q.other = focus, nil, nil
+
+	for _, e := range queued {
+		if within(e, focus) {
+			q.focused = append(q.focused, e)
+		} else {
+			q.other = append(q.other, e)
+		}
+	}
+}
+
+func (q *scanQueue) signal() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+		// a notification is already pending
+	}
+}
+
+// within checks whether the entry is the directory itself or is located within
+// its subtree.
+func within(e, dir *Entry) bool {
+	if dir == nil {
+		return false
+	}
+
+	for p := e; p != nil; p = p.parent {
+		if p == dir {
+			return true
+		}
+	}
+
+	return false
+}
@@ -10,9 +10,10 @@ import (
 // sorted by their sizes. The EntrySizeHeap could contain up to n files, where n
 // is defined when creating a new heap instance.
 type EntrySizeHeap struct {
-	files []*Entry
-	mx    sync.RWMutex
-	size  int
+	files    []*Entry
+	mx       sync.RWMutex
+	size     int
+	sizeMode SizeMode
 }
 
 // PushSafe provides a thread-safe method for adding elements to the heap. On each
@@ -37,7 +38,7 @@ func (esh *EntrySizeHeap) Less(i, j int) bool {
 		return false
 	}
 
-	return esh.files[i].Size < esh.files[j].Size
+	return esh.files[i].SizeBy(esh.sizeMode) < esh.files[j].SizeBy(esh.sizeMode)
 }
 
 func (esh *EntrySizeHeap) Swap(i, j int) {
@@ -0,0 +1,82 @@
+package structure
+
+import (
+	"sync"
+
+	"github.com/crumbyte/noxdir/drive"
+)
+
+// dirSet contains the device and inode numbers of all directories visited
+// during a single tree traversal. It's used for detecting cycles when following
+// symbolic links.
+type dirSet struct {
+	dirs map[drive.InoKey]struct{}
+	mx   sync.Mutex
+}
+
+func newDirSet() *dirSet {
+	return &dirSet{dirs: make(map[drive.InoKey]struct{})}
+}
+
+// add adds the directory to the set and returns "true" if it was not visited
+// before. An empty key can't identify the directory; hence, it's always
+// rejected.
+func (ds *dirSet) add(key drive.InoKey) bool {
+	if key == (drive.InoKey{}) {
+		return false
+	}
+
+	ds.mx.Lock()
+	defer ds.mx.Unlock()
+
+	if _, ok := ds.dirs[key]; ok {
+		return false
+	}
+
+	ds.dirs[key] = struct{}{}
+
+	return true
+}
+
+// handleSymlink adds the symbolic link to the parent entry. If following links
+// is enabled, the link will be resolved. A link to a directory that was not
+// visited before becomes a directory entry and is passed to the onNewDir
+// callback, while a link to a file gets the target file's size. If the parent's
+// content is collapsed, the link is summarized into the collapsed entry.
+func (t *Tree) handleSymlink(parent, collapsed *Entry, fi drive.FileInfo, path string, onNewDir func(*Entry)) {
+	link := NewLinkEntry(fi.Name(), fi.Target(), fi.ModTime())
+	link.parent, link.Ino = parent, fi.InoKey()
+
+	if !t.followSymlinks {
+		addChild(parent, collapsed, link)
+
+		return
+	}
+
+	target, err := drive.Stat(path)
+	if err != nil {
+		addChild(parent, collapsed, link)
+
+		return
+	}
+
+	if !target.IsDir() {
+		link.Size, link.Usage = target.Size(), target.Usage()
+		addChild(parent, collapsed, link)
+
+		return
+	}
+
+	// the target directory is either an ancestor of the link, which leads to
+	// a cycle, or has been already visited through another path.
+	if !t.visitedDirs.add(target.InoKey()) {
+		addChild(parent, collapsed, link)
+
+		return
+	}
+
+	link.IsDir, link.Child = true, make([]*Entry, 0)
+
+	addChild(parent, collapsed, link)
+	onNewDir(link)
+}
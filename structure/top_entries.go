@@ -18,6 +18,12 @@ func NewTopEntries(maxEntries int) *TopEntries {
 	}
 }
 
+// SetSizeMode defines which of the entries sizes will be used for ranking.
+// The change takes effect on the next ScanFiles or ScanDirs call.
+func (te *TopEntries) SetSizeMode(sm SizeMode) {
+	te.files.sizeMode, te.dirs.sizeMode = sm, sm
+}
+
 func (te *TopEntries) Files() heap.Interface {
 	return &te.files
 }
@@ -66,13 +72,14 @@ func (te *TopEntries) ScanDirs(root *Entry) {
 	for len(queue) > 0 {
 		currentNode, queue = queue[0], queue[1:]
 
-		totalSize := currentNode.Size
+		nodeSize := currentNode.SizeBy(te.dirs.sizeMode)
+		totalSize := nodeSize
 
 		for child := range currentNode.EntriesByType(false) {
-			totalSize -= child.Size
+			totalSize -= child.SizeBy(te.dirs.sizeMode)
 		}
 
-		if totalSize < currentNode.Size/2 {
+		if totalSize < nodeSize/2 {
 			te.dirs.PushSafe(currentNode)
 
 			continue
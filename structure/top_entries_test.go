@@ -12,36 +12,27 @@ import (
 func TestTopEntries_ScanFiles(t *testing.T) {
 	te := structure.NewTopEntries(5)
 
-	tfEntry := &structure.Entry{
-		Path: "root",
-		Child: []*structure.Entry{
-			{Path: "root_file_1", Size: 100},
-			{Path: "root_file_2", Size: 150},
-			{Path: "root_file_3", Size: 200},
-			{Path: "root_file_4", Size: 650},
-			{
-				Path: "level1",
-				Child: []*structure.Entry{
-					{Path: "level1_file_1", Size: 250},
-					{Path: "level1_file_2", Size: 300},
-					{Path: "level1_file_3", Size: 700},
-					{Path: "level1_file_4", Size: 400},
-					{
-						Path: "level2",
-						Child: []*structure.Entry{
-							{Path: "level2_file_1", Size: 450},
-							{Path: "level2_file_2", Size: 500},
-							{Path: "level2_file_3", Size: 550},
-							{Path: "level2_file_4", Size: 600},
-						},
-						IsDir: true,
-					},
-				},
-				IsDir: true,
-			},
-		},
-		IsDir: true,
-	}
+	tfEntry := testDir(
+		"root",
+		testFile("root_file_1", 100),
+		testFile("root_file_2", 150),
+		testFile("root_file_3", 200),
+		testFile("root_file_4", 650),
+		testDir(
+			"level1",
+			testFile("level1_file_1", 250),
+			testFile("level1_file_2", 300),
+			testFile("level1_file_3", 700),
+			testFile("level1_file_4", 400),
+			testDir(
+				"level2",
+				testFile("level2_file_1", 450),
+				testFile("level2_file_2", 500),
+				testFile("level2_file_3", 550),
+				testFile("level2_file_4", 600),
+			),
+		),
+	)
 
 	te.ScanFiles(tfEntry)
 
@@ -66,55 +57,22 @@ func TestTopEntries_ScanFiles(t *testing.T) {
 func TestTopEntries_ScanDirs(t *testing.T) {
 	te := structure.NewTopEntries(3)
 
-	tfEntry := &structure.Entry{
-		Path: "root",
-		Child: []*structure.Entry{
-			{
-				Path: "level1_1",
-				Child: []*structure.Entry{
-					{
-						Path: "level1_1_file_1", Size: 100,
-					},
-				},
-				IsDir: true,
-				Size:  100,
-			},
-			{
-				Path: "level1_2",
-				Child: []*structure.Entry{
-					{
-						Path: "level1_2_file_1", Size: 150,
-					},
-				},
-				IsDir: true,
-				Size:  150,
-			},
-			{
-				Path: "level1_3",
-				Child: []*structure.Entry{
-					{
-						Path: "level1_3_file_1", Size: 200,
-					},
-					{
-						Path: "level1_3_dir_1",
-						Child: []*structure.Entry{
-							{
-								Path: "level1_2_file_1", Size: 250,
-							},
-							{
-								Path: "level1_2_file_2", Size: 300,
-							},
-						},
-						Size:  550,
-						IsDir: true,
-					},
-				},
-				Size:  750,
-				IsDir: true,
-			},
-		},
-		IsDir: true,
-	}
+	tfEntry := testDir(
+		"root",
+		testDir("level1_1", testFile("level1_1_file_1", 100)),
+		testDir("level1_2", testFile("level1_2_file_1", 150)),
+		testDir(
+			"level1_3",
+			testFile("level1_3_file_1", 200),
+			testDir(
+				"level1_3_dir_1",
+				testFile("level1_2_file_1", 250),
+				testFile("level1_2_file_2", 300),
+			),
+		),
+	)
+
+	structure.NewTree(tfEntry).CalculateSize()
 
 	te.ScanDirs(tfEntry)
 
@@ -1,9 +1,11 @@
 package structure
 
 import (
+	"context"
 	"errors"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -11,14 +13,10 @@ import (
 
 	"github.com/crumbyte/noxdir/drive"
 	"github.com/crumbyte/noxdir/pkg/cache"
+	"github.com/crumbyte/noxdir/pkg/exclude"
 )
 
-const (
-	workerTimeout    = time.Second * 2
-	workerReset      = time.Second
-	childPathBufSize = 512
-	bfsQueueSize     = 64
-)
+const childPathBufSize = 512
 
 // TreeOpt defines a custom type for configuring a *Tree instance.
 type TreeOpt func(*Tree)
@@ -29,14 +27,39 @@ type TreeOpt func(*Tree)
 // directories that contain this name will be excluded. For example, the following
 // path "dir/sub_dir/inner/other" and adding the name "sub" for exclusion will
 // completely remove the "dir/sub_dir" directory from traversal. To avoid that,
-// use a more specific path, e.g., "dir/sub/".
+// use a more specific path, e.g., "dir/sub/". The names are matched case
+// insensitively; the provided slice is not modified.
 func WithExclude(exclude []string) TreeOpt {
 	return func(t *Tree) {
+		t.exclude = make([]string, len(exclude))
+
 		for i := range exclude {
-			exclude[i] = strings.ToLower(strings.TrimSpace(exclude[i]))
+			t.exclude[i] = strings.ToLower(strings.TrimSpace(exclude[i]))
 		}
+	}
+}
 
-		t.exclude = exclude
+// WithExcludeMatcher allows setting the pattern-based exclusion rules. The rules
+// are matched against the entries' paths relative to the tree's root. Unlike the
+// WithExclude rules, the patterns can exclude both directories and files.
+//
+// The excluded directories are still added to the tree as placeholders with the
+// [Entry.IsExcluded] flag set, but their content is not scanned.
+func WithExcludeMatcher(m *exclude.Matcher) TreeOpt {
+	return func(t *Tree) {
+		t.excludeMatcher = m
+	}
+}
+
+// WithIgnoreFiles enables reading the ".gitignore", ".ignore", and
+// ".noxdirignore" files in each scanned directory. The rules from these files
+// are applied to the directory's subtree the same way as git does it. The
+// provided IgnoreMode value defines how the ignored entries are handled.
+//
+// The ignore files located above the tree's root are not taken into account.
+func WithIgnoreFiles(mode IgnoreMode) TreeOpt {
+	return func(t *Tree) {
+		t.ignoreMode = mode
 	}
 }
 
@@ -60,6 +83,30 @@ func WithCache(c *cache.Cache) TreeOpt {
 	}
 }
 
+// WithHardlinkMode defines how the size of files with multiple hard links will be
+// accounted. By default, the HardlinkOnce mode is used.
+func WithHardlinkMode(hm HardlinkMode) TreeOpt {
+	return func(t *Tree) {
+		t.hardlinkMode = hm
+	}
+}
+
+// WithFollowSymlinks enables following symbolic links during the traversal. The
+// links to directories will be scanned as regular directories, and the links to
+// files will get the target file's size. The cycles are detected by the device
+// and inode numbers of the visited directories; hence, links that cannot be
+// identified this way, e.g., on Windows, are not followed. Links to already
+// visited directories are not followed either.
+func WithFollowSymlinks() TreeOpt {
+	return func(t *Tree) {
+		t.followSymlinks = true
+	}
+}
+
+// WithPartialRoot defines that the tree's root is a directory within a drive
+// rather than the drive itself. Once such a root is scanned, its content is
+// merged into the cached trees of the directories that contain it, e.g., the
+// cached drive tree, when the tree is persisted. See Tree.TrackRefresh.
 func WithPartialRoot() TreeOpt {
 	return func(t *Tree) {
 		t.partialRoot = true
@@ -70,14 +117,36 @@ func WithPartialRoot() TreeOpt {
 type Tree struct {
 	root             *Entry
 	cache            *cache.Cache
+	hardLinks        *hardLinks
+	visitedDirs      *dirSet
+	excludeMatcher   *exclude.Matcher
+	ignoreScopes     sync.Map
+	collapsedDirs    sync.Map
+	cachedDirs       sync.Map
+	progress         sync.Map
+	refreshMx        sync.Mutex
+	refreshed        map[string]struct{}
+	newDirs          []*Entry
+	focus            atomic.Pointer[Entry]
+	queue            atomic.Pointer[scanQueue]
+	reusedDirs       atomic.Uint64
+	rereadDirs       atomic.Uint64
 	exclude          []string
 	fiFilters        []drive.FileInfoFilter
+	basePath         string
 	calculateSizeSem uint32
+	maxDepth         int
+	hardlinkMode     HardlinkMode
+	ignoreMode       IgnoreMode
 	partialRoot      bool
+	followSymlinks   bool
+	incremental      bool
+	focusFirst       bool
+	interrupted      atomic.Bool
 }
 
 func NewTree(root *Entry, opts ...TreeOpt) *Tree {
-	t := &Tree{root: root}
+	t := &Tree{root: root, hardLinks: newHardLinks(), visitedDirs: newDirSet()}
 
 	for _, opt := range opts {
 		opt(t)
@@ -96,11 +165,71 @@ func (t *Tree) SetRoot(root *Entry) {
 	t.root = root
 }
 
+// Lookup finds the entry by its full path within the tree. A nil value will be
+// returned if the entry is not a part of the tree, or its content is not kept in
+// the tree, e.g., it's located within a collapsed directory.
+func (t *Tree) Lookup(path string) *Entry {
+	if t.root == nil {
+		return nil
+	}
+
+	if path == t.root.Path() {
+		return t.root
+	}
+
+	if !pathWithin(path, t.root.Path()) {
+		return nil
+	}
+
+	_, e := t.locate(path)
+
+	return e
+}
+
+// Subtree creates a new tree for the provided root entry with the same traversal
+// options as the current tree has. The new tree is a partial root tree and does
+// not use the cache. It allows scanning a part of the current tree, e.g., on
+// refresh or when drilling into a collapsed directory. The exclusion patterns
+// are still matched relative to the current tree's root.
+func (t *Tree) Subtree(root *Entry) *Tree {
+	return &Tree{
+		root:           root,
+		basePath:       t.scanBase(),
+		hardLinks:      newHardLinks(),
+		visitedDirs:    newDirSet(),
+		excludeMatcher: t.excludeMatcher,
+		exclude:        t.exclude,
+		fiFilters:      t.fiFilters,
+		maxDepth:       t.maxDepth,
+		hardlinkMode:   t.hardlinkMode,
+		ignoreMode:     t.ignoreMode,
+		followSymlinks: t.followSymlinks,
+		partialRoot:    true,
+	}
+}
+
+// HardLinks returns all files with more than one hard link found during the last
+// traversal, sorted by their size in descending order. The list is not persisted
+// in the cache; hence, it will be empty if the tree was restored from the cache.
+func (t *Tree) HardLinks() []*HardLink {
+	return t.hardLinks.list()
+}
+
+// Interrupted reports whether the last traversal was canceled before all
+// directories were scanned. In that case, the tree contains only a part of the
+// entries.
+func (t *Tree) Interrupted() bool {
+	return t.interrupted.Load()
+}
+
 // CalculateSize calculates the total number of directories and files, including
 // ones within child entries, and the total tail of the current entry instance.
 // This function call will recursively calculate the sizes of child entries. The
-// final [Entry.Size] field will be a sum of all nested files sizes. If the
-// current entry represents a file, only its own tail will be returned.
+// final [Entry.Size] and [Entry.Usage] fields will be a sum of all nested files
+// apparent and allocated sizes respectively.
+//
+// The traversal keeps the totals up to date on its own, so the function is only
+// required for the trees built manually without Entry.AddChild.
 func (t *Tree) CalculateSize() {
 	if t.root == nil || !t.root.IsDir {
 		return
@@ -112,28 +241,38 @@ func (t *Tree) CalculateSize() {
 
 	defer atomic.SwapUint32(&t.calculateSizeSem, 0)
 
-	var calculate func(e *Entry) int64
-	calculate = func(e *Entry) int64 {
-		if !e.IsDir {
-			return e.Size
+	var calculate func(e *Entry)
+	calculate = func(e *Entry) {
+		// the collapsed entries have no child entries, and their totals are
+		// calculated during the traversal.
+		if !e.IsDir || e.IsCollapsed {
+			return
 		}
 
-		e.TotalDirs, e.Size, e.TotalFiles = 0, 0, 0
+		e.TotalDirs, e.Size, e.Usage, e.TotalFiles = 0, 0, 0, 0
 
 		for _, child := range e.Child {
-			e.Size += calculate(child)
+			calculate(child)
 
-			if child.IsDir {
-				e.TotalDirs++
-			} else {
+			e.Size += child.Size
+			e.Usage += child.Usage
+
+			if !child.IsDir {
 				e.TotalFiles++
+
+				continue
+			}
+
+			// the ignored entries group is not an actual directory
+			if child.IsIgnored {
+				e.TotalFiles++
+			} else {
+				e.TotalDirs++
 			}
 
 			e.TotalDirs += child.TotalDirs
 			e.TotalFiles += child.TotalFiles
 		}
-
-		return e.Size
 	}
 
 	calculate(t.root)
@@ -143,102 +282,216 @@ func (t *Tree) CalculateSize() {
 // directories and builds the corresponding tree using a BFS approach. The total
 // traverse duration depends on the directory's structure depth.
 //
-// The traverse process only builds the tree structure of child entries and does
-// not calculate the final values for total tail and number of child directories
-// and files. To do this, the Tree.CalculateSize must be called during or
-// after the traverse finishes the execution. In the first case, the numbers
-// will not be accurate but can be used to display the progress of the traversing
-// process gradually.
-func (t *Tree) Traverse(skipCache bool) error {
+// The sizes and the total number of child directories and files are added to
+// all ancestors as soon as the entries are found. Hence, the numbers can be
+// used to display the progress of the traversing process gradually, and they
+// are final once the traversal is finished.
+//
+// The traversal stops as soon as the provided context is canceled. The already
+// built part of the tree is kept, and the context error is returned.
+func (t *Tree) Traverse(ctx context.Context, skipCache bool) error {
 	var (
 		errList     []error
 		currentNode *Entry
 	)
 
+	t.resetTraversal()
+
+	if t.root == nil || !t.root.IsDir {
+		return nil
+	}
+
 	if !skipCache && t.cache != nil {
-		if err := t.cache.Get(t.root.Path, t.root); err == nil {
+		err := t.cache.Get(cacheKey(t.root.Path()), t.root)
+		if err == nil {
 			return nil
 		}
-	}
 
-	drive.InoFilterInstance.Reset()
-
-	if t.root == nil || !t.root.IsDir {
-		return nil
+		// the rejected cache entry is reported, and the root is scanned
+		// from scratch.
+		if !errors.Is(err, cache.ErrNoCache) {
+			errList = append(errList, err)
+			t.root.ClearChild()
+		}
 	}
 
 	queue := []*Entry{t.root}
 
 	for len(queue) > 0 {
+		if err := ctx.Err(); err != nil {
+			t.interrupted.Store(true)
+
+			return errors.Join(append(errList, err)...)
+		}
+
 		currentNode, queue = queue[0], queue[1:]
 
-		t.handleEntry(
+		t.visit(
 			currentNode,
 			func(newDir *Entry) { queue = append(queue, newDir) },
 			func(err error) { errList = append(errList, err) },
 		)
 	}
 
+	if t.partialRoot {
+		t.TrackRefresh(t.root)
+	}
+
 	return errors.Join(errList...)
 }
 
+// PersistCache saves the current tree state to the cache. The trees whose
+// traversal was interrupted are never cached. The directories tracked by
+// TrackRefresh are merged into the cached trees containing them, even if the
+// tree itself is not cached.
 func (t *Tree) PersistCache() error {
-	if t.cache == nil || t.partialRoot || t.root == nil {
+	if t.cache == nil || t.root == nil {
 		return nil
 	}
 
-	return t.cache.Set(t.root.Path, t.root)
+	var errList []error
+
+	if !t.Interrupted() {
+		if err := t.cache.Set(cacheKey(t.root.Path()), t.root); err != nil {
+			errList = append(errList, err)
+		}
+	}
+
+	if err := t.mergeRefreshed(); err != nil {
+		errList = append(errList, err)
+	}
+
+	return errors.Join(errList...)
 }
 
-func (t *Tree) TraverseAsync(skipCache bool) (chan struct{}, chan error) {
-	var wg sync.WaitGroup
+// CachedAt returns the time the tree for the provided root path was cached at. It
+// reports "false" if the cache is disabled, or there is no valid cache entry for
+// the root's file system and the current scan options.
+func (t *Tree) CachedAt(path string) (time.Time, bool) {
+	if t.cache == nil {
+		return time.Time{}, false
+	}
 
-	drive.InoFilterInstance.Reset()
+	h, err := t.cache.Header(cacheKey(path))
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return h.CreatedAt, true
+}
+
+// cacheKey returns the cache key for the tree's root located at the provided
+// path. The file system the root is located on is a part of the key.
+func cacheKey(path string) cache.Key {
+	return cache.Key{Path: path, Device: drive.DeviceID(path)}
+}
+
+// TraverseAsync builds the tree the same way as Traverse does, but the directories
+// are read concurrently by a pool of workers. The function does not block, and
+// returns a "done" channel, which will be closed once the traversal is finished,
+// and a channel for the errors that occurred during the traversal.
+//
+// The number of directories that are queued but not yet read is tracked, so the
+// traversal finishes exactly when the last queued directory has been read. If
+// the provided context is canceled, the workers stop reading new directories,
+// and the "done" channel will be closed, leaving the partially built tree.
+//
+// By default, the directories are read in the order they were found. In the
+// focus-first mode, enabled by WithFocusFirst, the focused subtree is read first.
+func (t *Tree) TraverseAsync(ctx context.Context, skipCache bool) (chan struct{}, chan error) {
+	t.resetTraversal()
 
 	if t.root == nil || !t.root.IsDir {
 		return nil, nil
 	}
 
-	queue := make(chan *Entry, bfsQueueSize)
 	done, errChan := make(chan struct{}), make(chan error, 1)
 
-	if !skipCache && t.cache != nil && t.cache.Has(t.root.Path) {
+	if key := cacheKey(t.root.Path()); !skipCache && t.cache != nil && t.cache.Has(key) {
 		go func() {
-			if err := t.cache.Get(t.root.Path, t.root); err == nil {
+			err := t.cache.Get(key, t.root)
+			if err == nil {
 				close(done)
+
+				return
 			}
+
+			// the cache entry cannot be restored, fall back to the full scan
+			errChan <- err
+
+			t.root.ClearChild()
+			t.traverseAsync(ctx, done, errChan)
 		}()
 
 		return done, errChan
 	}
 
-	queue <- t.root
+	go t.traverseAsync(ctx, done, errChan)
 
-	worker := func() {
-		timeoutTimer := time.NewTimer(workerTimeout)
+	return done, errChan
+}
 
-		defer func() {
-			wg.Done()
-			timeoutTimer.Stop()
-		}()
+func (t *Tree) traverseAsync(ctx context.Context, done chan struct{}, errChan chan error) {
+	var (
+		wg      sync.WaitGroup
+		pending atomic.Int64
+	)
+
+	scanCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	queue := newScanQueue(t.focus.Load())
+
+	t.queue.Store(queue)
+	defer t.queue.Store(nil)
+
+	pending.Add(1)
+	t.trackDir(nil, t.root)
+	queue.push(t.root)
+
+	// the queued directories are tracked along with their parents, so it's
+	// known when the entire subtree is scanned.
+	enqueue := func(parent *Entry) func(*Entry) {
+		return func(newDir *Entry) {
+			pending.Add(1)
+			t.trackDir(parent, newDir)
+			queue.push(newDir)
+		}
+	}
+
+	onErr := func(err error) {
+		select {
+		case errChan <- err:
+		case <-scanCtx.Done():
+		}
+	}
+
+	worker := func() {
+		defer wg.Done()
 
 		for {
-			select {
-			case entry, ok := <-queue:
-				if !ok {
+			entry := queue.pop()
+			if entry == nil {
+				select {
+				case <-scanCtx.Done():
 					return
+				case <-queue.notify:
+					continue
 				}
+			}
 
-				t.handleEntry(
-					entry,
-					func(newDir *Entry) { go func() { queue <- newDir }() },
-					func(err error) { errChan <- err },
-				)
-
-				timeoutTimer.Reset(workerReset)
-			case <-timeoutTimer.C:
+			if scanCtx.Err() != nil {
 				return
 			}
+
+			t.visit(entry, enqueue(entry), onErr)
+			t.untrackDir(entry)
+
+			// the last queued directory has been read, and no new
+			// directories were found, hence the traversal is complete.
+			if pending.Add(-1) == 0 {
+				cancel()
+			}
 		}
 	}
 
@@ -247,15 +500,38 @@ func (t *Tree) TraverseAsync(skipCache bool) (chan struct{}, chan error) {
 		go worker()
 	}
 
-	go func() {
-		wg.Wait()
+	wg.Wait()
 
-		close(done)
-		close(queue)
-		close(errChan)
-	}()
+	if pending.Load() > 0 {
+		t.interrupted.Store(true)
+	} else if t.partialRoot {
+		t.TrackRefresh(t.root)
+	}
 
-	return done, errChan
+	t.progress.Clear()
+
+	close(done)
+	close(errChan)
+}
+
+// resetTraversal resets the state collected during the previous traversal.
+func (t *Tree) resetTraversal() {
+	t.hardLinks = newHardLinks()
+	t.visitedDirs = newDirSet()
+	t.interrupted.Store(false)
+	t.ignoreScopes.Clear()
+	t.collapsedDirs.Clear()
+	t.cachedDirs.Clear()
+	t.progress.Clear()
+	t.reusedDirs.Store(0)
+	t.rereadDirs.Store(0)
+	t.incremental = false
+
+	if t.followSymlinks && t.root != nil {
+		if rootInfo, err := drive.Stat(t.root.Path()); err == nil {
+			t.visitedDirs.add(rootInfo.InoKey())
+		}
+	}
 }
 
 var childPathBufPool = sync.Pool{
@@ -267,12 +543,20 @@ var childPathBufPool = sync.Pool{
 }
 
 func (t *Tree) handleEntry(e *Entry, onNewDir func(*Entry), onErr func(error)) {
-	if !e.IsDir || t.excludeEntry(e) {
+	if !e.IsDir || e.IsExcluded {
 		return
 	}
 
-	nodeEntries, err := drive.ReadDir(e.Path)
+	path := e.Path()
+
+	if e == t.root && t.excludeDir(path) {
+		return
+	}
+
+	nodeEntries, err := drive.ReadDir(path)
 	if err != nil {
+		t.ignoreScopes.Delete(e)
+		t.collapsedDirs.Delete(e)
 		onErr(err)
 
 		return
@@ -285,14 +569,37 @@ func (t *Tree) handleEntry(e *Entry, onNewDir func(*Entry), onErr func(error)) {
 
 	defer childPathBufPool.Put(nameBuf)
 
+	hasFile := func(name string) bool { return containsFile(nodeEntries, name) }
+
+	var (
+		scope     = t.resolveIgnoreScope(e, hasFile, onErr)
+		collapsed = t.collapsedTarget(e)
+		enqueue   = onNewDir
+		group     *Entry
+	)
+
+	// the directories below the collapsed one are transient and only refer
+	// to the collapsed entry their content must be summarized into. Otherwise,
+	// the child entries are allocated at once rather than growing gradually.
+	if collapsed == nil {
+		e.mx.Lock()
+		e.Child = slices.Grow(e.Child, len(nodeEntries))
+		e.mx.Unlock()
+	} else {
+		enqueue = func(newDir *Entry) {
+			t.collapsedDirs.Store(newDir, collapsed)
+			onNewDir(newDir)
+		}
+	}
+
 	for _, child := range nodeEntries {
 		if !t.filterFileInfo(child) {
 			continue
 		}
 
-		*nameBuf = append(*nameBuf, e.Path...)
+		*nameBuf = append(*nameBuf, path...)
 
-		if e.Path[len(e.Path)-1] != filepath.Separator {
+		if path[len(path)-1] != filepath.Separator {
 			*nameBuf = append(*nameBuf, filepath.Separator)
 		}
 
@@ -301,22 +608,129 @@ func (t *Tree) handleEntry(e *Entry, onNewDir func(*Entry), onErr func(error)) {
 		childPath := string(*nameBuf)
 		*nameBuf = (*nameBuf)[:0]
 
+		if t.excludeChild(childPath, child) {
+			// the excluded directories are kept as placeholders without
+			// child entries, so it's still visible that they were skipped.
+			if child.IsDir() {
+				placeholder := NewDirEntry(child.Name(), child.ModTime())
+				placeholder.IsExcluded, placeholder.Ino = true, child.InoKey()
+
+				addChild(e, collapsed, placeholder)
+			}
+
+			continue
+		}
+
+		parent, childScope := e, scope
+
+		if scope.match(childPath, child.IsDir()) {
+			if t.ignoreMode == IgnoreSkip {
+				continue
+			}
+
+			// the collapsed content has no details to group
+			if collapsed == nil {
+				group = ignoredGroup(e, group)
+				parent = group
+			}
+
+			childScope = disabledScope
+		}
+
+		if child.IsSymlink() {
+			t.handleSymlink(parent, collapsed, child, childPath, enqueue)
+
+			continue
+		}
+
 		if child.IsDir() {
-			newDir := NewDirEntry(childPath, child.ModTime())
+			// the directory has been already visited through the link to it
+			if t.followSymlinks && !t.visitedDirs.add(child.InoKey()) {
+				continue
+			}
 
-			e.AddChild(newDir)
-			onNewDir(newDir)
+			newDir := NewDirEntry(child.Name(), child.ModTime())
+			newDir.parent, newDir.Ino = parent, child.InoKey()
+
+			addChild(parent, collapsed, newDir)
+
+			if childScope != nil {
+				t.ignoreScopes.Store(newDir, childScope)
+			}
+
+			enqueue(newDir)
 
 			continue
 		}
 
-		e.AddChild(NewFileEntry(childPath, child.Size(), child.ModTime()))
+		size, usage, counted := t.linkedSize(child, childPath)
+		if !counted {
+			continue
+		}
+
+		fileEntry := NewFileEntry(child.Name(), size, child.ModTime())
+		fileEntry.Usage, fileEntry.Ino = usage, child.InoKey()
+
+		addChild(parent, collapsed, fileEntry)
+	}
+}
+
+// linkedSize returns the size and the disk usage of the file accounted according
+// to the hardlink mode. It reports whether the file is counted at all, i.e., it
+// is the first found link or all links are counted.
+func (t *Tree) linkedSize(fi drive.FileInfo, path string) (int64, int64, bool) {
+	size, usage := fi.Size(), fi.Usage()
+
+	if fi.Links() <= 1 {
+		return size, usage, true
 	}
+
+	first := t.hardLinks.add(fi, path)
+
+	switch {
+	case t.hardlinkMode == HardlinkOnce && !first:
+		return size, usage, false
+	case t.hardlinkMode == HardlinkSplit:
+		//nolint:gosec // the number of links always fits
+		size, usage = size/int64(fi.Links()), usage/int64(fi.Links())
+	}
+
+	return size, usage, true
+}
+
+// excludeChild checks the child entry against the exclusion rules. The legacy
+// substring rules are applied to the directories only, while the pattern rules
+// are matched against the path relative to the tree's root.
+func (t *Tree) excludeChild(path string, fi drive.FileInfo) bool {
+	if fi.IsDir() && t.excludeDir(path) {
+		return true
+	}
+
+	if t.excludeMatcher.Empty() {
+		return false
+	}
+
+	return t.excludeMatcher.Match(
+		strings.TrimLeft(path[len(t.scanBase()):], `/\`),
+		fi.IsDir(),
+	)
+}
+
+// scanBase returns the path the exclusion patterns are matched relative to,
+// i.e., the root of the scanned tree, even if the current tree is its subtree.
+func (t *Tree) scanBase() string {
+	if len(t.basePath) != 0 {
+		return t.basePath
+	}
+
+	return t.root.Path()
 }
 
-func (t *Tree) excludeEntry(e *Entry) bool {
+// excludeDir checks whether the directory's path contains any of the legacy
+// exclusion substrings.
+func (t *Tree) excludeDir(path string) bool {
 	for _, exclude := range t.exclude {
-		if strings.Contains(strings.ToLower(e.Path), exclude) {
+		if strings.Contains(strings.ToLower(path), exclude) {
 			return true
 		}
 	}
@@ -1,14 +1,20 @@
 package structure_test
 
 import (
+	"context"
 	"errors"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
 	"slices"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/crumbyte/noxdir/drive"
+	"github.com/crumbyte/noxdir/pkg/cache"
+	"github.com/crumbyte/noxdir/pkg/exclude"
 	"github.com/crumbyte/noxdir/structure"
 
 	"github.com/stretchr/testify/require"
@@ -76,8 +82,7 @@ func TestTree_Traverse(t *testing.T) {
 	e := structure.NewDirEntry(entryRoot, 0)
 	tree := structure.NewTree(e)
 
-	require.NoError(t, tree.Traverse(true))
-	tree.CalculateSize()
+	require.NoError(t, tree.Traverse(context.Background(), true))
 
 	require.Equal(t, uint64(4), e.LocalDirs)
 	require.Equal(t, uint64(3), e.LocalFiles)
@@ -90,6 +95,21 @@ func TestTree_Traverse(t *testing.T) {
 	require.NoError(t, os.RemoveAll(entryRoot))
 }
 
+func TestTree_TraverseNilRoot(t *testing.T) {
+	c, err := cache.NewCache(
+		func(w io.Writer) cache.Encoder { return structure.NewEncoder(w) },
+		func(r io.Reader) cache.Decoder { return structure.NewDecoder(r) },
+		false,
+		cache.WithCacheDir(t.TempDir()),
+	)
+	require.NoError(t, err)
+
+	tree := structure.NewTree(nil, structure.WithCache(c))
+
+	require.NoError(t, tree.Traverse(context.Background(), false))
+	require.Nil(t, tree.Root())
+}
+
 func TestTree_TraverseExclude(t *testing.T) {
 	root, err := filepath.Abs(".")
 	require.NoError(t, err)
@@ -121,6 +141,11 @@ func TestTree_TraverseExclude(t *testing.T) {
 			expectedDirsCnt:  9,
 			expectedFilesCnt: 15,
 		},
+		{
+			exclude:          []string{" LEVEL_2 "},
+			expectedDirsCnt:  7,
+			expectedFilesCnt: 11,
+		},
 	}
 
 	for _, data := range tableData {
@@ -128,15 +153,18 @@ func TestTree_TraverseExclude(t *testing.T) {
 			"exclude: "+strings.Join(data.exclude, ","),
 			func(t *testing.T) {
 				e := structure.NewDirEntry(entryRoot, 0)
+				excludeNames := slices.Clone(data.exclude)
 				tree := structure.NewTree(
-					e, structure.WithExclude(data.exclude),
+					e, structure.WithExclude(excludeNames),
 				)
 
-				require.NoError(t, tree.Traverse(true))
-				tree.CalculateSize()
+				require.NoError(t, tree.Traverse(context.Background(), true))
 
 				require.Equal(t, data.expectedDirsCnt, e.TotalDirs)
 				require.Equal(t, data.expectedFilesCnt, e.TotalFiles)
+
+				// the provided names are not modified
+				require.Equal(t, data.exclude, excludeNames)
 			},
 		)
 	}
@@ -144,6 +172,267 @@ func TestTree_TraverseExclude(t *testing.T) {
 	require.NoError(t, os.RemoveAll(entryRoot))
 }
 
+func TestTree_TraverseExcludePattern(t *testing.T) {
+	root := t.TempDir()
+
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "sub", "build"), 0700))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "build"), 0700))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "subversion"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "build", "out"), make([]byte, 100), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "sub", "build", "out"), make([]byte, 100), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "app.log"), make([]byte, 10), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "keep.log"), make([]byte, 10), 0600))
+
+	m, err := exclude.New([]string{"/build", "sub/", "*.log", "!keep.log"})
+	require.NoError(t, err)
+
+	e := structure.NewDirEntry(root, 0)
+	tree := structure.NewTree(e, structure.WithExcludeMatcher(m))
+
+	require.NoError(t, tree.Traverse(context.Background(), true))
+
+	build := e.GetChild("build")
+
+	require.True(t, build.IsExcluded)
+	require.False(t, build.HasChild())
+
+	sub := e.GetChild("sub")
+
+	require.True(t, sub.IsExcluded)
+	require.False(t, sub.HasChild())
+
+	require.False(t, e.GetChild("subversion").IsExcluded)
+	require.Nil(t, e.GetChild("app.log"))
+	require.NotNil(t, e.GetChild("keep.log"))
+	require.EqualValues(t, 10, e.Size)
+}
+
+func TestTree_TraverseIgnoreFiles(t *testing.T) {
+	root := t.TempDir()
+
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "build", "obj"), 0700))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "src", "gen"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".gitignore"), []byte("build/\n*.log\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "src", ".noxdirignore"), []byte("gen/\n!*.log\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "build", "obj", "out"), make([]byte, 100), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "src", "gen", "gen.go"), make([]byte, 10), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "src", "app.log"), make([]byte, 1), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "app.log"), make([]byte, 1000), 0600))
+
+	e := structure.NewDirEntry(root, 0)
+	tree := structure.NewTree(e, structure.WithIgnoreFiles(structure.IgnoreSkip))
+
+	require.NoError(t, tree.Traverse(context.Background(), true))
+
+	require.Nil(t, e.GetChild("build"))
+	require.Nil(t, e.GetChild("app.log"))
+	require.Nil(t, e.GetChild("src").GetChild("gen"))
+	require.NotNil(t, e.GetChild("src").GetChild("app.log"))
+
+	e = structure.NewDirEntry(root, 0)
+	tree = structure.NewTree(e, structure.WithIgnoreFiles(structure.IgnoreGroup))
+
+	require.NoError(t, tree.Traverse(context.Background(), true))
+
+	group := e.GetChild(structure.IgnoredGroupName)
+
+	require.True(t, group.IsIgnored)
+	require.EqualValues(t, 1100, group.Size)
+	require.NotNil(t, group.GetChild("build").GetChild("obj").GetChild("out"))
+	require.NotNil(t, e.GetChild("src").GetChild(structure.IgnoredGroupName).GetChild("gen"))
+	require.Nil(t, e.GetChild("build"))
+}
+
+func TestTree_TraverseRefreshIgnoreFiles(t *testing.T) {
+	root := t.TempDir()
+
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "src", "build"), 0700))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "build", "gen"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".gitignore"), []byte("build/\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "src", "build", "out"), make([]byte, 100), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "build", "gen", ".gitignore"), []byte("out\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "build", "gen", "out"), make([]byte, 10), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "src", "main.go"), make([]byte, 1), 0600))
+
+	e := structure.NewDirEntry(root, 0)
+	tree := structure.NewTree(e, structure.WithIgnoreFiles(structure.IgnoreSkip))
+
+	require.NoError(t, tree.Traverse(context.Background(), true))
+
+	src := e.GetChild("src")
+	src.ClearChild()
+
+	// the rules of the root ignore file still apply to the refreshed directory
+	require.NoError(t, tree.Subtree(src).Traverse(context.Background(), true))
+	require.Nil(t, src.GetChild("build"))
+	require.NotNil(t, src.GetChild("main.go"))
+
+	e = structure.NewDirEntry(root, 0)
+	tree = structure.NewTree(e, structure.WithIgnoreFiles(structure.IgnoreGroup))
+
+	require.NoError(t, tree.Traverse(context.Background(), true))
+
+	src = e.GetChild("src")
+	src.ClearChild()
+
+	require.NoError(t, tree.Subtree(src).Traverse(context.Background(), true))
+	require.NotNil(t, src.GetChild(structure.IgnoredGroupName).GetChild("build"))
+
+	// the ignore files within the ignored directories are not applied
+	gen := e.GetChild(structure.IgnoredGroupName).GetChild("build").GetChild("gen")
+	gen.ClearChild()
+
+	require.NoError(t, tree.Subtree(gen).Traverse(context.Background(), true))
+	require.NotNil(t, gen.GetChild("out"))
+	require.Nil(t, gen.GetChild(structure.IgnoredGroupName))
+}
+
+func TestTree_TraverseMaxDepth(t *testing.T) {
+	root := t.TempDir()
+
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "a", "b", "c"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a", "file"), make([]byte, 10), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a", "b", "file"), make([]byte, 100), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a", "b", "c", "file"), make([]byte, 1000), 0600))
+
+	e := structure.NewDirEntry(root, 0)
+	tree := structure.NewTree(e, structure.WithMaxDepth(2))
+
+	done, errChan := tree.TraverseAsync(context.Background(), true)
+
+	for err := range errChan {
+		require.NoError(t, err)
+	}
+
+	<-done
+
+	require.EqualValues(t, 1110, e.Size)
+	require.EqualValues(t, 3, e.TotalDirs)
+	require.EqualValues(t, 3, e.TotalFiles)
+
+	b := e.GetChild("a").GetChild("b")
+
+	require.True(t, b.IsCollapsed)
+	require.False(t, b.HasChild())
+	require.EqualValues(t, 1100, b.Size)
+	require.EqualValues(t, 1, b.TotalDirs)
+	require.EqualValues(t, 2, b.TotalFiles)
+	require.EqualValues(t, 1, b.LocalFiles)
+
+	b.ClearChild()
+	b.IsCollapsed = false
+
+	require.NoError(t, tree.Subtree(b).Traverse(context.Background(), true))
+
+	require.NotNil(t, b.GetChild("c").GetChild("file"))
+	require.EqualValues(t, 1110, e.Size)
+}
+
+func TestTree_TraverseIncremental(t *testing.T) {
+	root := t.TempDir()
+
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "a", "b"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a", "b", "file"), make([]byte, 100), 0600))
+
+	e := structure.NewDirEntry(root, 0)
+	tree := structure.NewTree(e)
+
+	require.NoError(t, tree.Traverse(context.Background(), true))
+
+	// the modification time has a one-second precision, so it's set explicitly
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a", "file"), make([]byte, 10), 0600))
+	require.NoError(t, os.Chtimes(filepath.Join(root, "a"), time.Now(), time.Now().Add(time.Hour)))
+
+	done, errChan := tree.TraverseIncremental(context.Background())
+
+	for err := range errChan {
+		require.NoError(t, err)
+	}
+
+	<-done
+
+	// the root entry has no modification time, so it's read again as well
+	require.Equal(t, structure.RescanStats{Reused: 1, Reread: 2}, tree.RescanStats())
+	require.EqualValues(t, 110, e.Size)
+	require.EqualValues(t, 2, e.TotalDirs)
+	require.EqualValues(t, 2, e.TotalFiles)
+	require.NotNil(t, e.GetChild("a").GetChild("b").GetChild("file"))
+}
+
+func TestTree_TraverseRefresh(t *testing.T) {
+	root := t.TempDir()
+
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "a", "b"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "file"), make([]byte, 10), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a", "b", "file"), make([]byte, 100), 0600))
+
+	e := structure.NewDirEntry(root, 0)
+	tree := structure.NewTree(e)
+
+	require.NoError(t, tree.Traverse(context.Background(), true))
+	require.EqualValues(t, 110, e.Size)
+
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a", "file"), make([]byte, 1000), 0600))
+
+	// the refreshed directory's totals are subtracted from its ancestors
+	// first, so nothing is counted twice.
+	a := e.GetChild("a")
+	a.ClearChild()
+
+	require.EqualValues(t, 10, e.Size)
+	require.EqualValues(t, 1, e.TotalDirs)
+	require.EqualValues(t, 1, e.TotalFiles)
+
+	done, errChan := tree.Subtree(a).TraverseAsync(context.Background(), true)
+
+	for err := range errChan {
+		require.NoError(t, err)
+	}
+
+	<-done
+
+	require.EqualValues(t, 1110, e.Size)
+	require.EqualValues(t, 2, e.TotalDirs)
+	require.EqualValues(t, 3, e.TotalFiles)
+	require.EqualValues(t, 1, a.LocalDirs)
+	require.EqualValues(t, 1, a.LocalFiles)
+}
+
+func TestTree_TraverseRefreshExcludePattern(t *testing.T) {
+	root := t.TempDir()
+
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "build"), 0700))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "foo", "build"), 0700))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "src", "gen"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "foo", "build", "out"), make([]byte, 100), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "src", "gen", "out"), make([]byte, 10), 0600))
+
+	m, err := exclude.New([]string{"/build", "src/gen"})
+	require.NoError(t, err)
+
+	e := structure.NewDirEntry(root, 0)
+	tree := structure.NewTree(e, structure.WithExcludeMatcher(m))
+
+	require.NoError(t, tree.Traverse(context.Background(), true))
+	require.True(t, e.GetChild("build").IsExcluded)
+	require.False(t, e.GetChild("foo").GetChild("build").IsExcluded)
+	require.True(t, e.GetChild("src").GetChild("gen").IsExcluded)
+	require.EqualValues(t, 100, e.Size)
+
+	// the patterns are matched relative to the scanned root, not the
+	// refreshed directory
+	for _, name := range []string{"foo", "src"} {
+		dir := e.GetChild(name)
+		dir.ClearChild()
+
+		require.NoError(t, tree.Subtree(dir).Traverse(context.Background(), true))
+	}
+
+	require.False(t, e.GetChild("foo").GetChild("build").IsExcluded)
+	require.True(t, e.GetChild("src").GetChild("gen").IsExcluded)
+	require.EqualValues(t, 100, e.Size)
+}
+
 func TestTree_TraverseAsync(t *testing.T) {
 	root, err := filepath.Abs(".")
 	require.NoError(t, err)
@@ -153,7 +442,7 @@ func TestTree_TraverseAsync(t *testing.T) {
 	e := structure.NewDirEntry(entryRoot, 0)
 	tree := structure.NewTree(e)
 
-	done, errCh := tree.TraverseAsync(true)
+	done, errCh := tree.TraverseAsync(context.Background(), true)
 
 	select {
 	case err = <-errCh:
@@ -164,8 +453,6 @@ func TestTree_TraverseAsync(t *testing.T) {
 		break
 	}
 
-	tree.CalculateSize()
-
 	require.Equal(t, uint64(4), e.LocalDirs)
 	require.Equal(t, uint64(3), e.LocalFiles)
 
@@ -177,6 +464,202 @@ func TestTree_TraverseAsync(t *testing.T) {
 	require.NoError(t, os.RemoveAll(entryRoot))
 }
 
+func TestTree_TraverseAsyncFocusFirst(t *testing.T) {
+	root, err := filepath.Abs(".")
+	require.NoError(t, err)
+
+	entryRoot := initTmpEntry(t, &testEntryInstance, root)
+
+	e := structure.NewDirEntry(entryRoot, 0)
+	tree := structure.NewTree(e, structure.WithFocusFirst())
+	tree.SetFocus(e)
+
+	done, errCh := tree.TraverseAsync(context.Background(), true)
+
+	// the focus can be changed during the traversal
+	tree.SetFocus(e.GetChild("level_1_3"))
+
+	for err = range errCh {
+		require.NoError(t, err)
+	}
+
+	<-done
+
+	require.False(t, tree.Scanning(e))
+	require.Equal(t, uint64(21), e.TotalFiles)
+	require.Equal(t, uint64(9), e.TotalDirs)
+
+	verifyEntryStructure(t, e, &testEntryInstance)
+
+	require.NoError(t, os.RemoveAll(entryRoot))
+}
+
+func TestTree_TraverseCanceled(t *testing.T) {
+	root, err := filepath.Abs(".")
+	require.NoError(t, err)
+
+	entryRoot := initTmpEntry(t, &testEntryInstance, root)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	e := structure.NewDirEntry(entryRoot, 0)
+	tree := structure.NewTree(e)
+
+	require.ErrorIs(t, tree.Traverse(ctx, true), context.Canceled)
+	require.True(t, tree.Interrupted())
+	require.False(t, e.HasChild())
+
+	e = structure.NewDirEntry(entryRoot, 0)
+	tree = structure.NewTree(e)
+
+	done, _ := tree.TraverseAsync(ctx, true)
+
+	select {
+	case <-time.After(time.Second):
+		t.Fatalf("canceled traverse async did not finish")
+	case <-done:
+		break
+	}
+
+	require.NoError(t, os.RemoveAll(entryRoot))
+}
+
+func TestTree_TraverseHardlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hard link counters are not reported on windows")
+	}
+
+	root := t.TempDir()
+	original := filepath.Join(root, "original")
+
+	require.NoError(t, os.WriteFile(original, make([]byte, 1000), 0600))
+	require.NoError(t, os.Mkdir(filepath.Join(root, "dir"), 0700))
+	require.NoError(t, os.Link(original, filepath.Join(root, "link_1")))
+	require.NoError(t, os.Link(original, filepath.Join(root, "dir", "link_2")))
+
+	tableData := []struct {
+		mode          structure.HardlinkMode
+		expectedSize  int64
+		expectedFiles uint64
+	}{
+		{structure.HardlinkOnce, 1000, 1},
+		{structure.HardlinkAll, 3000, 3},
+		{structure.HardlinkSplit, 999, 3},
+	}
+
+	for _, data := range tableData {
+		e := structure.NewDirEntry(root, 0)
+		tree := structure.NewTree(e, structure.WithHardlinkMode(data.mode))
+
+		require.NoError(t, tree.Traverse(context.Background(), true))
+
+		require.Equal(t, data.expectedSize, e.Size)
+		require.Equal(t, data.expectedFiles, e.TotalFiles)
+
+		hardLinks := tree.HardLinks()
+
+		require.Len(t, hardLinks, 1)
+		require.Len(t, hardLinks[0].Paths, 3)
+		require.EqualValues(t, 3, hardLinks[0].Links)
+		require.EqualValues(t, 1000, hardLinks[0].Size)
+	}
+}
+
+func TestTree_TraverseSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links are not followed on windows")
+	}
+
+	root, target := t.TempDir(), t.TempDir()
+
+	require.NoError(t, os.WriteFile(filepath.Join(target, "file"), make([]byte, 100), 0600))
+	require.NoError(t, os.Symlink(target, filepath.Join(root, "dir_link")))
+	require.NoError(t, os.Symlink(filepath.Join(target, "file"), filepath.Join(root, "file_link")))
+	require.NoError(t, os.Symlink(root, filepath.Join(target, "cycle_link")))
+
+	e := structure.NewDirEntry(root, 0)
+	tree := structure.NewTree(e)
+
+	require.NoError(t, tree.Traverse(context.Background(), true))
+
+	require.EqualValues(t, 0, e.Size)
+	require.EqualValues(t, 2, e.TotalFiles)
+
+	dirLink := e.GetChild("dir_link")
+
+	require.True(t, dirLink.IsLink)
+	require.False(t, dirLink.IsDir)
+	require.Equal(t, target, dirLink.Target)
+
+	e = structure.NewDirEntry(root, 0)
+	tree = structure.NewTree(e, structure.WithFollowSymlinks())
+
+	require.NoError(t, tree.Traverse(context.Background(), true))
+
+	require.EqualValues(t, 200, e.Size)
+
+	dirLink = e.GetChild("dir_link")
+
+	require.True(t, dirLink.IsLink)
+	require.True(t, dirLink.IsDir)
+	require.NotNil(t, dirLink.GetChild("file"))
+
+	cycleLink := dirLink.GetChild("cycle_link")
+
+	require.True(t, cycleLink.IsLink)
+	require.False(t, cycleLink.IsDir)
+}
+
+func TestTree_TraverseSymlinkBeforeTarget(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links are not followed on windows")
+	}
+
+	root := t.TempDir()
+	target := filepath.Join(root, "dir", "target")
+
+	require.NoError(t, os.MkdirAll(target, 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(target, "file"), make([]byte, 100), 0600))
+	require.NoError(t, os.Symlink(target, filepath.Join(root, "link")))
+
+	e := structure.NewDirEntry(root, 0)
+	tree := structure.NewTree(e, structure.WithFollowSymlinks())
+
+	require.NoError(t, tree.Traverse(context.Background(), true))
+
+	// the link is found first, so the target directory is not counted again
+	require.EqualValues(t, 100, e.Size)
+	require.EqualValues(t, 1, e.TotalFiles)
+	require.NotNil(t, e.GetChild("link").GetChild("file"))
+	require.Nil(t, e.GetChild("dir").GetChild("target"))
+}
+
+func TestTree_CalculateSizeUsage(t *testing.T) {
+	e := structure.NewDirEntry("root", 0)
+	sub := structure.NewDirEntry("root"+string(os.PathSeparator)+"sub", 0)
+
+	sparse := structure.NewFileEntry("sparse", 1<<20, 0)
+	sparse.Usage = 2048
+
+	small := structure.NewFileEntry("small", 10, 0)
+	small.Usage = 4096
+
+	sub.AddChild(sparse)
+	e.AddChild(sub)
+	e.AddChild(small)
+
+	structure.NewTree(e).CalculateSize()
+
+	require.EqualValues(t, 1<<20+10, e.Size)
+	require.EqualValues(t, 6144, e.Usage)
+	require.EqualValues(t, 1<<20, sub.Size)
+	require.EqualValues(t, 2048, sub.Usage)
+
+	require.Equal(t, small, e.SortChildBy(structure.DiskUsage).Child[0])
+	require.Equal(t, sub, e.SortChildBy(structure.ApparentSize).Child[0])
+}
+
 func TestEntry_AddChild(t *testing.T) {
 	e := structure.NewDirEntry("root", 0)
 
@@ -218,56 +701,37 @@ func TestEntry_AddChild(t *testing.T) {
 }
 
 func TestEntry_Diff(t *testing.T) {
-	currentState := &structure.Entry{
-		Path: "root",
-
-		Child: []*structure.Entry{
-			{Path: "root_file_1"},
-			{Path: "root_file_2"},
-			{
-				Path: "level1",
-				Child: []*structure.Entry{
-					{Path: "level1_file_1"},
-					{Path: "level1_file_2"},
-					{
-						Path: "level2",
-						Child: []*structure.Entry{
-							{Path: "level2_file_1"},
-							{Path: "level2_file_2"},
-						},
-						IsDir: true,
-					},
-				},
-				IsDir: true,
-			},
-		},
-		IsDir: true,
-	}
-
-	newState := &structure.Entry{
-		Path: "root",
-		Child: []*structure.Entry{
-			{Path: "root_file_2"},
-			{Path: "root_file_5"},
-			{
-				Path: "level1",
-				Child: []*structure.Entry{
-					{Path: "level1_file_2"},
-					{Path: "level1_file_5"},
-					{
-						Path: "level2",
-						Child: []*structure.Entry{
-							{Path: "level2_file_2"},
-							{Path: "level2_file_5"},
-						},
-						IsDir: true,
-					},
-				},
-				IsDir: true,
-			},
-		},
-		IsDir: true,
-	}
+	currentState := testDir(
+		"root",
+		testFile("root_file_1", 0),
+		testFile("root_file_2", 0),
+		testDir(
+			"level1",
+			testFile("level1_file_1", 0),
+			testFile("level1_file_2", 0),
+			testDir(
+				"level2",
+				testFile("level2_file_1", 0),
+				testFile("level2_file_2", 0),
+			),
+		),
+	)
+
+	newState := testDir(
+		"root",
+		testFile("root_file_2", 0),
+		testFile("root_file_5", 0),
+		testDir(
+			"level1",
+			testFile("level1_file_2", 0),
+			testFile("level1_file_5", 0),
+			testDir(
+				"level2",
+				testFile("level2_file_2", 0),
+				testFile("level2_file_5", 0),
+			),
+		),
+	)
 
 	diff := currentState.Diff(newState)
 
@@ -286,6 +750,139 @@ func TestEntry_Diff(t *testing.T) {
 	}
 }
 
+func TestEntry_DiffTypeChanged(t *testing.T) {
+	currentState := testDir(
+		"root",
+		testFile("replaced_file", 10),
+		testDir("replaced_dir", testFile("nested", 10)),
+	)
+
+	newState := testDir(
+		"root",
+		testDir("replaced_file", testFile("nested", 10)),
+		testFile("replaced_dir", 10),
+	)
+
+	diff := currentState.Diff(newState)
+
+	require.Empty(t, diff.Modified)
+	require.Empty(t, diff.Moved)
+	require.Len(t, diff.Added, 2)
+	require.Len(t, diff.Removed, 2)
+
+	for _, added := range diff.Added {
+		require.Equal(t, added.Name() == "replaced_file", added.IsDir, added.Name())
+	}
+
+	for _, removed := range diff.Removed {
+		require.Equal(t, removed.Name() == "replaced_dir", removed.IsDir, removed.Name())
+	}
+}
+
+func TestEntry_DiffMoved(t *testing.T) {
+	withIno := func(e *structure.Entry, ino uint64) *structure.Entry {
+		e.Ino = drive.InoKey{Dev: 1, Ino: ino}
+
+		return e
+	}
+
+	currentState := testDir(
+		"root",
+		withIno(testFile("renamed_old", 10), 1),
+		withIno(testFile("modified", 10), 2),
+		withIno(testDir(
+			"moved",
+			withIno(testFile("moved_file", 10), 3),
+			withIno(testFile("removed", 10), 4),
+		), 5),
+		withIno(testDir("target"), 6),
+	)
+
+	newState := testDir(
+		"root",
+		withIno(testFile("renamed_new", 10), 1),
+		withIno(testFile("modified", 20), 2),
+		withIno(testDir(
+			"target",
+			withIno(testDir(
+				"moved",
+				withIno(testFile("moved_file", 10), 3),
+				withIno(testFile("added", 10), 7),
+			), 5),
+		), 6),
+	)
+
+	diff := currentState.Diff(newState)
+
+	require.Len(t, diff.Moved, 2)
+	require.Len(t, diff.Modified, 1)
+	require.Equal(t, "modified", diff.Modified[0][1].Name())
+
+	moved := make(map[string]string)
+
+	for _, pair := range diff.Moved {
+		moved[pair[0].Path()] = pair[1].Path()
+	}
+
+	require.Equal(t, map[string]string{
+		filepath.Join("root", "renamed_old"): filepath.Join("root", "renamed_new"),
+		filepath.Join("root", "moved"):       filepath.Join("root", "target", "moved"),
+	}, moved)
+
+	// the moved directory's content is compared as well
+	require.Len(t, diff.Added, 1)
+	require.Equal(t, "added", diff.Added[0].Name())
+	require.Len(t, diff.Removed, 1)
+	require.Equal(t, "removed", diff.Removed[0].Name())
+
+	for _, c := range diff.Changes() {
+		if c.Kind == structure.ChangeMoved {
+			require.Equal(t, c.Old.Name() == "renamed_old", c.Renamed())
+		}
+	}
+}
+
+func TestTree_TraverseRenamed(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("inode numbers are not reported on windows")
+	}
+
+	root := t.TempDir()
+
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "old", "nested"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "old", "nested", "file"), make([]byte, 100), 0600))
+
+	before := structure.NewDirEntry(root, 0)
+	require.NoError(t, structure.NewTree(before).Traverse(context.Background(), true))
+
+	require.NoError(t, os.Rename(filepath.Join(root, "old"), filepath.Join(root, "new")))
+
+	after := structure.NewDirEntry(root, 0)
+	require.NoError(t, structure.NewTree(after).Traverse(context.Background(), true))
+
+	diff := before.Diff(after)
+
+	require.Empty(t, diff.Added)
+	require.Empty(t, diff.Removed)
+	require.Len(t, diff.Moved, 1)
+	require.Equal(t, filepath.Join(root, "old"), diff.Moved[0][0].Path())
+	require.Equal(t, filepath.Join(root, "new"), diff.Moved[0][1].Path())
+}
+
+func testDir(name string, child ...*structure.Entry) *structure.Entry {
+	dir := structure.NewDirEntry(name, 0)
+
+	for _, c := range child {
+		dir.AddChild(c)
+	}
+
+	return dir
+}
+
+func testFile(name string, size int64) *structure.Entry {
+	return structure.NewFileEntry(name, size, 0)
+}
+
 func verifyEntryStructure(t *testing.T, e *structure.Entry, te *testEntry) {
 	t.Helper()
 
@@ -334,3 +931,127 @@ func initTmpEntry(t *testing.T, et *testEntry, parent string) string {
 
 	return absRootPath
 }
+
+func TestTree_ApplyEvent(t *testing.T) {
+	root := t.TempDir()
+
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "a", "b"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a", "b", "file"), make([]byte, 100), 0600))
+
+	e := structure.NewDirEntry(root, 0)
+	tree := structure.NewTree(e)
+
+	require.NoError(t, tree.Traverse(context.Background(), true))
+
+	// create
+	newFile := filepath.Join(root, "a", "new")
+	require.NoError(t, os.WriteFile(newFile, make([]byte, 10), 0600))
+
+	dir, delta := tree.ApplyEvent(drive.WatchEvent{Path: newFile, Op: drive.WatchCreate})
+	require.Equal(t, filepath.Join(root, "a"), dir)
+	require.EqualValues(t, 10, delta)
+	require.EqualValues(t, 110, e.Size)
+	require.EqualValues(t, 2, e.TotalFiles)
+	require.EqualValues(t, 1, e.GetChild("a").LocalFiles)
+
+	// modify
+	require.NoError(t, os.WriteFile(newFile, make([]byte, 50), 0600))
+
+	_, delta = tree.ApplyEvent(drive.WatchEvent{Path: newFile, Op: drive.WatchModify})
+	require.EqualValues(t, 40, delta)
+	require.EqualValues(t, 150, e.Size)
+	require.EqualValues(t, 50, e.GetChild("a").GetChild("new").Size)
+
+	// rename the directory with its content
+	oldDir, renamedDir := filepath.Join(root, "a", "b"), filepath.Join(root, "c")
+	require.NoError(t, os.Rename(oldDir, renamedDir))
+
+	tree.ApplyEvent(drive.WatchEvent{Path: renamedDir, OldPath: oldDir, Op: drive.WatchRename})
+	require.Nil(t, e.GetChild("a").GetChild("b"))
+	require.EqualValues(t, 50, e.GetChild("a").Size)
+	require.EqualValues(t, 0, e.GetChild("a").TotalDirs)
+	require.EqualValues(t, 150, e.Size)
+	require.EqualValues(t, 2, e.TotalDirs)
+	require.Equal(
+		t,
+		filepath.Join(renamedDir, "file"),
+		e.GetChild("c").GetChild("file").Path(),
+	)
+
+	// remove
+	require.NoError(t, os.RemoveAll(renamedDir))
+
+	_, delta = tree.ApplyEvent(drive.WatchEvent{Path: renamedDir, Op: drive.WatchRemove})
+	require.EqualValues(t, -100, delta)
+	require.EqualValues(t, 50, e.Size)
+	require.EqualValues(t, 1, e.TotalDirs)
+	require.EqualValues(t, 1, e.TotalFiles)
+	require.EqualValues(t, 1, e.LocalDirs)
+
+	// the new directory is added empty, and its content is read by the scan
+	newDir := filepath.Join(root, "d")
+	require.NoError(t, os.MkdirAll(filepath.Join(newDir, "sub"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(newDir, "sub", "file"), make([]byte, 20), 0600))
+
+	dir, _ = tree.ApplyEvent(drive.WatchEvent{Path: newDir, Op: drive.WatchCreate})
+	require.Equal(t, root, dir)
+
+	dir, _ = tree.ApplyEvent(drive.WatchEvent{Path: filepath.Join(newDir, "sub"), Op: drive.WatchCreate})
+	require.Empty(t, dir)
+	require.EqualValues(t, 50, e.Size)
+
+	newDirs := tree.DrainNewDirs()
+	require.Len(t, newDirs, 1)
+	require.Empty(t, tree.DrainNewDirs())
+
+	require.NoError(t, tree.Subtree(newDirs[0]).Traverse(context.Background(), true))
+	require.EqualValues(t, 70, e.Size)
+	require.EqualValues(t, 3, e.TotalDirs)
+	require.NotNil(t, e.GetChild("d").GetChild("sub").GetChild("file"))
+
+	// outside the tree
+	dir, _ = tree.ApplyEvent(drive.WatchEvent{Path: filepath.Dir(root), Op: drive.WatchRemove})
+	require.Empty(t, dir)
+}
+
+func TestTree_ApplyEventHardLinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hard link counters are not reported on windows")
+	}
+
+	root := t.TempDir()
+
+	file := filepath.Join(root, "file")
+	require.NoError(t, os.WriteFile(file, make([]byte, 100), 0600))
+	require.NoError(t, os.Link(file, filepath.Join(root, "link")))
+
+	e := structure.NewDirEntry(root, 0)
+	tree := structure.NewTree(e, structure.WithHardlinkMode(structure.HardlinkOnce))
+
+	require.NoError(t, tree.Traverse(context.Background(), true))
+	require.EqualValues(t, 100, e.Size)
+
+	// the new link to the counted file is skipped
+	newLink := filepath.Join(root, "new_link")
+	require.NoError(t, os.Link(file, newLink))
+
+	dir, _ := tree.ApplyEvent(drive.WatchEvent{Path: newLink, Op: drive.WatchCreate})
+	require.Empty(t, dir)
+	require.EqualValues(t, 100, e.Size)
+	require.EqualValues(t, 1, e.LocalFiles)
+	require.Len(t, tree.HardLinks()[0].Paths, 3)
+
+	e = structure.NewDirEntry(root, 0)
+	tree = structure.NewTree(e, structure.WithHardlinkMode(structure.HardlinkSplit))
+
+	require.NoError(t, tree.Traverse(context.Background(), true))
+	require.EqualValues(t, 99, e.Size)
+
+	// each link holds its share of the modified file
+	require.NoError(t, os.WriteFile(file, make([]byte, 150), 0600))
+
+	_, delta := tree.ApplyEvent(drive.WatchEvent{Path: file, Op: drive.WatchModify})
+	require.EqualValues(t, 17, delta)
+	require.EqualValues(t, 50, e.GetChild("file").Size)
+	require.Len(t, tree.HardLinks()[0].Paths, 3)
+}
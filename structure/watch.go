@@ -0,0 +1,222 @@
+package structure
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/crumbyte/noxdir/drive"
+)
+
+// ApplyEvent applies the file system change reported by the drive.Watcher to
+// the tree in place. The sizes and the number of entries of all ancestors of
+// the changed entry are updated accordingly, so the tree doesn't have to be
+// recalculated. The new directories are added empty, and their content must be
+// scanned separately, see DrainNewDirs.
+//
+// It returns the path of the directory containing the changed entry and the
+// change of its apparent size. An empty path will be returned if the change
+// does not affect the tree, e.g., it happened within an excluded or collapsed
+// directory, or outside the tree's root.
+//
+// The ignore files are not applied to the new entries themselves.
+func (t *Tree) ApplyEvent(ev drive.WatchEvent) (string, int64) {
+	switch ev.Op {
+	case drive.WatchCreate, drive.WatchModify:
+		return t.applyChange(ev.Path)
+	case drive.WatchRemove:
+		return t.applyRemove(ev.Path)
+	case drive.WatchRename:
+		return t.applyRename(ev.OldPath, ev.Path)
+	}
+
+	return "", 0
+}
+
+// DrainNewDirs returns the directories added by ApplyEvent since the last call
+// and forgets them. The directories might be moved from the outside of the tree
+// along with their content, so they should be scanned, e.g., by the Subtree's
+// traversal. Until then, the changes within these directories are skipped by
+// ApplyEvent, since they will be read by the scan anyway.
+func (t *Tree) DrainNewDirs() []*Entry {
+	newDirs := t.newDirs
+	t.newDirs = nil
+
+	return newDirs
+}
+
+// applyChange adds the new entry to the tree or updates the existing one.
+func (t *Tree) applyChange(path string) (string, int64) {
+	parent, child := t.locate(path)
+	if parent == nil {
+		return "", 0
+	}
+
+	fi, err := drive.Lstat(path)
+	if err != nil || !t.filterFileInfo(fi) || t.excludeChild(path, fi) {
+		return "", 0
+	}
+
+	if child != nil {
+		// the directory's size is changed only by its content
+		if child.IsDir {
+			return "", 0
+		}
+
+		newSize, newUsage, _ := t.linkedSize(fi, path)
+		size := newSize - child.Size
+
+		// the file might have been replaced by another one, e.g., on an
+		// atomic save
+		child.ModTime, child.Ino = fi.ModTime(), fi.InoKey()
+		child.grow(size, newUsage-child.Usage, 0, 0)
+
+		return filepath.Dir(path), size
+	}
+
+	if child = t.newWatchedEntry(path, fi); child == nil {
+		return "", 0
+	}
+
+	parent.AddChild(child)
+
+	return filepath.Dir(path), child.Size
+}
+
+// applyRemove removes the entry from the tree.
+func (t *Tree) applyRemove(path string) (string, int64) {
+	parent, child := t.locate(path)
+	if child == nil {
+		return "", 0
+	}
+
+	parent.RemoveChild(child)
+
+	if idx := slices.Index(t.newDirs, child); idx != -1 {
+		t.newDirs = slices.Delete(t.newDirs, idx, idx+1)
+	}
+
+	return filepath.Dir(path), -child.Size
+}
+
+// applyRename moves the entry within the tree without scanning it again. If
+// either the source or the target is not a part of the tree, the rename is
+// handled as a new or a removed entry.
+func (t *Tree) applyRename(oldPath, newPath string) (string, int64) {
+	oldParent, child := t.locate(oldPath)
+	if child == nil {
+		return t.applyChange(newPath)
+	}
+
+	newParent, existing := t.locate(newPath)
+	if newParent == nil {
+		return t.applyRemove(oldPath)
+	}
+
+	// the rename replaces the existing target
+	if existing != nil {
+		newParent.RemoveChild(existing)
+	}
+
+	oldParent.RemoveChild(child)
+	child.name = filepath.Base(newPath)
+	newParent.AddChild(child)
+
+	return filepath.Dir(newPath), 0
+}
+
+// locate finds the entry by its path. It returns the entry's parent, which is
+// the ignored entries group if the entry belongs to it, and the entry itself. A
+// nil parent will be returned if the entry's parent directory is not a part of
+// the tree, its content is not kept in the tree, or it's not scanned yet.
+func (t *Tree) locate(path string) (*Entry, *Entry) {
+	if t.root == nil {
+		return nil, nil
+	}
+
+	rootPath := t.root.Path()
+
+	if len(path) <= len(rootPath) || !strings.HasPrefix(path, rootPath) {
+		return nil, nil
+	}
+
+	names := strings.Split(
+		strings.Trim(path[len(rootPath):], `/\`),
+		string(os.PathSeparator),
+	)
+
+	parent := t.root
+
+	for i, name := range names {
+		group, child := lookupChild(parent, name)
+
+		if i == len(names)-1 {
+			if group != nil {
+				return group, child
+			}
+
+			return parent, child
+		}
+
+		if child == nil || !child.IsDir || child.IsCollapsed || child.IsExcluded ||
+			slices.Contains(t.newDirs, child) {
+			return nil, nil
+		}
+
+		parent = child
+	}
+
+	return nil, nil
+}
+
+// newWatchedEntry creates a new entry for the watched change. The new directory
+// is registered to be scanned, since it might be moved from the outside of the
+// tree along with its content. A nil value will be returned if the entry is not
+// counted, e.g., it's a hard link to the file that is already in the tree.
+func (t *Tree) newWatchedEntry(path string, fi drive.FileInfo) *Entry {
+	switch {
+	case fi.IsSymlink():
+		link := NewLinkEntry(path, fi.Target(), fi.ModTime())
+		link.Ino = fi.InoKey()
+
+		return link
+	case fi.IsDir():
+		dir := NewDirEntry(path, fi.ModTime())
+		dir.Ino = fi.InoKey()
+
+		t.newDirs = append(t.newDirs, dir)
+
+		return dir
+	}
+
+	size, usage, counted := t.linkedSize(fi, path)
+	if !counted {
+		return nil
+	}
+
+	file := NewFileEntry(path, size, fi.ModTime())
+	file.Usage, file.Ino = usage, fi.InoKey()
+
+	return file
+}
+
+// lookupChild finds the child entry by its name within the directory itself or
+// the directory's ignored entries group. The group will be returned if the
+// child was found within it.
+func lookupChild(parent *Entry, name string) (*Entry, *Entry) {
+	if child := parent.GetChild(name); child != nil {
+		return nil, child
+	}
+
+	group := parent.GetChild(IgnoredGroupName)
+	if group == nil || !group.IsIgnored {
+		return nil, nil
+	}
+
+	if child := group.GetChild(name); child != nil {
+		return group, child
+	}
+
+	return nil, nil
+}